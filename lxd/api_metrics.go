@@ -36,6 +36,9 @@ type metricsCacheEntry struct {
 var metricsCache map[string]metricsCacheEntry
 var metricsCacheLock sync.Mutex
 
+// storageCommandDurations accumulates the time spent running external commands by the storage drivers.
+var storageCommandDurations = metrics.NewCommandDurations()
+
 var metricsCmd = APIEndpoint{
 	Path: "metrics",
 
@@ -402,6 +405,9 @@ func internalMetrics(ctx context.Context, daemonStartTime time.Time, tx *db.Clus
 	// Number of goroutines
 	out.AddSamples(metrics.GoGoroutines, metrics.Sample{Value: float64(runtime.NumGoroutine())})
 
+	// Storage commands
+	storageCommandDurations.AddSamples(out, metrics.StorageCommandsTotal, metrics.StorageCommandSecondsTotal)
+
 	// Go memory stats
 	var ms runtime.MemStats
 
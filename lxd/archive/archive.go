@@ -124,6 +124,57 @@ func CompressedTarReader(ctx context.Context, r io.ReadSeeker, unpacker []string
 	return tr, cancelFunc, nil
 }
 
+// unpackedBlockSize is the block size used to estimate the space taken by unpacked files.
+const unpackedBlockSize = 4096
+
+// UnpackedSize returns the size of the content of the supplied (optionally compressed) tarball or squashfs file,
+// and an estimate of the disk space needed to unpack it. For the estimate the size of each entry is rounded up to
+// a whole number of blocks, with directories and other entries without content taking one block.
+// The outputPath is the path the file would be unpacked to, used to confine the decompression process.
+func UnpackedSize(ctx context.Context, file string, sysOS *sys.OS, outputPath string) (int64, int64, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return -1, -1, err
+	}
+
+	defer func() { _ = f.Close() }()
+
+	_, extension, unpacker, err := shared.DetectCompressionFile(f)
+	if err != nil {
+		return -1, -1, err
+	}
+
+	if !strings.HasPrefix(extension, ".tar") && extension != ".squashfs" {
+		return -1, -1, fmt.Errorf("Unsupported image format: %s", extension)
+	}
+
+	tr, cancelFunc, err := CompressedTarReader(ctx, f, unpacker, sysOS, outputPath)
+	if err != nil {
+		return -1, -1, err
+	}
+
+	defer cancelFunc()
+
+	var contentSize, diskSize int64
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			return -1, -1, fmt.Errorf("Failed reading %q: %w", file, err)
+		}
+
+		contentSize += hdr.Size
+
+		blocks := (hdr.Size + unpackedBlockSize - 1) / unpackedBlockSize
+		diskSize += max(blocks, 1) * unpackedBlockSize
+	}
+
+	return contentSize, diskSize, nil
+}
+
 // Unpack extracts image from archive.
 func Unpack(file string, path string, blockBackend bool, sysOS *sys.OS, tracker *ioprogress.ProgressTracker) error {
 	extractArgs, extension, unpacker, err := shared.DetectCompression(file)
@@ -0,0 +1,41 @@
+package archive
+
+import (
+	"archive/tar"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Test UnpackedSize returns the content size and estimates the space needed for an image larger than the default volume size.
+func TestUnpackedSize(t *testing.T) {
+	largeSize := int64(12 * 1024 * 1024 * 1024) // Larger than the 10GiB default volume size.
+
+	imageFile := filepath.Join(t.TempDir(), "rootfs.tar")
+	f, err := os.Create(imageFile)
+	require.NoError(t, err)
+
+	defer func() { _ = f.Close() }()
+
+	tw := tar.NewWriter(f)
+	require.NoError(t, tw.WriteHeader(&tar.Header{Name: "rootfs/", Typeflag: tar.TypeDir, Mode: 0755}))
+	require.NoError(t, tw.WriteHeader(&tar.Header{Name: "rootfs/small", Typeflag: tar.TypeReg, Mode: 0644, Size: 10}))
+	_, err = tw.Write(make([]byte, 10))
+	require.NoError(t, err)
+	require.NoError(t, tw.WriteHeader(&tar.Header{Name: "rootfs/large", Typeflag: tar.TypeReg, Mode: 0644, Size: largeSize}))
+
+	// Leave the content of the large file as a hole, followed by the end of archive blocks.
+	offset, err := f.Seek(0, io.SeekCurrent)
+	require.NoError(t, err)
+	require.NoError(t, f.Truncate(offset+largeSize+2*512))
+
+	contentSize, diskSize, err := UnpackedSize(context.Background(), imageFile, nil, t.TempDir())
+	require.NoError(t, err)
+	assert.Equal(t, 10+largeSize, contentSize)
+	assert.Equal(t, 2*int64(unpackedBlockSize)+largeSize, diskSize)
+}
@@ -890,6 +890,9 @@ func (d *Daemon) init() error {
 		return apparmor.RsyncWrapper(d.os, cmd, source, destination)
 	}
 
+	// Record the duration of the commands run by the storage drivers.
+	storageDrivers.SetCommandObserver(storageCommandDurations)
+
 	// Bump some kernel limits to avoid issues
 	for _, limit := range []int{unix.RLIMIT_NOFILE} {
 		rLimit := unix.Rlimit{}
@@ -25,6 +25,7 @@ import (
 
 	"github.com/gorilla/mux"
 	"github.com/kballard/go-shellquote"
+	"golang.org/x/sys/unix"
 	"gopkg.in/yaml.v2"
 
 	"github.com/canonical/lxd/client"
@@ -43,6 +44,7 @@ import (
 	"github.com/canonical/lxd/lxd/response"
 	"github.com/canonical/lxd/lxd/state"
 	storagePools "github.com/canonical/lxd/lxd/storage"
+	"github.com/canonical/lxd/lxd/storage/filesystem"
 	"github.com/canonical/lxd/lxd/task"
 	"github.com/canonical/lxd/lxd/util"
 	"github.com/canonical/lxd/shared"
@@ -2428,12 +2430,14 @@ func pruneLeftoverImages(s *state.State) {
 		for _, entry := range entries {
 			fp := strings.Split(entry.Name(), ".")[0]
 			if !shared.ValueInSlice(fp, images) {
-				err = os.RemoveAll(shared.VarPath("images", entry.Name()))
+				removed, err := removeLeftoverImageEntry(shared.VarPath("images", entry.Name()))
 				if err != nil {
 					return fmt.Errorf("Unable to remove leftover image: %v: %w", entry.Name(), err)
 				}
 
-				logger.Debugf("Removed leftover image file: %s", entry.Name())
+				if removed {
+					logger.Debugf("Removed leftover image file: %s", entry.Name())
+				}
 			}
 		}
 
@@ -2467,6 +2471,32 @@ func pruneLeftoverImages(s *state.State) {
 	logger.Infof("Done cleaning up leftover image files")
 }
 
+// removeLeftoverImageEntry removes a leftover entry of the images directory. Temporary directories left behind
+// by an interrupted image unpack may still be mounted, in which case they are unmounted first so that the content
+// of the mounted filesystem isn't deleted. Mounts that are still in use are skipped and false is returned.
+func removeLeftoverImageEntry(path string) (bool, error) {
+	if filesystem.IsMountPoint(path) {
+		err := unix.Unmount(path, 0)
+		if err != nil {
+			if errors.Is(err, unix.EBUSY) {
+				logger.Warn("Skipping leftover image mount that is in use", logger.Ctx{"path": path})
+				return false, nil
+			}
+
+			return false, fmt.Errorf("Failed unmounting %q: %w", path, err)
+		}
+
+		logger.Debug("Unmounted leftover image mount", logger.Ctx{"path": path})
+	}
+
+	err := os.RemoveAll(path)
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
 func pruneExpiredImages(ctx context.Context, s *state.State, op *operations.Operation) error {
 	var err error
 	var projectsImageRemoteCacheExpiryDays map[string]int64
@@ -5421,12 +5421,12 @@ func (d *lxc) MigrateSend(args instance.MigrateSendArgs) error {
 
 		d.logger.Debug("Starting storage migration phase")
 
-		err = pool.MigrateInstance(d, filesystemConn, volSourceArgs, d.op)
+		stats, err := pool.MigrateInstance(d, filesystemConn, volSourceArgs, d.op)
 		if err != nil {
 			return err
 		}
 
-		d.logger.Debug("Finished storage migration phase")
+		d.logger.Debug("Finished storage migration phase", stats.LogCtx())
 
 		if args.Live {
 			d.logger.Debug("Starting live migration phase")
@@ -5629,12 +5629,13 @@ func (d *lxc) MigrateSend(args instance.MigrateSendArgs) error {
 			volSourceArgs.Snapshots = nil
 			volSourceArgs.Info.Config.VolumeSnapshots = nil
 
-			err = pool.MigrateInstance(d, filesystemConn, volSourceArgs, d.op)
+			finalStats, err := pool.MigrateInstance(d, filesystemConn, volSourceArgs, d.op)
+			stats.Add(finalStats)
 			if err != nil {
 				return err
 			}
 
-			d.logger.Debug("Finished final storage migration phase")
+			d.logger.Debug("Finished final storage migration phase", stats.LogCtx())
 		}
 
 		return nil
@@ -6157,11 +6158,13 @@ func (d *lxc) MigrateReceive(args instance.MigrateReceiveArgs) error {
 			}
 		}
 
-		err = pool.CreateInstanceFromMigration(d, filesystemConn, volTargetArgs, d.op)
+		stats, err := pool.CreateInstanceFromMigration(d, filesystemConn, volTargetArgs, d.op)
 		if err != nil {
 			return fmt.Errorf("Failed creating instance on target: %w", err)
 		}
 
+		d.logger.Debug("Finished storage migration phase", stats.LogCtx())
+
 		isRemoteClusterMove := args.ClusterMoveSourceName != "" && pool.Driver().Info().Remote
 
 		// Only delete all instance volumes on error if the pool volume creation has succeeded to
@@ -6728,10 +6728,12 @@ func (d *qemu) MigrateSend(args instance.MigrateSendArgs) error {
 				}
 			}
 
-			err = pool.MigrateInstance(d, filesystemConn, volSourceArgs, d.op)
+			stats, err := pool.MigrateInstance(d, filesystemConn, volSourceArgs, d.op)
 			if err != nil {
 				return err
 			}
+
+			d.logger.Debug("Finished storage migration phase", stats.LogCtx())
 		}
 
 		return nil
@@ -6891,11 +6893,13 @@ func (d *qemu) migrateSendLive(pool storagePools.Pool, clusterMoveSourceName str
 	// We enable AllowInconsistent mode as this allows for transferring the VM storage whilst it is running
 	// and the snapshot we took earlier is designed to provide consistency anyway.
 	volSourceArgs.AllowInconsistent = true
-	err = pool.MigrateInstance(d, filesystemConn, volSourceArgs, d.op)
+	stats, err := pool.MigrateInstance(d, filesystemConn, volSourceArgs, d.op)
 	if err != nil {
 		return err
 	}
 
+	d.logger.Debug("Finished storage migration phase", stats.LogCtx())
+
 	// Non-shared storage snapshot transfer.
 	if !sharedStorage {
 		listener, err := net.Listen("unix", "")
@@ -7378,11 +7382,13 @@ func (d *qemu) MigrateReceive(args instance.MigrateReceiveArgs) error {
 			}
 		}
 
-		err = pool.CreateInstanceFromMigration(d, filesystemConn, volTargetArgs, d.op)
+		stats, err := pool.CreateInstanceFromMigration(d, filesystemConn, volTargetArgs, d.op)
 		if err != nil {
 			return fmt.Errorf("Failed creating instance on target: %w", err)
 		}
 
+		d.logger.Debug("Finished storage migration phase", stats.LogCtx())
+
 		// Only delete all instance volumes on error if the pool volume creation has succeeded to
 		// avoid deleting an existing conflicting volume.
 		isRemoteClusterMove := args.ClusterMoveSourceName != "" && poolInfo.Remote
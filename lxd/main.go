@@ -27,6 +27,8 @@ type cmdGlobal struct {
 	flagVersion bool
 
 	flagLogFile    string
+	flagLogFormat  string
+	flagLogCaller  bool
 	flagLogDebug   bool
 	flagLogSyslog  bool
 	flagLogTrace   []string
@@ -61,7 +63,12 @@ func (c *cmdGlobal) Run(cmd *cobra.Command, args []string) error {
 		syslog = "lxd"
 	}
 
-	err = logger.InitLogger(c.flagLogFile, syslog, c.flagLogVerbose, c.flagLogDebug, events.NewEventHandler())
+	opts := []logger.Option{logger.WithFormat(c.flagLogFormat)}
+	if c.flagLogCaller {
+		opts = append(opts, logger.WithReportCaller())
+	}
+
+	err = logger.InitLogger(c.flagLogFile, syslog, c.flagLogVerbose, c.flagLogDebug, events.NewEventHandler(), opts...)
 	if err != nil {
 		return err
 	}
@@ -97,6 +104,8 @@ func main() {
 	app.PersistentFlags().BoolVar(&globalCmd.flagVersion, "version", false, "Print version number")
 	app.PersistentFlags().BoolVarP(&globalCmd.flagHelp, "help", "h", false, "Print help")
 	app.PersistentFlags().StringVar(&globalCmd.flagLogFile, "logfile", "", "Path to the log file"+"``")
+	app.PersistentFlags().StringVar(&globalCmd.flagLogFormat, "logformat", logger.FormatText, "Format of the log messages (text or json)"+"``")
+	app.PersistentFlags().BoolVar(&globalCmd.flagLogCaller, "logcaller", false, "Include the source file and line of each log message")
 	app.PersistentFlags().BoolVar(&globalCmd.flagLogSyslog, "syslog", false, "Log to syslog")
 	app.PersistentFlags().StringArrayVar(&globalCmd.flagLogTrace, "trace", []string{}, "Log tracing targets"+"``")
 	app.PersistentFlags().BoolVarP(&globalCmd.flagLogDebug, "debug", "d", false, "Show all debug messages")
@@ -223,6 +232,7 @@ func main() {
 
 	// Run the main command and handle errors
 	err := app.Execute()
+	logger.Flush()
 	if err != nil {
 		os.Exit(1)
 	}
@@ -0,0 +1,49 @@
+package metrics
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// CommandDurations accumulates the number of times external commands were run and the time spent running them.
+type CommandDurations struct {
+	mu       sync.Mutex
+	counts   map[string]uint64
+	duration map[string]time.Duration
+}
+
+// NewCommandDurations returns an empty CommandDurations.
+func NewCommandDurations() *CommandDurations {
+	return &CommandDurations{
+		counts:   map[string]uint64{},
+		duration: map[string]time.Duration{},
+	}
+}
+
+// ObserveCommand records a run of the named command which took the given duration.
+func (c *CommandDurations) ObserveCommand(command string, duration time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.counts[command]++
+	c.duration[command] += duration
+}
+
+// AddSamples adds the accumulated command counts and durations to the metric set, labelled by command.
+func (c *CommandDurations) AddSamples(m *MetricSet, countType MetricType, secondsType MetricType) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	commands := make([]string, 0, len(c.counts))
+	for command := range c.counts {
+		commands = append(commands, command)
+	}
+
+	sort.Strings(commands)
+
+	for _, command := range commands {
+		m.AddSamples(countType, Sample{Labels: map[string]string{"command": command}, Value: float64(c.counts[command])})
+		m.AddSamples(secondsType, Sample{Labels: map[string]string{"command": command}, Value: c.duration[command].Seconds()})
+	}
+}
@@ -2,6 +2,7 @@ package metrics
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 
@@ -56,3 +57,25 @@ func TestMetricSet_FilterSamples(t *testing.T) {
 		require.Contains(t, hasKeys, "project")
 	}
 }
+
+func TestCommandDurations(t *testing.T) {
+	durations := NewCommandDurations()
+	durations.ObserveCommand("lvcreate", 2*time.Second)
+	durations.ObserveCommand("mount", 500*time.Millisecond)
+	durations.ObserveCommand("lvcreate", time.Second)
+
+	m := NewMetricSet(nil)
+	durations.AddSamples(m, StorageCommandsTotal, StorageCommandSecondsTotal)
+
+	require.Equal(t, []Sample{
+		{Labels: map[string]string{"command": "lvcreate"}, Value: 2},
+		{Labels: map[string]string{"command": "mount"}, Value: 1},
+	}, m.set[StorageCommandsTotal])
+
+	require.Equal(t, []Sample{
+		{Labels: map[string]string{"command": "lvcreate"}, Value: 3},
+		{Labels: map[string]string{"command": "mount"}, Value: 0.5},
+	}, m.set[StorageCommandSecondsTotal])
+
+	require.Contains(t, m.String(), "# TYPE lxd_storage_command_seconds_total counter")
+}
@@ -146,6 +146,10 @@ const (
 	GoNextGCBytes
 	// Instances represents the instance count.
 	Instances
+	// StorageCommandsTotal represents the number of external commands run by the storage drivers.
+	StorageCommandsTotal
+	// StorageCommandSecondsTotal represents the time spent running external commands by the storage drivers.
+	StorageCommandSecondsTotal
 )
 
 // MetricNames associates a metric type to its name.
@@ -215,6 +219,8 @@ var MetricNames = map[MetricType]string{
 	UptimeSeconds:               "lxd_uptime_seconds",
 	WarningsTotal:               "lxd_warnings_total",
 	Instances:                   "lxd_instances",
+	StorageCommandsTotal:        "lxd_storage_commands_total",
+	StorageCommandSecondsTotal:  "lxd_storage_command_seconds_total",
 }
 
 // MetricHeaders represents the metric headers which contain help messages as specified by OpenMetrics.
@@ -284,4 +290,6 @@ var MetricHeaders = map[MetricType]string{
 	UptimeSeconds:               "# HELP lxd_uptime_seconds The daemon uptime in seconds.",
 	WarningsTotal:               "# HELP lxd_warnings_total The number of active warnings.",
 	Instances:                   "# HELP lxd_instances The number of instances.",
+	StorageCommandsTotal:        "# HELP lxd_storage_commands_total The number of external commands run by the storage drivers.",
+	StorageCommandSecondsTotal:  "# HELP lxd_storage_command_seconds_total The time spent running external commands by the storage drivers in seconds.",
 }
@@ -179,12 +179,14 @@ func (s *migrationSourceWs) DoStorage(state *state.State, projectName string, po
 		return err
 	}
 
-	err = pool.MigrateCustomVolume(projectName, fsConn, volSourceArgs, migrateOp)
+	stats, err := pool.MigrateCustomVolume(projectName, fsConn, volSourceArgs, migrateOp)
 	if err != nil {
 		s.sendControl(err)
 		return err
 	}
 
+	logger.Debug("Migration source finished sending storage volume", stats.LogCtx())
+
 	msg := migration.MigrationControl{}
 	err = s.recv(&msg)
 	if err != nil {
@@ -339,7 +341,14 @@ func (c *migrationSink) DoStorage(state *state.State, projectName string, poolNa
 			}
 		}
 
-		return pool.CreateCustomVolumeFromMigration(projectName, conn, volTargetArgs, op)
+		stats, err := pool.CreateCustomVolumeFromMigration(projectName, conn, volTargetArgs, op)
+		if err != nil {
+			return err
+		}
+
+		logger.Debug("Migration sink finished storing storage volume", stats.LogCtx())
+
+		return nil
 	}
 
 	if c.refresh {
@@ -0,0 +1,92 @@
+package migration
+
+import (
+	"io"
+	"sync/atomic"
+	"time"
+
+	"github.com/canonical/lxd/shared/logger"
+)
+
+// VolumeStats represents statistics gathered while transferring a volume.
+// It is returned by the storage pool migration methods even when the transfer fails, in which case it contains
+// partial values.
+type VolumeStats struct {
+	FSType        MigrationFSType // Transport mode negotiated for the transfer.
+	BytesSent     int64           // Bytes written to the migration connection.
+	BytesReceived int64           // Bytes read from the migration connection.
+	Duration      time.Duration   // Time spent transferring.
+	Retries       int             // Number of times the volume was transferred again, such as for a final sync.
+
+	start time.Time
+}
+
+// Track records the transport mode and start time of a transfer and returns the connection wrapped so that
+// the bytes going through it are accounted for.
+func (s *VolumeStats) Track(migrationType Type, conn io.ReadWriteCloser) io.ReadWriteCloser {
+	s.FSType = migrationType.FSType
+	s.start = time.Now()
+
+	return &statsConn{ReadWriteCloser: conn, stats: s}
+}
+
+// Finish records the duration of the transfer.
+func (s *VolumeStats) Finish() {
+	if s.start.IsZero() {
+		return
+	}
+
+	s.Duration = time.Since(s.start)
+}
+
+// Add accounts for a further transfer of the same volume over the migration connection, such as the final sync
+// of a multi sync migration, by adding its statistics and counting it as a retry.
+func (s *VolumeStats) Add(other *VolumeStats) {
+	if other == nil {
+		return
+	}
+
+	atomic.AddInt64(&s.BytesSent, other.Sent())
+	atomic.AddInt64(&s.BytesReceived, other.Received())
+	s.Duration += other.Duration
+	s.Retries += other.Retries + 1
+}
+
+// LogCtx returns the statistics as logging context.
+func (s *VolumeStats) LogCtx() logger.Ctx {
+	return logger.Ctx{"fsType": s.FSType.String(), "bytesSent": s.Sent(), "bytesReceived": s.Received(), "duration": s.Duration, "retries": s.Retries}
+}
+
+// Sent returns the number of bytes written to the migration connection so far.
+func (s *VolumeStats) Sent() int64 {
+	return atomic.LoadInt64(&s.BytesSent)
+}
+
+// Received returns the number of bytes read from the migration connection so far.
+func (s *VolumeStats) Received() int64 {
+	return atomic.LoadInt64(&s.BytesReceived)
+}
+
+// statsConn counts the bytes read and written through a migration connection.
+// Reads and writes may happen concurrently (e.g. bidirectional rsync) so counters are updated atomically.
+type statsConn struct {
+	io.ReadWriteCloser
+
+	stats *VolumeStats
+}
+
+// Read reads from the wrapped connection and accounts for the bytes received.
+func (c *statsConn) Read(p []byte) (int, error) {
+	n, err := c.ReadWriteCloser.Read(p)
+	atomic.AddInt64(&c.stats.BytesReceived, int64(n))
+
+	return n, err
+}
+
+// Write writes to the wrapped connection and accounts for the bytes sent.
+func (c *statsConn) Write(p []byte) (int, error) {
+	n, err := c.ReadWriteCloser.Write(p)
+	atomic.AddInt64(&c.stats.BytesSent, int64(n))
+
+	return n, err
+}
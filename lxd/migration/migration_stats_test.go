@@ -0,0 +1,81 @@
+package migration
+
+import (
+	"bytes"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// bufferConn is an in-memory connection used to simulate a migration transfer.
+type bufferConn struct {
+	in  *bytes.Reader
+	out bytes.Buffer
+}
+
+func (c *bufferConn) Read(p []byte) (int, error) {
+	return c.in.Read(p)
+}
+
+func (c *bufferConn) Write(p []byte) (int, error) {
+	return c.out.Write(p)
+}
+
+func (c *bufferConn) Close() error {
+	return nil
+}
+
+// Test VolumeStats records the transfer type and byte counts of a simulated transfer.
+func TestVolumeStats(t *testing.T) {
+	stats := &VolumeStats{}
+	raw := &bufferConn{in: bytes.NewReader(make([]byte, 512))}
+
+	conn := stats.Track(Type{FSType: MigrationFSType_RSYNC}, raw)
+
+	_, err := io.Copy(conn, bytes.NewReader(make([]byte, 4096)))
+	assert.NoError(t, err)
+
+	_, err = io.Copy(io.Discard, conn)
+	assert.NoError(t, err)
+
+	stats.Finish()
+
+	assert.Equal(t, MigrationFSType_RSYNC, stats.FSType)
+	assert.Equal(t, int64(4096), stats.Sent())
+	assert.Equal(t, int64(512), stats.Received())
+	assert.Equal(t, 4096, raw.out.Len())
+	assert.Greater(t, stats.Duration.Nanoseconds(), int64(0))
+}
+
+// Test VolumeStats keeps partial statistics when the transfer fails.
+func TestVolumeStats_Partial(t *testing.T) {
+	stats := &VolumeStats{}
+	conn := stats.Track(Type{FSType: MigrationFSType_BLOCK_AND_RSYNC}, &bufferConn{in: bytes.NewReader(make([]byte, 100))})
+
+	buf := make([]byte, 64)
+	_, err := conn.Read(buf)
+	assert.NoError(t, err)
+
+	stats.Finish()
+
+	assert.Equal(t, MigrationFSType_BLOCK_AND_RSYNC, stats.FSType)
+	assert.Equal(t, int64(64), stats.Received())
+	assert.Equal(t, int64(0), stats.Sent())
+}
+
+// Test VolumeStats accumulates further transfers of the same volume as retries.
+func TestVolumeStats_Add(t *testing.T) {
+	stats := &VolumeStats{FSType: MigrationFSType_ZFS, BytesSent: 100, BytesReceived: 10, Duration: time.Second}
+
+	stats.Add(&VolumeStats{FSType: MigrationFSType_ZFS, BytesSent: 50, BytesReceived: 5, Duration: time.Second})
+	stats.Add(nil)
+
+	assert.Equal(t, MigrationFSType_ZFS, stats.FSType)
+	assert.Equal(t, int64(150), stats.Sent())
+	assert.Equal(t, int64(15), stats.Received())
+	assert.Equal(t, 2*time.Second, stats.Duration)
+	assert.Equal(t, 1, stats.Retries)
+	assert.Equal(t, 1, stats.LogCtx()["retries"])
+}
@@ -3,6 +3,9 @@ package node
 import (
 	"context"
 	"fmt"
+	"path/filepath"
+
+	"github.com/kballard/go-shellquote"
 
 	"github.com/canonical/lxd/lxd/config"
 	"github.com/canonical/lxd/lxd/db"
@@ -118,6 +121,16 @@ func (c *Config) StorageImagesVolume() string {
 	return c.m.GetString("storage.images_volume")
 }
 
+// StorageLVMPostCreateHook returns the command to run after creating the logical volume of an instance.
+func (c *Config) StorageLVMPostCreateHook() string {
+	return c.m.GetString("storage.lvm.post_create_hook")
+}
+
+// StorageLVMPreDeleteHook returns the command to run before deleting the logical volume of an instance.
+func (c *Config) StorageLVMPreDeleteHook() string {
+	return c.m.GetString("storage.lvm.pre_delete_hook")
+}
+
 // SyslogSocket returns true if the syslog socket is enabled, otherwise false.
 func (c *Config) SyslogSocket() bool {
 	return c.m.GetBool("core.syslog_socket")
@@ -278,4 +291,50 @@ var ConfigSchema = config.Schema{
 	//  scope: local
 	//  shortdesc: Volume to use to store the image tarballs
 	"storage.images_volume": {},
+
+	// LVM volume hooks
+
+	// lxdmeta:generate(entities=server; group=miscellaneous; key=storage.lvm.post_create_hook)
+	// The command is run as root on this server after the logical volume of an instance is created on an
+	// LVM storage pool, for example to register it in an external system. It is split into arguments using
+	// shell quoting rules but isn't run through a shell, so it must start with the absolute path of the
+	// program to run.
+	// The `LXD_POOL`, `LXD_VOLUME_NAME`, `LXD_VOLUME_TYPE` and `LXD_LV_PATH` environment variables
+	// describe the volume. The instance volume is removed again if the command fails or doesn't complete
+	// within five minutes.
+	// ---
+	//  type: string
+	//  scope: local
+	//  shortdesc: Command to run after creating an LVM instance volume
+	"storage.lvm.post_create_hook": {Validator: validate.Optional(validateHookCommand)},
+	// lxdmeta:generate(entities=server; group=miscellaneous; key=storage.lvm.pre_delete_hook)
+	// The command is run as root on this server before the logical volume of an instance is removed from an
+	// LVM storage pool, with the same arguments handling and environment variables as
+	// {config:option}`server-miscellaneous:storage.lvm.post_create_hook`.
+	// The command is killed if it doesn't complete within five minutes. A failure of the command is logged
+	// but doesn't prevent the volume from being removed.
+	// ---
+	//  type: string
+	//  scope: local
+	//  shortdesc: Command to run before deleting an LVM instance volume
+	"storage.lvm.pre_delete_hook": {Validator: validate.Optional(validateHookCommand)},
+}
+
+// validateHookCommand validates a hook command. The command is split into arguments using shell quoting rules
+// but isn't run through a shell, so the first argument must be the absolute path of the program to run.
+func validateHookCommand(value string) error {
+	args, err := shellquote.Split(value)
+	if err != nil {
+		return fmt.Errorf("Invalid command: %w", err)
+	}
+
+	if len(args) == 0 {
+		return fmt.Errorf("Command cannot be empty")
+	}
+
+	if !filepath.IsAbs(args[0]) {
+		return fmt.Errorf("Command %q must be an absolute path", args[0])
+	}
+
+	return nil
 }
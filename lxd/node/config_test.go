@@ -170,3 +170,24 @@ func TestClusterAddress(t *testing.T) {
 
 	assert.Equal(t, "127.0.0.1:666", nodeConfig.ClusterAddress())
 }
+
+// The LVM volume hooks must be commands starting with the absolute path of the program to run.
+func TestConfig_StorageLVMHooks(t *testing.T) {
+	tx, cleanup := db.NewTestNodeTx(t)
+	defer cleanup()
+
+	config, err := node.ConfigLoad(context.Background(), tx)
+	require.NoError(t, err)
+
+	_, err = config.Patch(map[string]any{"storage.lvm.post_create_hook": "register --volume"})
+	assert.ErrorContains(t, err, `Command "register" must be an absolute path`)
+
+	_, err = config.Patch(map[string]any{"storage.lvm.pre_delete_hook": "/bin/echo 'unterminated"})
+	assert.ErrorContains(t, err, "Invalid command")
+
+	_, err = config.Patch(map[string]any{"storage.lvm.post_create_hook": "/usr/local/bin/register --volume"})
+	require.NoError(t, err)
+
+	assert.Equal(t, "/usr/local/bin/register --volume", config.StorageLVMPostCreateHook())
+	assert.Equal(t, "", config.StorageLVMPreDeleteHook())
+}
@@ -61,7 +61,104 @@ func rsync(args ...string) (string, error) {
 	return stdout.String(), nil
 }
 
+// outputTailLines is the number of lines of rsync output kept for error messages when the output is streamed.
+const outputTailLines = 50
+
+// outputLogger is an io.Writer that logs each line of rsync output as it is written and keeps the last lines.
+type outputLogger struct {
+	stream  string
+	partial []byte
+	lines   []string
+}
+
+// Write logs the complete lines in p and keeps any trailing partial line until it is completed.
+// Carriage returns are treated as line endings so that progress output doesn't accumulate.
+func (l *outputLogger) Write(p []byte) (int, error) {
+	l.partial = append(l.partial, p...)
+
+	for {
+		i := bytes.IndexAny(l.partial, "\r\n")
+		if i < 0 {
+			break
+		}
+
+		l.addLine(string(l.partial[:i]))
+		l.partial = l.partial[i+1:]
+	}
+
+	return len(p), nil
+}
+
+// addLine logs a line of output and adds it to the kept lines.
+func (l *outputLogger) addLine(line string) {
+	if line == "" {
+		return
+	}
+
+	logger.Debug("rsync output", logger.Ctx{"stream": l.stream, "line": line})
+
+	l.lines = append(l.lines, line)
+	if len(l.lines) > outputTailLines {
+		l.lines = l.lines[1:]
+	}
+}
+
+// Tail returns the last lines of output, including any trailing partial line.
+func (l *outputLogger) Tail() *bytes.Buffer {
+	if len(l.partial) > 0 {
+		l.addLine(string(l.partial))
+		l.partial = nil
+	}
+
+	var buf bytes.Buffer
+	for _, line := range l.lines {
+		buf.WriteString(line + "\n")
+	}
+
+	return &buf
+}
+
+// rsyncStreamed is like rsync but logs the output as it is produced rather than buffering all of it.
+// Only the last lines of stdout are returned and included in errors.
+func rsyncStreamed(args ...string) (string, error) {
+	if len(args) < 2 {
+		return "", fmt.Errorf("rsync call expects a minimum of two arguments (source and destination)")
+	}
+
+	// Setup the command.
+	cmd := exec.Command("rsync", args...)
+	stdout := &outputLogger{stream: "stdout"}
+	cmd.Stdout = stdout
+	stderr := &outputLogger{stream: "stderr"}
+	cmd.Stderr = stderr
+
+	// Call the wrapper if defined.
+	if RunWrapper != nil {
+		source := args[len(args)-2]
+		destination := args[len(args)-1]
+
+		cleanup, err := RunWrapper(cmd, source, destination)
+		if err != nil {
+			return "", err
+		}
+
+		defer cleanup()
+	}
+
+	// Run the command.
+	err := cmd.Run()
+	stdoutTail := stdout.Tail()
+	if err != nil {
+		return stdoutTail.String(), shared.NewRunError("rsync", args, err, stdoutTail, stderr.Tail())
+	}
+
+	return stdoutTail.String(), nil
+}
+
 // LocalCopy copies a directory using rsync (with the --devices option).
+// Hard links, ACLs and the numeric ownership are preserved. If xattrs is set, the extended attributes are too,
+// except for security.selinux when supported by rsync, as the labels are specific to the source.
+// The rsync output is logged as it is produced and only its last lines are returned.
 func LocalCopy(source string, dest string, bwlimit string, xattrs bool, rsyncArgs ...string) (string, error) {
 	err := os.MkdirAll(dest, 0755)
 	if err != nil {
@@ -104,7 +201,7 @@ func LocalCopy(source string, dest string, bwlimit string, xattrs bool, rsyncArg
 		shared.AddSlash(source),
 		dest)
 
-	msg, err := rsync(args...)
+	msg, err := rsyncStreamed(args...)
 	if err != nil {
 		runError, ok := err.(shared.RunError)
 		if ok {
@@ -122,6 +219,69 @@ func LocalCopy(source string, dest string, bwlimit string, xattrs bool, rsyncArg
 	return msg, nil
 }
 
+// LocalVerify checks that dest is identical to source following a LocalCopy with the same xattrs and rsyncArgs.
+// It does a dry run of the copy comparing the checksums of the file contents, so that files where the copy was
+// truncated (for example, by running out of space) are detected. An error listing the differences found is
+// returned if the directories diverge.
+func LocalVerify(source string, dest string, xattrs bool, rsyncArgs ...string) error {
+	args := []string{
+		"-a",
+		"-HA",
+		"--devices",
+		"--delete",
+		"--numeric-ids",
+		"--checksum",
+		"--dry-run",
+		"--itemize-changes",
+	}
+
+	if xattrs {
+		args = append(args, "--xattrs")
+		if AtLeast("3.1.3") {
+			args = append(args, "--filter=-x security.selinux")
+		}
+	}
+
+	if len(rsyncArgs) > 0 {
+		args = append(args, rsyncArgs...)
+	}
+
+	args = append(args, shared.AddSlash(source), dest)
+
+	msg, err := rsync(args...)
+	if err != nil {
+		return fmt.Errorf("Failed verifying copy of %q: %w", source, err)
+	}
+
+	changes := parseItemizedChanges(msg)
+	if len(changes) > 0 {
+		// Don't flood the error with a full listing.
+		if len(changes) > 10 {
+			changes = append(changes[:10], fmt.Sprintf("... (%d more)", len(changes)-10))
+		}
+
+		return fmt.Errorf("Copy of %q differs from source: %s", source, strings.Join(changes, ", "))
+	}
+
+	return nil
+}
+
+// parseItemizedChanges returns the changes listed in the output of rsync --itemize-changes.
+func parseItemizedChanges(output string) []string {
+	var changes []string
+
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		changes = append(changes, line)
+	}
+
+	return changes
+}
+
 // Send sets up the sending half of an rsync, to recursively send the
 // directory pointed to by path over the websocket.
 func Send(name string, path string, conn io.ReadWriteCloser, tracker *ioprogress.ProgressTracker, features []string, bwlimit string, execPath string, rsyncArgs ...string) error {
@@ -308,6 +468,8 @@ func Recv(path string, conn io.ReadWriteCloser, tracker *ioprogress.ProgressTrac
 		"-vlogDtpre.iLsfx",
 		"--numeric-ids",
 		"--devices",
+		// Keep partially transferred files if the transfer is interrupted, so that a refresh of the volume
+		// uses them as the basis of the transfer rather than sending those files again in full.
 		"--partial",
 		"--sparse",
 		// This flag is only required on the receiving end.
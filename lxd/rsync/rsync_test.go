@@ -0,0 +1,108 @@
+package rsync
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/sys/unix"
+)
+
+// Test LocalCopy preserves the extended attributes when requested.
+func TestLocalCopy_Xattrs(t *testing.T) {
+	_, err := exec.LookPath("rsync")
+	if err != nil {
+		t.Skip("rsync isn't available")
+	}
+
+	source := t.TempDir()
+	dest := filepath.Join(t.TempDir(), "dest")
+
+	err = os.WriteFile(filepath.Join(source, "file"), []byte("content"), 0644)
+	require.NoError(t, err)
+
+	err = unix.Setxattr(filepath.Join(source, "file"), "user.lxd.test", []byte("value"), 0)
+	if err != nil {
+		t.Skipf("Extended attributes aren't supported: %v", err)
+	}
+
+	_, err = LocalCopy(source, dest, "", true)
+	require.NoError(t, err)
+
+	buf := make([]byte, 64)
+	n, err := unix.Getxattr(filepath.Join(dest, "file"), "user.lxd.test", buf)
+	require.NoError(t, err)
+	assert.Equal(t, "value", string(buf[:n]))
+
+	// Without xattrs, the attribute isn't copied.
+	dest = filepath.Join(t.TempDir(), "dest")
+
+	_, err = LocalCopy(source, dest, "", false)
+	require.NoError(t, err)
+
+	_, err = unix.Getxattr(filepath.Join(dest, "file"), "user.lxd.test", buf)
+	assert.ErrorIs(t, err, unix.ENODATA)
+}
+
+// Test parseItemizedChanges lists each change reported by rsync.
+func TestParseItemizedChanges(t *testing.T) {
+	output := ">fc........ rootfs/etc/hostname\n*deleting   rootfs/tmp/file\n\n"
+
+	assert.Equal(t, []string{">fc........ rootfs/etc/hostname", "*deleting   rootfs/tmp/file"}, parseItemizedChanges(output))
+	assert.Empty(t, parseItemizedChanges(""))
+}
+
+// Test LocalVerify detects a copy whose content differs from the source.
+func TestLocalVerify(t *testing.T) {
+	_, err := exec.LookPath("rsync")
+	if err != nil {
+		t.Skip("rsync isn't available")
+	}
+
+	source := t.TempDir()
+	dest := filepath.Join(t.TempDir(), "dest")
+
+	err = os.WriteFile(filepath.Join(source, "file"), []byte("content"), 0644)
+	require.NoError(t, err)
+
+	_, err = LocalCopy(source, dest, "", false)
+	require.NoError(t, err)
+
+	assert.NoError(t, LocalVerify(source, dest, false))
+
+	// Corrupt the copy keeping its size and modification time.
+	info, err := os.Stat(filepath.Join(dest, "file"))
+	require.NoError(t, err)
+
+	err = os.WriteFile(filepath.Join(dest, "file"), []byte("CONTENT"), 0644)
+	require.NoError(t, err)
+
+	err = os.Chtimes(filepath.Join(dest, "file"), info.ModTime(), info.ModTime())
+	require.NoError(t, err)
+
+	assert.ErrorContains(t, LocalVerify(source, dest, false), "differs from source")
+}
+
+// Test outputLogger splits the output into lines and only keeps the last ones.
+func TestOutputLogger(t *testing.T) {
+	l := &outputLogger{stream: "stdout"}
+
+	_, err := l.Write([]byte("first\nsec"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"first"}, l.lines)
+
+	_, err = l.Write([]byte("ond\r\nprogress 50%\rprogress 100%\nlast"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"first", "second", "progress 50%", "progress 100%"}, l.lines)
+	assert.Equal(t, "first\nsecond\nprogress 50%\nprogress 100%\nlast\n", l.Tail().String())
+
+	for i := 0; i < outputTailLines*2; i++ {
+		_, err = l.Write([]byte("line\n"))
+		require.NoError(t, err)
+	}
+
+	assert.Len(t, l.lines, outputTailLines)
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"sync"
 	"sync/atomic"
@@ -46,6 +47,26 @@ func readStoragePoolDriversCache() ([]api.ServerStorageDriverInfo, map[string]st
 	return driverInfo, driversUsed
 }
 
+// storagePoolCheckConsistency logs the volumes that exist only in the database or only on the storage pool.
+func storagePoolCheckConsistency(pool storagePools.Pool) {
+	report, err := pool.CheckVolumeConsistency()
+	if err != nil {
+		if !errors.Is(err, storageDrivers.ErrNotSupported) {
+			logger.Warn("Failed checking storage pool consistency", logger.Ctx{"pool": pool.Name(), "err": err})
+		}
+
+		return
+	}
+
+	for _, vol := range report.MissingOnPool {
+		logger.Warn("Storage volume recorded in database not found on storage pool", logger.Ctx{"pool": pool.Name(), "project": vol.Project, "type": vol.Type, "volume": vol.Name})
+	}
+
+	for _, vol := range report.MissingInDatabase {
+		logger.Warn("Storage volume found on storage pool not recorded in database", logger.Ctx{"pool": pool.Name(), "project": vol.Project, "type": vol.Type, "volume": vol.Name})
+	}
+}
+
 func storageStartup(s *state.State) error {
 	// Update the storage drivers supported and used cache in api_1.0.go.
 	storagePoolDriversCacheUpdate(s)
@@ -98,6 +119,7 @@ func storageStartup(s *state.State) error {
 		}
 
 		logger.Info("Initialized storage pool", logger.Ctx{"pool": poolName})
+		storagePoolCheckConsistency(pool)
 		_ = warnings.ResolveWarningsByLocalNodeAndProjectAndTypeAndEntity(s.DB.Cluster, "", warningtype.StoragePoolUnvailable, entity.TypeStoragePool, int(pool.ID()))
 
 		return true
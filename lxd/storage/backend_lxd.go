@@ -55,6 +55,10 @@ import (
 var unavailablePools = make(map[string]struct{})
 var unavailablePoolsMu = sync.Mutex{}
 
+// imageUnpackLogInterval is the percentage of progress between log messages when unpacking an image outside
+// of an operation.
+const imageUnpackLogInterval = 10
+
 // instanceDiskVolumeEffectiveFields fields from the instance disks that are applied to the volume's effective
 // config (but not stored in the disk's volume database record).
 var instanceDiskVolumeEffectiveFields = []string{
@@ -99,6 +103,10 @@ func (b *lxdBackend) ValidateName(value string) error {
 		}
 	}
 
+	if drivers.IsTemporaryVolumeName(value) {
+		return fmt.Errorf("Storage name %q is reserved for temporary volumes", value)
+	}
+
 	return nil
 }
 
@@ -1185,7 +1193,7 @@ func (b *lxdBackend) CreateInstanceFromCopy(inst instance.Instance, src instance
 
 		// Start each side of the migration concurrently and collect any errors.
 		g.Go(func() error {
-			return srcPool.MigrateInstance(src, aEnd, &migration.VolumeSourceArgs{
+			_, err := srcPool.MigrateInstance(src, aEnd, &migration.VolumeSourceArgs{
 				IndexHeaderVersion: migration.IndexHeaderVersion,
 				Name:               src.Name(),
 				Snapshots:          snapshotNames,
@@ -1195,10 +1203,12 @@ func (b *lxdBackend) CreateInstanceFromCopy(inst instance.Instance, src instance
 				VolumeOnly:         !snapshots,
 				Info:               &migration.Info{Config: srcConfig},
 			}, op)
+
+			return err
 		})
 
 		g.Go(func() error {
-			return b.CreateInstanceFromMigration(inst, bEnd, migration.VolumeTargetArgs{
+			_, err := b.CreateInstanceFromMigration(inst, bEnd, migration.VolumeTargetArgs{
 				IndexHeaderVersion: migration.IndexHeaderVersion,
 				Name:               inst.Name(),
 				Snapshots:          snapshotNames,
@@ -1207,6 +1217,8 @@ func (b *lxdBackend) CreateInstanceFromCopy(inst instance.Instance, src instance
 				TrackProgress:      false,         // Do not use a progress tracker on receiver.
 				VolumeOnly:         !snapshots,
 			}, op)
+
+			return err
 		})
 
 		err = g.Wait()
@@ -1474,7 +1486,7 @@ func (b *lxdBackend) RefreshCustomVolume(projectName string, srcProjectName stri
 		aEndErrCh := make(chan error, 1)
 		bEndErrCh := make(chan error, 1)
 		go func() {
-			err := srcPool.MigrateCustomVolume(srcProjectName, aEnd, &migration.VolumeSourceArgs{
+			_, err := srcPool.MigrateCustomVolume(srcProjectName, aEnd, &migration.VolumeSourceArgs{
 				IndexHeaderVersion: migration.IndexHeaderVersion,
 				Name:               srcConfig.Volume.Name,
 				Snapshots:          snapshotNames,
@@ -1492,7 +1504,7 @@ func (b *lxdBackend) RefreshCustomVolume(projectName string, srcProjectName stri
 		}()
 
 		go func() {
-			err := b.CreateCustomVolumeFromMigration(projectName, bEnd, migration.VolumeTargetArgs{
+			_, err := b.CreateCustomVolumeFromMigration(projectName, bEnd, migration.VolumeTargetArgs{
 				IndexHeaderVersion: migration.IndexHeaderVersion,
 				Name:               volName,
 				Description:        desc,
@@ -1724,7 +1736,7 @@ func (b *lxdBackend) RefreshInstance(inst instance.Instance, src instance.Instan
 
 		// Start each side of the migration concurrently and collect any errors.
 		g.Go(func() error {
-			return srcPool.MigrateInstance(src, aEnd, &migration.VolumeSourceArgs{
+			_, err := srcPool.MigrateInstance(src, aEnd, &migration.VolumeSourceArgs{
 				IndexHeaderVersion: migration.IndexHeaderVersion,
 				Name:               src.Name(),
 				Snapshots:          snapshotNames,
@@ -1735,10 +1747,12 @@ func (b *lxdBackend) RefreshInstance(inst instance.Instance, src instance.Instan
 				Info:               &migration.Info{Config: srcConfig},
 				VolumeOnly:         !snapshots,
 			}, op)
+
+			return err
 		})
 
 		g.Go(func() error {
-			return b.CreateInstanceFromMigration(inst, bEnd, migration.VolumeTargetArgs{
+			_, err := b.CreateInstanceFromMigration(inst, bEnd, migration.VolumeTargetArgs{
 				IndexHeaderVersion: migration.IndexHeaderVersion,
 				Name:               inst.Name(),
 				Snapshots:          snapshotNames,
@@ -1747,6 +1761,8 @@ func (b *lxdBackend) RefreshInstance(inst instance.Instance, src instance.Instan
 				TrackProgress:      false, // Do not use a progress tracker on receiver.
 				VolumeOnly:         !snapshots,
 			}, op)
+
+			return err
 		})
 
 		err = g.Wait()
@@ -1769,6 +1785,15 @@ func (b *lxdBackend) RefreshInstance(inst instance.Instance, src instance.Instan
 	return nil
 }
 
+// imageRootfsSizeConfig returns the config holding the image size recorded in an image volume, if any.
+func imageRootfsSizeConfig(imgVol drivers.Volume) map[string]string {
+	if imgVol.Config()["volatile.rootfs.size"] == "" {
+		return nil
+	}
+
+	return map[string]string{"volatile.rootfs.size": imgVol.Config()["volatile.rootfs.size"]}
+}
+
 // imageFiller returns a function that can be used as a filler function with CreateVolume().
 // The function returned will unpack the specified image archive into the specified mount path
 // provided, and for VM images, a raw root block path is required to unpack the qcow2 image into.
@@ -1782,6 +1807,19 @@ func (b *lxdBackend) imageFiller(fingerprint string, op *operations.Operation) f
 					shared.SetProgressMetadata(metadata, "create_instance_from_image_unpack", "Unpack", percent, 0, speed)
 					_ = op.UpdateMetadata(metadata)
 				}}
+		} else {
+			// Without an operation to report to, log the unpack progress at intervals so that
+			// unpacking a large image doesn't look like it has stalled.
+			var lastPercent int64
+			tracker = &ioprogress.ProgressTracker{
+				Handler: func(percent, speed int64) {
+					if percent < lastPercent+imageUnpackLogInterval && percent < 100 {
+						return
+					}
+
+					lastPercent = percent
+					b.logger.Debug("Unpacking image", logger.Ctx{"fingerprint": fingerprint, "percent": percent, "speed": units.GetByteSizeString(speed, 2) + "/s"})
+				}}
 		}
 
 		imageFile := shared.VarPath("images", fingerprint)
@@ -1947,23 +1985,25 @@ func (b *lxdBackend) CreateInstanceFromImage(inst instance.Instance, fingerprint
 
 // CreateInstanceFromMigration receives an instance being migrated.
 // The args.Name and args.Config fields are ignored and, instance properties are used instead.
-func (b *lxdBackend) CreateInstanceFromMigration(inst instance.Instance, conn io.ReadWriteCloser, args migration.VolumeTargetArgs, op *operations.Operation) error {
+func (b *lxdBackend) CreateInstanceFromMigration(inst instance.Instance, conn io.ReadWriteCloser, args migration.VolumeTargetArgs, op *operations.Operation) (*migration.VolumeStats, error) {
 	l := b.logger.AddContext(logger.Ctx{"project": inst.Project().Name, "instance": inst.Name(), "args": fmt.Sprintf("%+v", args)})
 	l.Debug("CreateInstanceFromMigration started")
 	defer l.Debug("CreateInstanceFromMigration finished")
 
+	stats := &migration.VolumeStats{FSType: args.MigrationType.FSType}
+
 	err := b.isStatusReady()
 	if err != nil {
-		return err
+		return stats, err
 	}
 
 	if args.Config != nil {
-		return fmt.Errorf("Migration VolumeTargetArgs.Config cannot be set for instances")
+		return stats, fmt.Errorf("Migration VolumeTargetArgs.Config cannot be set for instances")
 	}
 
 	volType, err := InstanceTypeToVolumeType(inst.Type())
 	if err != nil {
-		return err
+		return stats, err
 	}
 
 	contentType := InstanceContentType(inst)
@@ -1973,7 +2013,7 @@ func (b *lxdBackend) CreateInstanceFromMigration(inst instance.Instance, conn io
 	// caller if the instance DB record already exists).
 	srcInfo, err := b.migrationIndexHeaderReceive(l, args.IndexHeaderVersion, conn, args.Refresh)
 	if err != nil {
-		return err
+		return stats, err
 	}
 
 	var volumeDescription string
@@ -1982,7 +2022,7 @@ func (b *lxdBackend) CreateInstanceFromMigration(inst instance.Instance, conn io
 	// Check if the volume exists in database
 	dbVol, err := VolumeDBGet(b, inst.Project().Name, inst.Name(), volType)
 	if err != nil && !response.IsNotFoundError(err) {
-		return err
+		return stats, err
 	}
 
 	// Prefer using existing volume config (to allow mounting existing volume correctly).
@@ -2018,30 +2058,30 @@ func (b *lxdBackend) CreateInstanceFromMigration(inst instance.Instance, conn io
 
 		err = b.driver.FillVolumeConfig(vol)
 		if err != nil {
-			return fmt.Errorf("Failed filling volume config: %w", err)
+			return stats, fmt.Errorf("Failed filling volume config: %w", err)
 		}
 	}
 
 	// Check if the volume exists on storage.
 	volExists, err := b.driver.HasVolume(vol)
 	if err != nil {
-		return err
+		return stats, err
 	}
 
 	// Check for inconsistencies between database and storage before continuing.
 	if dbVol == nil && volExists {
-		return fmt.Errorf("Volume already exists on storage but not in database")
+		return stats, fmt.Errorf("Volume already exists on storage but not in database")
 	}
 
 	if dbVol != nil && !volExists {
-		return fmt.Errorf("Volume exists in database but not on storage")
+		return stats, fmt.Errorf("Volume exists in database but not on storage")
 	}
 
 	// Consistency check for refresh mode.
 	// We expect that the args.Refresh setting will have already been set to false by the caller as part of
 	// detecting if the instance DB record exists or not. If we get here then something has gone wrong.
 	if args.Refresh && !volExists {
-		return fmt.Errorf("Cannot refresh volume, doesn't exist on migration target storage")
+		return stats, fmt.Errorf("Cannot refresh volume, doesn't exist on migration target storage")
 	}
 
 	revert := revert.New()
@@ -2050,14 +2090,14 @@ func (b *lxdBackend) CreateInstanceFromMigration(inst instance.Instance, conn io
 	if !args.Refresh {
 		if volExists {
 			if !isRemoteClusterMove {
-				return fmt.Errorf("Cannot create volume, already exists on migration target storage")
+				return stats, fmt.Errorf("Cannot create volume, already exists on migration target storage")
 			}
 		} else {
 			// Validate config and create database entry for new storage volume if not refreshing.
 			// Strip unsupported config keys (in case the export was made from a different type of storage pool).
 			err = VolumeDBCreate(b, inst.Project().Name, inst.Name(), volumeDescription, volType, false, vol.Config(), inst.CreationDate(), time.Time{}, contentType, true, true)
 			if err != nil {
-				return err
+				return stats, err
 			}
 
 			revert.Add(func() { _ = VolumeDBDelete(b, inst.Project().Name, inst.Name(), volType) })
@@ -2102,7 +2142,7 @@ func (b *lxdBackend) CreateInstanceFromMigration(inst instance.Instance, conn io
 			// Strip unsupported config keys (in case the export was made from a different type of storage pool).
 			err = VolumeDBCreate(b, inst.Project().Name, newSnapshotName, snapDescription, volType, true, snapVol.Config(), snapCreationDate, snapExpiryDate, contentType, true, true)
 			if err != nil {
-				return err
+				return stats, err
 			}
 
 			revert.Add(func() { _ = VolumeDBDelete(b, inst.Project().Name, newSnapshotName, volType) })
@@ -2112,7 +2152,7 @@ func (b *lxdBackend) CreateInstanceFromMigration(inst instance.Instance, conn io
 	// Generate the effective root device volume for instance.
 	err = b.applyInstanceRootDiskOverrides(inst, &vol)
 	if err != nil {
-		return err
+		return stats, err
 	}
 
 	// Override args.Name and args.Config to ensure volume is created based on instance.
@@ -2150,7 +2190,7 @@ func (b *lxdBackend) CreateInstanceFromMigration(inst instance.Instance, conn io
 					return err
 				})
 				if err != nil && !response.IsNotFoundError(err) {
-					return err
+					return stats, err
 				}
 
 				// Make sure that the image is available locally too (not guaranteed in clusters).
@@ -2172,7 +2212,7 @@ func (b *lxdBackend) CreateInstanceFromMigration(inst instance.Instance, conn io
 				// optimized storage, then it gets created first.
 				err = b.EnsureImage(preFiller.Fingerprint, op)
 				if err != nil {
-					return err
+					return stats, err
 				}
 			}
 		}
@@ -2182,14 +2222,14 @@ func (b *lxdBackend) CreateInstanceFromMigration(inst instance.Instance, conn io
 	// Afterwards load the volume from the snapshot to ensure the right ordering.
 	instSnapshots, err := inst.Snapshots()
 	if err != nil {
-		return err
+		return stats, err
 	}
 
 	targetSnapshots := make([]drivers.Volume, 0, len(instSnapshots))
 	for _, instSnapshot := range instSnapshots {
 		snap, err := VolumeDBGet(b, inst.Project().Name, instSnapshot.Name(), volType)
 		if err != nil {
-			return err
+			return stats, err
 		}
 
 		snapshotStorageName := project.Instance(inst.Project().Name, instSnapshot.Name())
@@ -2198,9 +2238,11 @@ func (b *lxdBackend) CreateInstanceFromMigration(inst instance.Instance, conn io
 
 	volCopy := drivers.NewVolumeCopy(vol, targetSnapshots...)
 
-	err = b.driver.CreateVolumeFromMigration(volCopy, conn, args, &preFiller, op)
+	err = b.driver.CreateVolumeFromMigration(volCopy, stats.Track(args.MigrationType, conn), args, &preFiller, op)
+	stats.Finish()
+	l.Debug("Volume transfer statistics", stats.LogCtx())
 	if err != nil {
-		return err
+		return stats, err
 	}
 
 	if !isRemoteClusterMove {
@@ -2209,18 +2251,18 @@ func (b *lxdBackend) CreateInstanceFromMigration(inst instance.Instance, conn io
 
 	err = b.ensureInstanceSymlink(inst.Type(), inst.Project().Name, inst.Name(), vol.MountPath())
 	if err != nil {
-		return err
+		return stats, err
 	}
 
 	if len(args.Snapshots) > 0 {
 		err = b.ensureInstanceSnapshotSymlink(inst.Type(), inst.Project().Name, inst.Name())
 		if err != nil {
-			return err
+			return stats, err
 		}
 	}
 
 	revert.Success()
-	return nil
+	return stats, nil
 }
 
 // RenameInstance renames the instance's root volume and any snapshot volumes.
@@ -2559,38 +2601,40 @@ func (b *lxdBackend) UpdateInstanceSnapshot(inst instance.Instance, newDesc stri
 
 // MigrateInstance sends an instance volume for migration.
 // The args.Name field is ignored and the name of the instance is used instead.
-func (b *lxdBackend) MigrateInstance(inst instance.Instance, conn io.ReadWriteCloser, args *migration.VolumeSourceArgs, op *operations.Operation) error {
+func (b *lxdBackend) MigrateInstance(inst instance.Instance, conn io.ReadWriteCloser, args *migration.VolumeSourceArgs, op *operations.Operation) (*migration.VolumeStats, error) {
 	l := b.logger.AddContext(logger.Ctx{"project": inst.Project().Name, "instance": inst.Name(), "args": fmt.Sprintf("%+v", args)})
 	l.Debug("MigrateInstance started")
 	defer l.Debug("MigrateInstance finished")
 
+	stats := &migration.VolumeStats{FSType: args.MigrationType.FSType}
+
 	volType, err := InstanceTypeToVolumeType(inst.Type())
 	if err != nil {
-		return err
+		return stats, err
 	}
 
 	contentType := InstanceContentType(inst)
 
 	if len(args.Snapshots) > 0 && args.FinalSync {
-		return fmt.Errorf("Snapshots should not be transferred during final sync")
+		return stats, fmt.Errorf("Snapshots should not be transferred during final sync")
 	}
 
 	if args.Info == nil {
-		return fmt.Errorf("Migration info required")
+		return stats, fmt.Errorf("Migration info required")
 	}
 
 	if args.Info.Config == nil || args.Info.Config.Volume == nil || args.Info.Config.Volume.Config == nil {
-		return fmt.Errorf("Volume config is required")
+		return stats, fmt.Errorf("Volume config is required")
 	}
 
 	if len(args.Snapshots) != len(args.Info.Config.VolumeSnapshots) {
-		return fmt.Errorf("Requested snapshots count (%d) doesn't match volume snapshot config count (%d)", len(args.Snapshots), len(args.Info.Config.VolumeSnapshots))
+		return stats, fmt.Errorf("Requested snapshots count (%d) doesn't match volume snapshot config count (%d)", len(args.Snapshots), len(args.Info.Config.VolumeSnapshots))
 	}
 
 	// Load storage volume from database.
 	dbVol, err := VolumeDBGet(b, inst.Project().Name, inst.Name(), volType)
 	if err != nil {
-		return err
+		return stats, err
 	}
 
 	// Generate the effective root device volume for instance.
@@ -2598,21 +2642,21 @@ func (b *lxdBackend) MigrateInstance(inst instance.Instance, conn io.ReadWriteCl
 	vol := b.GetVolume(volType, contentType, volStorageName, dbVol.Config)
 	err = b.applyInstanceRootDiskOverrides(inst, &vol)
 	if err != nil {
-		return err
+		return stats, err
 	}
 
 	// Retrieve a list of snapshots.
 	// Afterwards load the volume from the snapshot to ensure the right ordering.
 	instSnapshots, err := inst.Snapshots()
 	if err != nil {
-		return err
+		return stats, err
 	}
 
 	sourceSnapshots := make([]drivers.Volume, 0, len(instSnapshots))
 	for _, instSnapshot := range instSnapshots {
 		snap, err := VolumeDBGet(b, inst.Project().Name, instSnapshot.Name(), volType)
 		if err != nil {
-			return err
+			return stats, err
 		}
 
 		snapshotStorageName := project.Instance(inst.Project().Name, snap.Name)
@@ -2625,7 +2669,7 @@ func (b *lxdBackend) MigrateInstance(inst instance.Instance, conn io.ReadWriteCl
 	if !args.FinalSync {
 		resp, err := b.migrationIndexHeaderSend(l, args.IndexHeaderVersion, conn, args.Info)
 		if err != nil {
-			return err
+			return stats, err
 		}
 
 		if resp.Refresh != nil {
@@ -2647,7 +2691,7 @@ func (b *lxdBackend) MigrateInstance(inst instance.Instance, conn io.ReadWriteCl
 		b.logger.Info("Freezing instance for consistent migration transfer")
 		err = inst.Freeze()
 		if err != nil {
-			return err
+			return stats, err
 		}
 
 		defer func() { _ = inst.Unfreeze() }()
@@ -2658,12 +2702,14 @@ func (b *lxdBackend) MigrateInstance(inst instance.Instance, conn io.ReadWriteCl
 
 	volCopy := drivers.NewVolumeCopy(vol, sourceSnapshots...)
 
-	err = b.driver.MigrateVolume(volCopy, conn, args, op)
+	err = b.driver.MigrateVolume(volCopy, stats.Track(args.MigrationType, conn), args, op)
+	stats.Finish()
+	l.Debug("Volume transfer statistics", stats.LogCtx())
 	if err != nil {
-		return err
+		return stats, err
 	}
 
-	return nil
+	return stats, nil
 }
 
 // CleanupInstancePaths removes any remaining mount paths and symlinks for the instance and its snapshots.
@@ -2870,6 +2916,46 @@ func (b *lxdBackend) GetInstanceUsage(inst instance.Instance) (*VolumeUsage, err
 	return &val, nil
 }
 
+// GetInstanceDevice returns the block device backing the instance's root volume and whether it is mounted.
+// This is only supported on LVM storage pools.
+func (b *lxdBackend) GetInstanceDevice(inst instance.Instance) (*VolumeDevice, error) {
+	l := b.logger.AddContext(logger.Ctx{"project": inst.Project().Name, "instance": inst.Name()})
+	l.Debug("GetInstanceDevice started")
+	defer l.Debug("GetInstanceDevice finished")
+
+	err := b.isStatusReady()
+	if err != nil {
+		return nil, err
+	}
+
+	volType, err := InstanceTypeToVolumeType(inst.Type())
+	if err != nil {
+		return nil, err
+	}
+
+	contentType := InstanceContentType(inst)
+
+	// Load storage volume from database.
+	dbVol, err := VolumeDBGet(b, inst.Project().Name, inst.Name(), volType)
+	if err != nil {
+		return nil, err
+	}
+
+	volStorageName := project.Instance(inst.Project().Name, inst.Name())
+	vol := b.GetVolume(volType, contentType, volStorageName, dbVol.Config)
+
+	devPath, mounted, err := b.driver.GetVolumeDevice(vol)
+	if err != nil {
+		if errors.Is(err, drivers.ErrNotSupported) {
+			return nil, api.StatusErrorf(http.StatusBadRequest, "Instance %q isn't on an LVM storage pool (pool %q uses %q)", inst.Name(), b.name, b.driver.Info().Name)
+		}
+
+		return nil, err
+	}
+
+	return &VolumeDevice{Path: devPath, Mounted: mounted}, nil
+}
+
 // SetInstanceQuota sets the quota on the instance's root volume.
 // Returns ErrInUse if the instance is running and the storage driver doesn't support online resizing.
 func (b *lxdBackend) SetInstanceQuota(inst instance.Instance, size string, vmStateSize string, op *operations.Operation) error {
@@ -3089,6 +3175,10 @@ func (b *lxdBackend) CreateInstanceSnapshot(inst instance.Instance, src instance
 		return fmt.Errorf("Source instance cannot be a snapshot")
 	}
 
+	if drivers.IsTemporaryVolumeName(inst.Name()) {
+		return api.StatusErrorf(http.StatusBadRequest, "Snapshot name %q is reserved for temporary volumes", inst.Name())
+	}
+
 	// Check we can convert the instance to the volume type needed.
 	volType, err := InstanceTypeToVolumeType(inst.Type())
 	if err != nil {
@@ -3139,6 +3229,10 @@ func (b *lxdBackend) CreateInstanceSnapshot(inst instance.Instance, src instance
 
 		// Attempt to sync the filesystem.
 		_ = filesystem.SyncFS(src.RootfsPath())
+	} else if src.IsRunning() {
+		// The snapshot is taken while the instance keeps running, so at least flush the writes it has made so
+		// far to the volume to avoid them being missing from the snapshot.
+		_ = filesystem.SyncFS(src.RootfsPath())
 	}
 
 	// Lock this operation to ensure that the only one snapshot is made at the time.
@@ -3181,6 +3275,10 @@ func (b *lxdBackend) RenameInstanceSnapshot(inst instance.Instance, newName stri
 		return fmt.Errorf("New name cannot be a snapshot")
 	}
 
+	if drivers.IsTemporaryVolumeName(newName) {
+		return api.StatusErrorf(http.StatusBadRequest, "Snapshot name %q is reserved for temporary volumes", newName)
+	}
+
 	// Check we can convert the instance to the volume types needed.
 	volType, err := InstanceTypeToVolumeType(inst.Type())
 	if err != nil {
@@ -3414,6 +3512,20 @@ func (b *lxdBackend) RestoreInstanceSnapshot(inst instance.Instance, src instanc
 
 	err = b.driver.RestoreVolume(vol, snapVol, op)
 	if err != nil {
+		var snapLostErr drivers.ErrSnapshotLost
+		if errors.As(err, &snapLostErr) {
+			// The volume has been restored but the snapshot no longer exists, so delete what is left of it.
+			l.Warn("Deleting instance snapshot lost while restoring from it", logger.Ctx{"err": snapLostErr.Err})
+
+			err = src.Delete(true)
+			if err != nil {
+				return fmt.Errorf("Failed deleting lost instance snapshot %q: %w", src.Name(), err)
+			}
+
+			revert.Success()
+			return nil
+		}
+
 		snapErr, ok := err.(drivers.ErrDeleteSnapshots)
 		if ok {
 			// We need to delete some snapshots and try again.
@@ -3676,14 +3788,30 @@ func (b *lxdBackend) EnsureImage(fingerprint string, op *operations.Operation) e
 					return err
 				}
 
-				// Reset img volume variables as we just deleted the old one.
+				// Reset img volume variables as we just deleted the old one, keeping the recorded image size so
+				// that it doesn't need to be worked out again when unpacking the image.
 				imgDBVol = nil
-				imgVol = b.GetVolume(drivers.VolumeTypeImage, contentType, fingerprint, nil)
+				imgVol = b.GetVolume(drivers.VolumeTypeImage, contentType, fingerprint, imageRootfsSizeConfig(imgVol))
 			} else if err != nil {
 				return err
 			} else {
-				// We already have a valid volume at the correct size, just return.
-				return nil
+				// We already have a volume at the correct size, check that it is intact before reusing it
+				// so that a damaged image volume doesn't get copied into every new instance.
+				err = b.verifyImageVolume(imgVol, op)
+				if err == nil {
+					return nil
+				}
+
+				l.Warn("Cached image volume failed verification, regenerating image volume", logger.Ctx{"err": err})
+				err = b.DeleteImage(fingerprint, op)
+				if err != nil {
+					return err
+				}
+
+				// Reset img volume variables as we just deleted the old one, keeping the recorded image size so
+				// that it doesn't need to be worked out again when unpacking the image.
+				imgDBVol = nil
+				imgVol = b.GetVolume(drivers.VolumeTypeImage, contentType, fingerprint, imageRootfsSizeConfig(imgVol))
 			}
 		} else {
 			// We have an unrecorded on-disk volume, assume it's a partial unpack and delete it.
@@ -3736,6 +3864,35 @@ func (b *lxdBackend) EnsureImage(fingerprint string, op *operations.Operation) e
 	return nil
 }
 
+// verifyImageVolume checks that an existing image volume can be mounted and contains the files created when the
+// image was unpacked. Any error, including failing to mount the volume, means that the volume is damaged and needs
+// regenerating. Only block backed filesystem volumes are checked as their filesystem can be damaged independently
+// of the storage pool.
+func (b *lxdBackend) verifyImageVolume(imgVol drivers.Volume, op *operations.Operation) error {
+	if imgVol.ContentType() != drivers.ContentTypeFS || !imgVol.IsBlockBacked() {
+		return nil
+	}
+
+	return imgVol.MountTask(func(mountPath string, op *operations.Operation) error {
+		metadataPath := filepath.Join(mountPath, "metadata.yaml")
+		if !shared.PathExists(metadataPath) {
+			return fmt.Errorf("Image metadata file %q is missing", metadataPath)
+		}
+
+		rootfsPath := filepath.Join(mountPath, "rootfs")
+		entries, err := os.ReadDir(rootfsPath)
+		if err != nil {
+			return fmt.Errorf("Failed reading image root filesystem %q: %w", rootfsPath, err)
+		}
+
+		if len(entries) == 0 {
+			return fmt.Errorf("Image root filesystem %q is empty", rootfsPath)
+		}
+
+		return nil
+	}, op)
+}
+
 // shouldUseOptimizedImage determines if an optimized image should be used based on the provided volume config.
 // It returns true if the volume config aligns with the pool's default configuration, and an optimized image does
 // not exist or also matches the pool's default confgiuration.
@@ -5002,7 +5159,7 @@ func (b *lxdBackend) CreateCustomVolumeFromCopy(projectName string, srcProjectNa
 	aEndErrCh := make(chan error, 1)
 	bEndErrCh := make(chan error, 1)
 	go func() {
-		err := srcPool.MigrateCustomVolume(srcProjectName, aEnd, &migration.VolumeSourceArgs{
+		_, err := srcPool.MigrateCustomVolume(srcProjectName, aEnd, &migration.VolumeSourceArgs{
 			IndexHeaderVersion: migration.IndexHeaderVersion,
 			Name:               srcConfig.Volume.Name,
 			Snapshots:          snapshotNames,
@@ -5021,7 +5178,7 @@ func (b *lxdBackend) CreateCustomVolumeFromCopy(projectName string, srcProjectNa
 	}()
 
 	go func() {
-		err := b.CreateCustomVolumeFromMigration(projectName, bEnd, migration.VolumeTargetArgs{
+		_, err := b.CreateCustomVolumeFromMigration(projectName, bEnd, migration.VolumeTargetArgs{
 			IndexHeaderVersion: migration.IndexHeaderVersion,
 			Name:               volName,
 			Description:        desc,
@@ -5151,40 +5308,42 @@ func (b *lxdBackend) migrationIndexHeaderReceive(l logger.Logger, indexHeaderVer
 }
 
 // MigrateCustomVolume sends a volume for migration.
-func (b *lxdBackend) MigrateCustomVolume(projectName string, conn io.ReadWriteCloser, args *migration.VolumeSourceArgs, op *operations.Operation) error {
+func (b *lxdBackend) MigrateCustomVolume(projectName string, conn io.ReadWriteCloser, args *migration.VolumeSourceArgs, op *operations.Operation) (*migration.VolumeStats, error) {
 	l := b.logger.AddContext(logger.Ctx{"project": projectName, "volName": args.Name, "args": fmt.Sprintf("%+v", args)})
 	l.Debug("MigrateCustomVolume started")
 	defer l.Debug("MigrateCustomVolume finished")
 
+	stats := &migration.VolumeStats{FSType: args.MigrationType.FSType}
+
 	// Get the volume name on storage.
 	volStorageName := project.StorageVolume(projectName, args.Name)
 
 	dbContentType, err := VolumeContentTypeNameToContentType(args.ContentType)
 	if err != nil {
-		return err
+		return stats, err
 	}
 
 	contentType, err := VolumeDBContentTypeToContentType(dbContentType)
 	if err != nil {
-		return err
+		return stats, err
 	}
 
 	if args.Info == nil {
-		return fmt.Errorf("Migration info required")
+		return stats, fmt.Errorf("Migration info required")
 	}
 
 	if args.Info.Config == nil || args.Info.Config.Volume == nil || args.Info.Config.Volume.Config == nil {
-		return fmt.Errorf("Volume config is required")
+		return stats, fmt.Errorf("Volume config is required")
 	}
 
 	if len(args.Snapshots) != len(args.Info.Config.VolumeSnapshots) {
-		return fmt.Errorf("Requested snapshots count (%d) doesn't match volume snapshot config count (%d)", len(args.Snapshots), len(args.Info.Config.VolumeSnapshots))
+		return stats, fmt.Errorf("Requested snapshots count (%d) doesn't match volume snapshot config count (%d)", len(args.Snapshots), len(args.Info.Config.VolumeSnapshots))
 	}
 
 	// Send migration index header frame with volume info and wait for receipt.
 	resp, err := b.migrationIndexHeaderSend(l, args.IndexHeaderVersion, conn, args.Info)
 	if err != nil {
-		return err
+		return stats, err
 	}
 
 	if resp.Refresh != nil {
@@ -5196,7 +5355,7 @@ func (b *lxdBackend) MigrateCustomVolume(projectName string, conn io.ReadWriteCl
 	// Retrieve a list of snapshots.
 	allSourceSnapshots, err := VolumeDBSnapshotsGet(b, projectName, args.Name, drivers.VolumeTypeCustom)
 	if err != nil {
-		return err
+		return stats, err
 	}
 
 	sourceSnapshots := make([]drivers.Volume, 0, len(allSourceSnapshots))
@@ -5207,23 +5366,27 @@ func (b *lxdBackend) MigrateCustomVolume(projectName string, conn io.ReadWriteCl
 
 	volCopy := drivers.NewVolumeCopy(vol, sourceSnapshots...)
 
-	err = b.driver.MigrateVolume(volCopy, conn, args, op)
+	err = b.driver.MigrateVolume(volCopy, stats.Track(args.MigrationType, conn), args, op)
+	stats.Finish()
+	l.Debug("Volume transfer statistics", stats.LogCtx())
 	if err != nil {
-		return err
+		return stats, err
 	}
 
-	return nil
+	return stats, nil
 }
 
 // CreateCustomVolumeFromMigration receives a volume being migrated.
-func (b *lxdBackend) CreateCustomVolumeFromMigration(projectName string, conn io.ReadWriteCloser, args migration.VolumeTargetArgs, op *operations.Operation) error {
+func (b *lxdBackend) CreateCustomVolumeFromMigration(projectName string, conn io.ReadWriteCloser, args migration.VolumeTargetArgs, op *operations.Operation) (*migration.VolumeStats, error) {
 	l := b.logger.AddContext(logger.Ctx{"project": projectName, "volName": args.Name, "args": fmt.Sprintf("%+v", args)})
 	l.Debug("CreateCustomVolumeFromMigration started")
 	defer l.Debug("CreateCustomVolumeFromMigration finished")
 
+	stats := &migration.VolumeStats{FSType: args.MigrationType.FSType}
+
 	err := b.isStatusReady()
 	if err != nil {
-		return err
+		return stats, err
 	}
 
 	storagePoolSupported := false
@@ -5235,7 +5398,7 @@ func (b *lxdBackend) CreateCustomVolumeFromMigration(projectName string, conn io
 	}
 
 	if !storagePoolSupported {
-		return fmt.Errorf("Storage pool does not support custom volume type")
+		return stats, fmt.Errorf("Storage pool does not support custom volume type")
 	}
 
 	var volumeConfig map[string]string
@@ -5243,7 +5406,7 @@ func (b *lxdBackend) CreateCustomVolumeFromMigration(projectName string, conn io
 	// Check if the volume exists in database.
 	dbVol, err := VolumeDBGet(b, projectName, args.Name, drivers.VolumeTypeCustom)
 	if err != nil && !response.IsNotFoundError(err) {
-		return err
+		return stats, err
 	}
 
 	// Prefer using existing volume config (to allow mounting existing volume correctly).
@@ -5258,16 +5421,16 @@ func (b *lxdBackend) CreateCustomVolumeFromMigration(projectName string, conn io
 	vol := b.GetNewVolume(drivers.VolumeTypeCustom, drivers.ContentType(args.ContentType), volStorageName, volumeConfig)
 	volExists, err := b.driver.HasVolume(vol)
 	if err != nil {
-		return err
+		return stats, err
 	}
 
 	// Check for inconsistencies between database and storage before continuing.
 	if dbVol == nil && volExists {
-		return fmt.Errorf("Volume already exists on storage but not in database")
+		return stats, fmt.Errorf("Volume already exists on storage but not in database")
 	}
 
 	if dbVol != nil && !volExists {
-		return fmt.Errorf("Volume exists in database but not on storage")
+		return stats, fmt.Errorf("Volume exists in database but not on storage")
 	}
 
 	// Disable refresh mode if volume doesn't exist yet.
@@ -5276,7 +5439,7 @@ func (b *lxdBackend) CreateCustomVolumeFromMigration(projectName string, conn io
 	if args.Refresh && !volExists {
 		args.Refresh = false
 	} else if !args.Refresh && volExists {
-		return fmt.Errorf("Cannot create volume, already exists on migration target storage")
+		return stats, fmt.Errorf("Cannot create volume, already exists on migration target storage")
 	}
 
 	// VolumeSize is set to the actual size of the underlying block device.
@@ -5291,7 +5454,7 @@ func (b *lxdBackend) CreateCustomVolumeFromMigration(projectName string, conn io
 	// will set Refresh to false if the volume doesn't exist.
 	srcInfo, err := b.migrationIndexHeaderReceive(l, args.IndexHeaderVersion, conn, args.Refresh)
 	if err != nil {
-		return err
+		return stats, err
 	}
 
 	revert := revert.New()
@@ -5302,7 +5465,7 @@ func (b *lxdBackend) CreateCustomVolumeFromMigration(projectName string, conn io
 		// Strip unsupported config keys (in case the export was made from a different type of storage pool).
 		err = VolumeDBCreate(b, projectName, args.Name, args.Description, vol.Type(), false, vol.Config(), time.Now().UTC(), time.Time{}, vol.ContentType(), true, true)
 		if err != nil {
-			return err
+			return stats, err
 		}
 
 		revert.Add(func() { _ = VolumeDBDelete(b, projectName, args.Name, vol.Type()) })
@@ -5345,7 +5508,7 @@ func (b *lxdBackend) CreateCustomVolumeFromMigration(projectName string, conn io
 			// Strip unsupported config keys (in case the export was made from a different type of storage pool).
 			err = VolumeDBCreate(b, projectName, newSnapshotName, snapDescription, vol.Type(), true, snapVol.Config(), snapCreationDate, snapExpiryDate, vol.ContentType(), true, true)
 			if err != nil {
-				return err
+				return stats, err
 			}
 
 			revert.Add(func() { _ = VolumeDBDelete(b, projectName, newSnapshotName, vol.Type()) })
@@ -5355,7 +5518,7 @@ func (b *lxdBackend) CreateCustomVolumeFromMigration(projectName string, conn io
 	// Retrieve a list of target volume snapshots.
 	allTargetSnapshots, err := VolumeDBSnapshotsGet(b, projectName, args.Name, drivers.VolumeTypeCustom)
 	if err != nil {
-		return err
+		return stats, err
 	}
 
 	targetSnapshots := make([]drivers.Volume, 0, len(allTargetSnapshots))
@@ -5366,9 +5529,11 @@ func (b *lxdBackend) CreateCustomVolumeFromMigration(projectName string, conn io
 
 	volCopy := drivers.NewVolumeCopy(vol, targetSnapshots...)
 
-	err = b.driver.CreateVolumeFromMigration(volCopy, conn, args, nil, op)
+	err = b.driver.CreateVolumeFromMigration(volCopy, stats.Track(args.MigrationType, conn), args, nil, op)
+	stats.Finish()
+	l.Debug("Volume transfer statistics", stats.LogCtx())
 	if err != nil {
-		return err
+		return stats, err
 	}
 
 	eventCtx := logger.Ctx{"type": vol.Type()}
@@ -5379,7 +5544,7 @@ func (b *lxdBackend) CreateCustomVolumeFromMigration(projectName string, conn io
 	b.state.Events.SendLifecycle(projectName, lifecycle.StorageVolumeCreated.Event(vol, string(vol.Type()), projectName, op, eventCtx))
 
 	revert.Success()
-	return nil
+	return stats, nil
 }
 
 // RenameCustomVolume renames a custom volume and its snapshots.
@@ -5957,6 +6122,10 @@ func (b *lxdBackend) CreateCustomVolumeSnapshot(projectName, volName string, new
 		return fmt.Errorf("Snapshot name is not a valid snapshot name")
 	}
 
+	if drivers.IsTemporaryVolumeName(newSnapshotName) {
+		return api.StatusErrorf(http.StatusBadRequest, "Snapshot name %q is reserved for temporary volumes", newSnapshotName)
+	}
+
 	fullSnapshotName := drivers.GetSnapshotVolumeName(volName, newSnapshotName)
 
 	// Check snapshot volume doesn't exist already.
@@ -6051,6 +6220,10 @@ func (b *lxdBackend) RenameCustomVolumeSnapshot(projectName, volName string, new
 		return fmt.Errorf("Invalid new snapshot name")
 	}
 
+	if drivers.IsTemporaryVolumeName(newSnapshotName) {
+		return api.StatusErrorf(http.StatusBadRequest, "Snapshot name %q is reserved for temporary volumes", newSnapshotName)
+	}
+
 	volume, err := VolumeDBGet(b, projectName, volName, drivers.VolumeTypeCustom)
 	if err != nil {
 		return err
@@ -6210,6 +6383,21 @@ func (b *lxdBackend) RestoreCustomVolume(projectName, volName string, snapshotNa
 
 	err = b.driver.RestoreVolume(vol, snapVol, op)
 	if err != nil {
+		var snapLostErr drivers.ErrSnapshotLost
+		if errors.As(err, &snapLostErr) {
+			// The volume has been restored but the snapshot no longer exists, so delete what is left of it.
+			l.Warn("Deleting custom volume snapshot lost while restoring from it", logger.Ctx{"err": snapLostErr.Err})
+
+			err = b.DeleteCustomVolumeSnapshot(projectName, fullSnapshotName, op)
+			if err != nil {
+				return fmt.Errorf("Failed deleting lost custom volume snapshot %q: %w", fullSnapshotName, err)
+			}
+
+			b.state.Events.SendLifecycle(projectName, lifecycle.StorageVolumeRestored.Event(vol, string(vol.Type()), projectName, op, logger.Ctx{"snapshot": snapshotName}))
+
+			return nil
+		}
+
 		snapErr, ok := err.(drivers.ErrDeleteSnapshots)
 		if ok {
 			// We need to delete some snapshots and try again.
@@ -6499,6 +6687,61 @@ func (b *lxdBackend) CheckInstanceBackupFileSnapshots(backupConf *backupConfig.C
 	return backupConf.Snapshots, nil
 }
 
+// CheckVolumeConsistency compares the volumes recorded in the database for this member with the volumes that
+// exist on the storage pool and returns the differences. It doesn't modify either of them.
+// Snapshots are not considered. Returns drivers.ErrNotSupported if the driver cannot list its volumes.
+func (b *lxdBackend) CheckVolumeConsistency() (*VolumeConsistencyReport, error) {
+	poolVols, err := b.driver.ListVolumes()
+	if err != nil {
+		return nil, fmt.Errorf("Failed getting pool volumes: %w", err)
+	}
+
+	var dbVols []*db.StorageVolume
+
+	err = b.state.DB.Cluster.Transaction(context.TODO(), func(ctx context.Context, tx *db.ClusterTx) error {
+		poolID := b.ID()
+		dbVols, err = tx.GetStorageVolumes(ctx, true, db.StorageVolumeFilter{PoolID: &poolID})
+
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Failed getting database volumes: %w", err)
+	}
+
+	dbRefs := make([]VolumeReference, 0, len(dbVols))
+	for _, dbVol := range dbVols {
+		if shared.IsSnapshot(dbVol.Name) {
+			continue
+		}
+
+		volDBType, err := VolumeTypeNameToDBType(dbVol.Type)
+		if err != nil {
+			return nil, err
+		}
+
+		volType, err := VolumeDBTypeToType(volDBType)
+		if err != nil {
+			return nil, err
+		}
+
+		dbRefs = append(dbRefs, VolumeReference{Project: dbVol.Project, Type: volType, Name: dbVol.Name})
+	}
+
+	poolRefs := make([]VolumeReference, 0, len(poolVols))
+	for _, poolVol := range poolVols {
+		ref := VolumeReference{Project: api.ProjectDefaultName, Type: poolVol.Type(), Name: poolVol.Name()}
+
+		// Image volumes are shared by all projects and their names are not prefixed with the project.
+		if ref.Type != drivers.VolumeTypeImage {
+			ref.Project, ref.Name = project.StorageVolumeParts(poolVol.Name())
+		}
+
+		poolRefs = append(poolRefs, ref)
+	}
+
+	return compareVolumes(dbRefs, poolRefs), nil
+}
+
 // ListUnknownVolumes returns volumes that exist on the storage pool but don't have records in the database.
 // Returns the unknown volumes parsed/generated backup config in a slice (keyed on project name).
 func (b *lxdBackend) ListUnknownVolumes(op *operations.Operation) (map[string][]*backupConfig.Config, error) {
@@ -124,8 +124,8 @@ func (b *mockBackend) CreateInstanceFromImage(inst instance.Instance, fingerprin
 	return nil
 }
 
-func (b *mockBackend) CreateInstanceFromMigration(inst instance.Instance, conn io.ReadWriteCloser, args migration.VolumeTargetArgs, op *operations.Operation) error {
-	return nil
+func (b *mockBackend) CreateInstanceFromMigration(inst instance.Instance, conn io.ReadWriteCloser, args migration.VolumeTargetArgs, op *operations.Operation) (*migration.VolumeStats, error) {
+	return &migration.VolumeStats{}, nil
 }
 
 func (b *mockBackend) RenameInstance(inst instance.Instance, newName string, op *operations.Operation) error {
@@ -161,12 +161,16 @@ func (b *mockBackend) ListUnknownVolumes(op *operations.Operation) (map[string][
 	return nil, nil
 }
 
+func (b *mockBackend) CheckVolumeConsistency() (*VolumeConsistencyReport, error) {
+	return &VolumeConsistencyReport{}, nil
+}
+
 func (b *mockBackend) ImportInstance(inst instance.Instance, poolVol *backupConfig.Config, op *operations.Operation) (revert.Hook, error) {
 	return nil, nil
 }
 
-func (b *mockBackend) MigrateInstance(inst instance.Instance, conn io.ReadWriteCloser, args *migration.VolumeSourceArgs, op *operations.Operation) error {
-	return nil
+func (b *mockBackend) MigrateInstance(inst instance.Instance, conn io.ReadWriteCloser, args *migration.VolumeSourceArgs, op *operations.Operation) (*migration.VolumeStats, error) {
+	return &migration.VolumeStats{}, nil
 }
 
 func (b *mockBackend) CleanupInstancePaths(inst instance.Instance, op *operations.Operation) error {
@@ -189,6 +193,10 @@ func (b *mockBackend) GetInstanceUsage(inst instance.Instance) (*VolumeUsage, er
 	return nil, nil
 }
 
+func (b *mockBackend) GetInstanceDevice(inst instance.Instance) (*VolumeDevice, error) {
+	return nil, nil
+}
+
 func (b *mockBackend) SetInstanceQuota(inst instance.Instance, size string, vmStateSize string, op *operations.Operation) error {
 	return nil
 }
@@ -297,12 +305,12 @@ func (b *mockBackend) DeleteCustomVolume(projectName string, volName string, op
 	return nil
 }
 
-func (b *mockBackend) MigrateCustomVolume(projectName string, conn io.ReadWriteCloser, args *migration.VolumeSourceArgs, op *operations.Operation) error {
-	return nil
+func (b *mockBackend) MigrateCustomVolume(projectName string, conn io.ReadWriteCloser, args *migration.VolumeSourceArgs, op *operations.Operation) (*migration.VolumeStats, error) {
+	return &migration.VolumeStats{}, nil
 }
 
-func (b *mockBackend) CreateCustomVolumeFromMigration(projectName string, conn io.ReadWriteCloser, args migration.VolumeTargetArgs, op *operations.Operation) error {
-	return nil
+func (b *mockBackend) CreateCustomVolumeFromMigration(projectName string, conn io.ReadWriteCloser, args migration.VolumeTargetArgs, op *operations.Operation) (*migration.VolumeStats, error) {
+	return &migration.VolumeStats{}, nil
 }
 
 func (b *mockBackend) GetCustomVolumeDisk(projectName string, volName string) (string, error) {
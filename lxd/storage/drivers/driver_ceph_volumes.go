@@ -739,7 +739,7 @@ func (d *ceph) CreateVolumeFromMigration(vol VolumeCopy, conn io.ReadWriteCloser
 func (d *ceph) refreshVolume(vol VolumeCopy, srcVol VolumeCopy, refreshSnapshots []string, allowInconsistent bool, op *operations.Operation) (revert.Hook, error) {
 	// Copy volumes with content type filesystem using the generic approach.
 	if vol.contentType == ContentTypeFS {
-		return genericVFSCopyVolume(d, nil, vol, srcVol, refreshSnapshots, true, allowInconsistent, op)
+		return genericVFSCopyVolume(d, nil, vol, srcVol, refreshSnapshots, true, allowInconsistent, nil, op)
 	}
 
 	var lastCommonSnapshotName string
@@ -1148,7 +1148,7 @@ func (d *ceph) FillVolumeConfig(vol Volume) error {
 // commonVolumeRules returns validation rules which are common for pool and volume.
 func (d *ceph) commonVolumeRules() map[string]func(value string) error {
 	return map[string]func(value string) error{
-		// lxdmeta:generate(entities=storage-ceph,storage-lvm; group=volume-conf; key=block.filesystem)
+		// lxdmeta:generate(entities=storage-ceph; group=volume-conf; key=block.filesystem)
 		// Valid options are: `btrfs`, `ext4`, `xfs`
 		// If not set, `ext4` is assumed.
 		// ---
@@ -1499,7 +1499,7 @@ func (d *ceph) ListVolumes() ([]Volume, error) {
 		return nil, fmt.Errorf("Failed getting volume list: %v: %w", strings.TrimSpace(string(errMsg)), err)
 	}
 
-	volList := make([]Volume, len(vols))
+	volList := make([]Volume, 0, len(vols))
 	for _, v := range vols {
 		volList = append(volList, v)
 	}
@@ -1547,7 +1547,7 @@ func (d *ceph) MountVolume(vol Volume, op *operations.Operation) error {
 			}
 
 			mountFlags, mountOptions := filesystem.ResolveMountOptions(strings.Split(vol.ConfigBlockMountOptions(), ","))
-			err = TryMount(volDevPath, mountPath, fsType, mountFlags, mountOptions)
+			err = tryMountBlockFilesystem(d.logger, volDevPath, mountPath, fsType, mountFlags, mountOptions)
 			if err != nil {
 				return err
 			}
@@ -1697,7 +1697,7 @@ func (d *ceph) MigrateVolume(vol VolumeCopy, conn io.ReadWriteCloser, volSrcArgs
 
 		defer func() { _, _ = d.UnmountVolume(parentVol, false, op) }()
 
-		return genericVFSMigrateVolume(d, d.state, vol, conn, volSrcArgs, op)
+		return genericVFSMigrateVolume(d, d.state, vol, conn, volSrcArgs, nil, op)
 	} else if !shared.ValueInSlice(volSrcArgs.MigrationType.FSType, []migration.MigrationFSType{migration.MigrationFSType_RBD, migration.MigrationFSType_RBD_AND_RSYNC}) {
 		return ErrNotSupported
 	}
@@ -1849,7 +1849,7 @@ func (d *ceph) MigrateVolume(vol VolumeCopy, conn io.ReadWriteCloser, volSrcArgs
 
 // BackupVolume creates an exported version of a volume.
 func (d *ceph) BackupVolume(vol VolumeCopy, tarWriter *instancewriter.InstanceTarWriter, optimized bool, snapshots []string, op *operations.Operation) error {
-	return genericVFSBackupVolume(d, vol, tarWriter, snapshots, op)
+	return genericVFSBackupVolume(d, vol, tarWriter, snapshots, nil, op)
 }
 
 // CreateVolumeSnapshot creates a snapshot of a volume.
@@ -2032,7 +2032,7 @@ func (d *ceph) MountVolumeSnapshot(snapVol Volume, op *operations.Operation) err
 			}
 		}
 
-		err = TryMount(rbdDevPath, mountPath, RBDFilesystem, mountFlags, mountOptions)
+		err = tryMountBlockFilesystem(d.logger, rbdDevPath, mountPath, RBDFilesystem, mountFlags, mountOptions)
 		if err != nil {
 			return err
 		}
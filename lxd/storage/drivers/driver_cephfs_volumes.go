@@ -473,12 +473,12 @@ func (d *cephfs) RenameVolume(vol Volume, newVolName string, op *operations.Oper
 
 // MigrateVolume streams the volume (with or without snapshots).
 func (d *cephfs) MigrateVolume(vol VolumeCopy, conn io.ReadWriteCloser, volSrcArgs *migration.VolumeSourceArgs, op *operations.Operation) error {
-	return genericVFSMigrateVolume(d, d.state, vol, conn, volSrcArgs, op)
+	return genericVFSMigrateVolume(d, d.state, vol, conn, volSrcArgs, nil, op)
 }
 
 // BackupVolume creates an exported version of a volume.
 func (d *cephfs) BackupVolume(vol VolumeCopy, tarWriter *instancewriter.InstanceTarWriter, optimized bool, snapshots []string, op *operations.Operation) error {
-	return genericVFSBackupVolume(d, vol, tarWriter, snapshots, op)
+	return genericVFSBackupVolume(d, vol, tarWriter, snapshots, nil, op)
 }
 
 // CreateVolumeSnapshot creates a new snapshot.
@@ -373,6 +373,11 @@ func (d *common) GetVolumeDiskPath(vol Volume) (string, error) {
 	return "", ErrNotSupported
 }
 
+// GetVolumeDevice returns the block device backing a volume and whether the volume is mounted.
+func (d *common) GetVolumeDevice(vol Volume) (string, bool, error) {
+	return "", false, ErrNotSupported
+}
+
 // ListVolumes returns a list of LXD volumes in storage pool.
 func (d *common) ListVolumes() ([]Volume, error) {
 	return nil, ErrNotSupported
@@ -26,6 +26,7 @@ func (d *dir) load() error {
 		"storage_delete_old_snapshot_records":                nil,
 		"storage_zfs_drop_block_volume_filesystem_extension": nil,
 		"storage_prefix_bucket_names_with_project":           nil,
+		"storage_lvm_tag_volumes":                            nil,
 	}
 
 	return nil
@@ -6,8 +6,8 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
-	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	deviceConfig "github.com/canonical/lxd/lxd/device/config"
@@ -20,11 +20,125 @@ import (
 	"github.com/canonical/lxd/shared/validate"
 )
 
-const lvmVgPoolMarker = "lxd_pool" // Indicator tag used to mark volume groups as in use by LXD.
+const lvmVgPoolMarker = "lxd_pool"       // Indicator tag used to mark volume groups as in use by LXD.
+const lvmThinpoolMarker = "lxd_thinpool" // Indicator tag used to mark thin pools created by LXD.
+const lvmVolumeMarker = "lxd_volume"     // Indicator tag used to mark logical volumes managed by LXD.
+
+// lvmAllowedFilesystems are the filesystems that can be used for LVM volumes. As well as the filesystems supported
+// by the other block backed drivers, the older ext2 and ext3 filesystems are supported for compatibility.
+var lvmAllowedFilesystems = append([]string{"ext2", "ext3"}, blockBackedAllowedFilesystems...)
+
+// lvmFsckTimeout is how long the filesystem check done before mounting a volume can take.
+const lvmFsckTimeout = 5 * time.Minute
+
+// lvmVolumeHookTimeout is how long the storage.lvm.post_create_hook and storage.lvm.pre_delete_hook commands can
+// run for before they are killed.
+const lvmVolumeHookTimeout = 5 * time.Minute
 
 var lvmLoaded bool
 var lvmVersion string
 
+// lvmTools are the LVM commands used by the driver.
+var lvmTools = []string{"lvm", "lvchange", "lvconvert", "lvcreate", "lvextend", "lvremove", "lvrename", "lvresize", "lvs", "pvcreate", "pvremove", "pvresize", "pvs", "vgchange", "vgcreate", "vgremove", "vgrename", "vgs"}
+
+// lvmToolPaths maps the LVM commands to the executables resolved when the driver is loaded.
+var lvmToolPaths = map[string]string{}
+var lvmToolPathsMu sync.Mutex
+
+// lvmCommand returns the executable and arguments to run for the LVM command name. Commands that don't have an
+// executable of their own are run as a subcommand of the lvm executable once it has been resolved.
+// Names that haven't been resolved are returned as is so they are looked up in PATH.
+func lvmCommand(name string, args ...string) (string, []string) {
+	lvmToolPathsMu.Lock()
+	defer lvmToolPathsMu.Unlock()
+
+	path, ok := lvmToolPaths[name]
+	if ok {
+		return path, args
+	}
+
+	lvmPath, ok := lvmToolPaths["lvm"]
+	if ok && shared.ValueInSlice(name, lvmTools) {
+		return lvmPath, append([]string{name}, args...)
+	}
+
+	return name, args
+}
+
+// resolveLVMTools resolves the executables of the LVM commands. Only the lvm command is required, the other
+// commands are run through it when they don't have an executable of their own.
+// If set, the LXD_LVM_PATH environment variable is the directory the commands are taken from instead of PATH,
+// which allows using wrappers or commands installed in a non-standard location.
+func resolveLVMTools() (map[string]string, error) {
+	toolsDir := os.Getenv("LXD_LVM_PATH")
+
+	paths := make(map[string]string, len(lvmTools))
+	for _, tool := range lvmTools {
+		toolPath := tool
+		if toolsDir != "" {
+			toolPath = filepath.Join(toolsDir, tool)
+		}
+
+		path, err := exec.LookPath(toolPath)
+		if err != nil {
+			if tool == "lvm" {
+				return nil, fmt.Errorf("Required tool %q is missing: %w", toolPath, err)
+			}
+
+			continue
+		}
+
+		paths[tool] = path
+	}
+
+	return paths, nil
+}
+
+// lvmCheckedFilesystems records the filesystems whose tools have been found, so they are only looked up once.
+var lvmCheckedFilesystems = map[string]bool{}
+var lvmCheckedFilesystemsMu sync.Mutex
+
+// lvmFilesystemTools returns the tools used to identify, create and check filesystems of the given type on the
+// pool's volumes.
+func lvmFilesystemTools(fsType string) []string {
+	tools := []string{"blkid", fmt.Sprintf("mkfs.%s", fsType)}
+
+	switch fsType {
+	case "ext2", "ext3", "ext4":
+		tools = append(tools, "e2fsck")
+	case "xfs":
+		tools = append(tools, "xfs_repair")
+	}
+
+	return tools
+}
+
+// checkLVMFilesystemTools checks that the tools needed for volumes with the given filesystem are installed, so that
+// a missing tool is reported when the pool is loaded rather than when the first volume is created.
+func checkLVMFilesystemTools(fsType string) error {
+	if fsType == "" {
+		fsType = DefaultFilesystem
+	}
+
+	lvmCheckedFilesystemsMu.Lock()
+	defer lvmCheckedFilesystemsMu.Unlock()
+
+	if lvmCheckedFilesystems[fsType] {
+		return nil
+	}
+
+	for _, tool := range lvmFilesystemTools(fsType) {
+		_, err := exec.LookPath(tool)
+		if err != nil {
+			return fmt.Errorf("Required tool %q for %q filesystems is missing: %w", tool, fsType, err)
+		}
+	}
+
+	lvmCheckedFilesystems[fsType] = true
+
+	return nil
+}
+
 type lvm struct {
 	common
 }
@@ -37,6 +151,13 @@ func (d *lvm) load() error {
 		"storage_delete_old_snapshot_records":                nil,
 		"storage_zfs_drop_block_volume_filesystem_extension": nil,
 		"storage_prefix_bucket_names_with_project":           nil,
+		"storage_lvm_tag_volumes":                            d.patchStorageTagVolumes,
+	}
+
+	// Validate the filesystem tools for every pool, as pools can use different filesystems for their volumes.
+	err := checkLVMFilesystemTools(d.config["volume.block.filesystem"])
+	if err != nil {
+		return err
 	}
 
 	// Done if previously loaded.
@@ -45,41 +166,44 @@ func (d *lvm) load() error {
 	}
 
 	// Validate the required binaries.
-	for _, tool := range []string{"lvm"} {
-		_, err := exec.LookPath(tool)
-		if err != nil {
-			return fmt.Errorf("Required tool %q is missing", tool)
-		}
+	toolPaths, err := resolveLVMTools()
+	if err != nil {
+		return err
 	}
 
+	lvmToolPathsMu.Lock()
+	lvmToolPaths = toolPaths
+	lvmToolPathsMu.Unlock()
+
 	// Detect and record the version.
 	if lvmVersion == "" {
-		output, err := shared.RunCommand("lvm", "version")
+		output, err := d.runLVMCommand("lvm", "version")
 		if err != nil {
 			return fmt.Errorf("Error getting LVM version: %w", err)
 		}
 
-		lines := strings.Split(output, "\n")
-		for idx, line := range lines {
-			fields := strings.SplitAfterN(line, ":", 2)
-			if len(fields) < 2 {
-				continue
-			}
+		lvmVersion = parseLVMVersion(output)
+	}
 
-			if !strings.Contains(line, "version:") {
-				continue
-			}
+	lvmLoaded = true
+	return nil
+}
 
-			if idx > 0 {
-				lvmVersion += " / "
-			}
+// parseLVMVersion parses the output of "lvm version" and returns the versions it reports (LVM, library and driver)
+// joined by " / ", starting with the LVM version. Lines that aren't versions, such as warnings, are ignored.
+func parseLVMVersion(output string) string {
+	var versions []string
 
-			lvmVersion += strings.TrimSpace(fields[1])
+	for _, line := range strings.Split(output, "\n") {
+		name, value, found := strings.Cut(line, ":")
+		if !found || !strings.HasSuffix(name, "version") {
+			continue
 		}
+
+		versions = append(versions, strings.TrimSpace(value))
 	}
 
-	lvmLoaded = true
-	return nil
+	return strings.Join(versions, " / ")
 }
 
 // Info returns info about the driver and its environment.
@@ -98,6 +222,7 @@ func (d *lvm) Info() Info {
 		IOUring:                      true,
 		MountedRoot:                  false,
 		Buckets:                      true,
+		ZeroedVolumes:                d.usesThinpool(), // Unprovisioned thin volume blocks read as zeroes.
 	}
 }
 
@@ -290,12 +415,28 @@ func (d *lvm) Create() error {
 				// Always check if the thin pool exists as we may need to create it later.
 				thinPoolExists, err = d.thinpoolExists(d.config["lvm.vg_name"], d.thinpoolName())
 				if err != nil {
-					return fmt.Errorf("Failed to determine whether thinpool %q exists in volume group %q: %w", d.config["lvm.vg_name"], d.thinpoolName(), err)
+					return fmt.Errorf("Failed to determine whether thinpool %q exists in volume group %q: %w", d.thinpoolName(), d.config["lvm.vg_name"], err)
+				}
+
+				thinPoolCount := 0
+				if thinPoolExists {
+					thinPoolCount++
 				}
 
-				// If the single volume is the storage pool's thin pool LV then we still consider
+				if d.config["lvm.image_thinpool_name"] != "" {
+					imageThinPoolExists, err := d.thinpoolExists(d.config["lvm.vg_name"], d.config["lvm.image_thinpool_name"])
+					if err != nil {
+						return fmt.Errorf("Failed to determine whether thinpool %q exists in volume group %q: %w", d.config["lvm.image_thinpool_name"], d.config["lvm.vg_name"], err)
+					}
+
+					if imageThinPoolExists {
+						thinPoolCount++
+					}
+				}
+
+				// If the only volumes are the storage pool's thin pool LVs then we still consider
 				// this an empty volume group.
-				if thinPoolExists && lvCount == 1 {
+				if thinPoolCount > 0 && lvCount == thinPoolCount {
 					empty = true
 				}
 			}
@@ -326,22 +467,22 @@ func (d *lvm) Create() error {
 				return fmt.Errorf("No name for physical volume detected")
 			}
 
-			_, err := shared.TryRunCommand("pvcreate", pvName)
+			_, err := d.tryRunLVMCommand("pvcreate", pvName)
 			if err != nil {
 				return err
 			}
 
-			revert.Add(func() { _, _ = shared.TryRunCommand("pvremove", pvName) })
+			revert.Add(func() { _, _ = d.tryRunLVMCommand("pvremove", pvName) })
 		}
 
 		// Create volume group.
-		_, err := shared.TryRunCommand("vgcreate", d.config["lvm.vg_name"], pvName)
+		_, err := d.tryRunLVMCommand("vgcreate", d.config["lvm.vg_name"], pvName)
 		if err != nil {
 			return err
 		}
 
 		d.logger.Debug("Volume group created", logger.Ctx{"pv_name": pvName, "vg_name": d.config["lvm.vg_name"]})
-		revert.Add(func() { _, _ = shared.TryRunCommand("vgremove", d.config["lvm.vg_name"]) })
+		revert.Add(func() { _, _ = d.tryRunLVMCommand("vgremove", d.config["lvm.vg_name"]) })
 	}
 
 	// Create thin pool if needed.
@@ -370,10 +511,17 @@ func (d *lvm) Create() error {
 		} else if d.config["size"] != "" {
 			return fmt.Errorf("Cannot specify size when using an existing thin pool")
 		}
+
+		if d.config["lvm.image_thinpool_name"] != "" {
+			err = d.checkImageThinpool(d.config["lvm.vg_name"], d.config["lvm.image_thinpool_name"])
+			if err != nil {
+				return err
+			}
+		}
 	}
 
 	// Mark the volume group with the lvmVgPoolMarker tag to indicate it is now in use by LXD.
-	_, err = shared.TryRunCommand("vgchange", "--addtag", lvmVgPoolMarker, d.config["lvm.vg_name"])
+	_, err = d.tryRunLVMCommand("vgchange", "--addtag", lvmVgPoolMarker, d.config["lvm.vg_name"])
 	if err != nil {
 		return err
 	}
@@ -399,81 +547,25 @@ func (d *lvm) Delete(op *operations.Operation) error {
 		defer func() { _ = loopDeviceAutoDetach(loopDevPath) }()
 	}
 
-	vgExists, vgTags, err := d.volumeGroupExists(d.config["lvm.vg_name"])
+	actions, removeVg, err := d.deleteActions()
 	if err != nil {
 		return err
 	}
 
-	removeVg := false
-	if vgExists && shared.IsFalseOrEmpty(d.config["lvm.vg.force_reuse"]) {
-		// Count normal and thin volumes.
-		lvCount, err := d.countLogicalVolumes(d.config["lvm.vg_name"])
-		if err != nil {
-			if !api.StatusErrorCheck(err, http.StatusNotFound) {
-				return err
-			}
-		}
-
-		// Check that volume group is not in use. If it is we need to assume that other users are using
-		// the volume group, so don't remove it. This actually goes against policy since we explicitly
-		// state: our pool, and nothing but our pool, but still, let's not hurt users.
-		if err == nil {
-			if lvCount == 0 {
-				removeVg = true // Volume group is totally empty, safe to remove.
-			} else if d.usesThinpool() && lvCount > 0 {
-				// Lets see if the lv count is just our thin pool, or whether we can only remove
-				// the thin pool itself and not the volume group.
-				thinVolCount, err := d.countThinVolumes(d.config["lvm.vg_name"], d.thinpoolName())
-				if err != nil {
-					if !api.StatusErrorCheck(err, http.StatusNotFound) {
-						return err
-					}
-				}
+	d.logger.Debug("Removing storage pool", logger.Ctx{"vg_name": d.config["lvm.vg_name"], "actions": actions})
 
-				// Thin pool exists.
-				if err == nil {
-					// If thin pool is empty and the total VG volume count is 1 (our thin pool
-					// volume) then just remove the entire volume group.
-					if thinVolCount == 0 && lvCount == 1 {
-						removeVg = true
-					} else if thinVolCount == 0 && lvCount > 1 {
-						// Otherwise, if the thin pool is empty but the volume group has
-						// other volumes, then just remove the thin pool volume.
-						err = d.removeLogicalVolume(d.lvmDevPath(d.config["lvm.vg_name"], "", "", d.thinpoolName()))
-						if err != nil {
-							return fmt.Errorf("Failed to delete thin pool %q from volume group %q: %w", d.thinpoolName(), d.config["lvm.vg_name"], err)
-						}
-
-						d.logger.Debug("Thin pool removed", logger.Ctx{"vg_name": d.config["lvm.vg_name"], "thinpool_name": d.thinpoolName()})
-					}
-				}
-			}
+	for _, action := range actions {
+		_, err := d.tryRunVolumeGroupCommand(d.config["lvm.vg_name"], action.command, action.args...)
+		if err != nil {
+			return fmt.Errorf("Failed to %s: %w", action.description, err)
 		}
 
-		// Remove volume group if needed.
-		if removeVg {
-			_, err := shared.TryRunCommand("vgremove", "-f", d.config["lvm.vg_name"])
-			if err != nil {
-				return fmt.Errorf("Failed to delete the volume group for the lvm storage pool: %w", err)
-			}
-
-			d.logger.Debug("Volume group removed", logger.Ctx{"vg_name": d.config["lvm.vg_name"]})
-		} else {
-			// Otherwise just remove the lvmVgPoolMarker tag to indicate LXD no longer uses this VG.
-			if shared.ValueInSlice(lvmVgPoolMarker, vgTags) {
-				_, err = shared.TryRunCommand("vgchange", "--deltag", lvmVgPoolMarker, d.config["lvm.vg_name"])
-				if err != nil {
-					return fmt.Errorf("Failed to remove marker tag on volume group for the lvm storage pool: %w", err)
-				}
-
-				d.logger.Debug("LXD marker tag removed from volume group", logger.Ctx{"vg_name": d.config["lvm.vg_name"]})
-			}
-		}
+		d.logger.Debug(action.done, logger.Ctx{"vg_name": d.config["lvm.vg_name"]})
 	}
 
 	// If we have removed the volume group and this is a loop file, lets clean up the physical volume too.
 	if removeVg && loopDevPath != "" {
-		_, err := shared.TryRunCommand("pvremove", "-f", loopDevPath)
+		_, err := d.tryRunLVMCommand("pvremove", "-f", loopDevPath)
 		if err != nil {
 			d.logger.Warn("Failed to destroy the physical volume for the lvm storage pool", logger.Ctx{"err": err})
 		}
@@ -503,25 +595,182 @@ func (d *lvm) Delete(op *operations.Operation) error {
 	return nil
 }
 
+// deleteActions works out the LVM commands needed to remove the storage pool from its volume group without running
+// them, so that they can be reviewed before anything is removed. It also returns whether the volume group is removed.
+// Any loop file backing the pool must already be open.
+func (d *lvm) deleteActions() ([]lvmAction, bool, error) {
+	vgName := d.config["lvm.vg_name"]
+
+	vgExists, vgTags, err := d.volumeGroupExists(vgName)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if !vgExists || !shared.IsFalseOrEmpty(d.config["lvm.vg.force_reuse"]) {
+		return nil, false, nil
+	}
+
+	var actions []lvmAction
+	removeVg := false
+
+	// Count normal and thin volumes.
+	lvCount, err := d.countLogicalVolumes(vgName)
+	if err != nil {
+		if !api.StatusErrorCheck(err, http.StatusNotFound) {
+			return nil, false, err
+		}
+	}
+
+	// Check that volume group is not in use. If it is we need to assume that other users are using
+	// the volume group, so don't remove it. This actually goes against policy since we explicitly
+	// state: our pool, and nothing but our pool, but still, let's not hurt users.
+	if err == nil {
+		if lvCount == 0 {
+			removeVg = true // Volume group is totally empty, safe to remove.
+		} else if d.usesThinpool() && lvCount > 0 {
+			// Lets see if the lv count is just our thin pool, or whether we can only remove
+			// the thin pool itself and not the volume group.
+			thinVolCount, err := d.countThinVolumes(vgName, d.thinpoolName())
+			if err != nil {
+				if !api.StatusErrorCheck(err, http.StatusNotFound) {
+					return nil, false, err
+				}
+			}
+
+			// Thin pool exists.
+			if err == nil {
+				// If thin pool is empty and the total VG volume count is 1 (our thin pool
+				// volume) then just remove the entire volume group.
+				if thinVolCount == 0 && lvCount == 1 {
+					removeVg = true
+				} else if thinVolCount == 0 && lvCount > 1 {
+					// Otherwise, if the thin pool is empty but the volume group has
+					// other volumes, then just remove the thin pool volume.
+					actions = append(actions, lvmAction{
+						command:     "lvremove",
+						args:        []string{"-f", d.lvmDevPath(vgName, "", "", d.thinpoolName())},
+						description: fmt.Sprintf("delete thin pool %q from volume group %q", d.thinpoolName(), vgName),
+						done:        "Thin pool removed",
+					})
+				}
+			}
+		}
+	}
+
+	// Remove volume group if needed.
+	if removeVg {
+		actions = append(actions, lvmAction{
+			command:     "vgremove",
+			args:        []string{"-f", vgName},
+			description: "delete the volume group for the lvm storage pool",
+			done:        "Volume group removed",
+		})
+	} else if shared.ValueInSlice(lvmVgPoolMarker, vgTags) {
+		// Otherwise just remove the lvmVgPoolMarker tag to indicate LXD no longer uses this VG.
+		actions = append(actions, lvmAction{
+			command:     "vgchange",
+			args:        []string{"--deltag", lvmVgPoolMarker, vgName},
+			description: "remove marker tag on volume group for the lvm storage pool",
+			done:        "LXD marker tag removed from volume group",
+		})
+	}
+
+	return actions, removeVg, nil
+}
+
 // Validate checks that all provide keys are supported and that no conflicting
 // or missing configuration is present.
 func (d *lvm) Validate(config map[string]string) error {
 	rules := map[string]func(value string) error{
 		"size": validate.Optional(validate.IsSize),
 		// lxdmeta:generate(entities=storage-lvm; group=pool-conf; key=lvm.vg_name)
-		//
+		// Changing this option renames the volume group. If the volume group was already renamed outside of
+		// LXD (using `vgrename`), set this option to its new name to have LXD use it. The volume group is then
+		// checked to contain the pool's thin pool and the logical volumes of its instances.
 		// ---
 		//  type: string
 		//  defaultdesc: name of the pool
 		//  shortdesc: Name of the volume group to create
-		"lvm.vg_name": validate.IsAny,
+		"lvm.vg_name": validate.Optional(validateLVMName),
+		// lxdmeta:generate(entities=storage-lvm; group=pool-conf; key=lvm.log_warnings)
+		// When enabled, warnings printed by the LVM commands (such as a thin pool approaching its size limit)
+		// are logged even if the commands succeed.
+		// ---
+		//  type: bool
+		//  defaultdesc: `true`
+		//  shortdesc: Whether to log warnings from LVM commands
+		"lvm.log_warnings": validate.Optional(validate.IsBool),
+		// lxdmeta:generate(entities=storage-lvm; group=pool-conf; key=lvm.command_log_level)
+		// Every LVM command run by LXD is logged with its arguments before it runs, and with its exit status
+		// and duration once it finishes. This sets the level of those log messages, `none` disables them.
+		// The values of options carrying secrets are redacted.
+		// ---
+		//  type: string
+		//  defaultdesc: `debug`
+		//  shortdesc: Log level of the executed LVM commands (`debug`, `info` or `none`)
+		"lvm.command_log_level": validate.Optional(validate.IsOneOf("debug", "info", "none")),
+		// lxdmeta:generate(entities=storage-lvm; group=pool-conf; key=lvm.max_overcommit_ratio)
+		// Creating, snapshotting or growing a thin volume fails if the total virtual size of the volumes in the
+		// thin pool would become larger than the size of the thin pool multiplied by this ratio. For example `2` allows provisioning
+		// volumes adding up to twice the size of the thin pool.
+		// ---
+		//  type: string
+		//  defaultdesc: unlimited
+		//  shortdesc: Maximum ratio of the thin volumes' virtual size to the thin pool size
+		"lvm.max_overcommit_ratio": validate.Optional(validateLVMOvercommitRatio),
+		// lxdmeta:generate(entities=storage-lvm; group=pool-conf; key=lvm.max_snapshots)
+		// Creating a snapshot fails once a volume already has this many snapshots.
+		// This can be used to avoid exhausting the thin pool metadata with snapshot-heavy workloads.
+		// ---
+		//  type: integer
+		//  defaultdesc: `0` (unlimited)
+		//  shortdesc: Maximum number of snapshots per volume
+		"lvm.max_snapshots": validate.Optional(validate.IsUint32),
+		// lxdmeta:generate(entities=storage-lvm; group=pool-conf; key=lvm.freeze_on_snapshot)
+		// When enabled, the filesystem of a mounted volume is synced and frozen (using `fsfreeze`) while its
+		// LVM snapshot is taken, so that the snapshot doesn't contain partially written data. Writes to the
+		// volume are blocked for that time. Creating the snapshot fails if the filesystem can't be frozen.
+		// ---
+		//  type: bool
+		//  defaultdesc: `false`
+		//  shortdesc: Whether to freeze volume filesystems while snapshotting them
+		"lvm.freeze_on_snapshot": validate.Optional(validate.IsBool),
+		// lxdmeta:generate(entities=storage-lvm; group=pool-conf; key=lvm.btrfs_snapshots)
+		// When enabled, snapshots of container volumes formatted as `btrfs` are created as read-only `btrfs`
+		// subvolume snapshots inside the volume rather than as LVM snapshots. Existing LVM snapshots, and
+		// snapshots of other volumes, keep using LVM snapshots.
+		// Copying a volume with such snapshots within the pool doesn't use thin pool snapshots.
+		// ---
+		//  type: bool
+		//  defaultdesc: `false`
+		//  shortdesc: Whether to use `btrfs` snapshots for `btrfs` container volumes
+		"lvm.btrfs_snapshots": validate.Optional(validate.IsBool),
 		// lxdmeta:generate(entities=storage-lvm; group=pool-conf; key=lvm.thinpool_name)
 		//
 		// ---
 		//  type: string
 		//  defaultdesc: `LXDThinPool`
 		//  shortdesc: Thin pool where volumes are created
-		"lvm.thinpool_name": validate.IsAny,
+		"lvm.thinpool_name": validate.Optional(validateLVMName),
+		// lxdmeta:generate(entities=storage-lvm; group=pool-conf; key=lvm.image_thinpool_name)
+		// When set, image volumes are created in this thin pool instead of the
+		// {config:option}`storage-lvm-pool-conf:lvm.thinpool_name` thin pool, so that the image volumes don't
+		// compete with the instance volumes for space. The thin pool must already exist in the volume group.
+		// Instance volumes created from an image in a different thin pool are copied from the image volume
+		// instead of being snapshots of it.
+		// ---
+		//  type: string
+		//  shortdesc: Thin pool where image volumes are created
+		"lvm.image_thinpool_name": validate.Optional(validateLVMName),
+		// lxdmeta:generate(entities=storage-lvm; group=pool-conf; key=lvm.thinpool_chunk_size)
+		// The chunk size must be a power of two between 64 KiB and 1 GiB. By default, LVM picks an
+		// appropriate size.
+		// This setting is only used when LXD creates the thin pool, not for existing thin pools.
+		// ---
+		//  type: string
+		//  defaultdesc: `0` (auto)
+		//  shortdesc: The chunk size of the thin pool
+		"lvm.thinpool_chunk_size": validate.Optional(validateLVMThinpoolChunkSize),
 		// lxdmeta:generate(entities=storage-lvm; group=pool-conf; key=lvm.thinpool_metadata_size)
 		// By default, LVM calculates an appropriate size.
 		// ---
@@ -529,6 +778,25 @@ func (d *lvm) Validate(config map[string]string) error {
 		//  defaultdesc: `0` (auto)
 		//  shortdesc: The size of the thin pool metadata volume
 		"lvm.thinpool_metadata_size": validate.Optional(validate.IsSize),
+		// lxdmeta:generate(entities=storage-lvm; group=pool-conf; key=lvm.thinpool_metadata_autoextend)
+		// When enabled, the thin pool metadata volume is doubled in size when its usage reaches 80% and a
+		// new volume is created, up to the maximum size supported by LVM (about 15.88GiB). Otherwise, a
+		// warning is logged.
+		// ---
+		//  type: bool
+		//  defaultdesc: `false`
+		//  shortdesc: Whether to automatically extend the thin pool metadata volume
+		"lvm.thinpool_metadata_autoextend": validate.Optional(validate.IsBool),
+		// lxdmeta:generate(entities=storage-lvm; group=pool-conf; key=lvm.thinpool_reclaim)
+		// When enabled, the thin pool is removed once its last volume is deleted, returning the space to the
+		// volume group. It is re-created when the next volume is created.
+		// Only thin pools that were created by LXD are removed. Thin pools created before LXD started tagging
+		// the thin pools it creates are never removed.
+		// ---
+		//  type: bool
+		//  defaultdesc: `false`
+		//  shortdesc: Whether to remove the thin pool when it is no longer used
+		"lvm.thinpool_reclaim": validate.Optional(validate.IsBool),
 		// lxdmeta:generate(entities=storage-lvm; group=pool-conf; key=lvm.use_thinpool)
 		//
 		// ---
@@ -543,6 +811,42 @@ func (d *lvm) Validate(config map[string]string) error {
 		//  defaultdesc: `false`
 		//  shortdesc: Force using an existing non-empty volume group
 		"lvm.vg.force_reuse": validate.Optional(validate.IsBool),
+		// lxdmeta:generate(entities=storage-lvm; group=pool-conf; key=lvm.wipe_on_delete)
+		// When enabled, the contents of a volume are wiped before it is deleted, so that its data can't surface
+		// in volumes created later. Volumes in a thin pool that passes discards down are discarded (using
+		// `blkdiscard`), other volumes are overwritten with zeroes, which can be slow for large volumes.
+		// Snapshots aren't wiped.
+		// ---
+		//  type: bool
+		//  defaultdesc: `false`
+		//  shortdesc: Whether to wipe volumes when deleting them
+		"lvm.wipe_on_delete": validate.Optional(validate.IsBool),
+		// lxdmeta:generate(entities=storage-lvm; group=pool-conf; key=lvm.fsck_on_mount)
+		// When enabled, the filesystem of a volume is checked and repaired (using `e2fsck -p` for `ext4` and
+		// `xfs_repair` for `xfs`) before the volume is mounted. The check isn't done for snapshots.
+		// Mounting fails if the filesystem has errors that can't be corrected.
+		// ---
+		//  type: bool
+		//  defaultdesc: `false`
+		//  shortdesc: Whether to check volume filesystems before mounting them
+		"lvm.fsck_on_mount": validate.Optional(validate.IsBool),
+		// lxdmeta:generate(entities=storage-lvm; group=pool-conf; key=lvm.mount_attempts)
+		// Mounting or unmounting a volume is retried until it succeeds or this many attempts were made, waiting
+		// longer after each failed attempt. A volume that is still busy after the last unmount attempt is
+		// lazily unmounted.
+		// ---
+		//  type: integer
+		//  defaultdesc: `20`
+		//  shortdesc: Maximum number of attempts to mount or unmount a volume
+		"lvm.mount_attempts": validate.Optional(validate.IsInRange(1, 1000)),
+		// lxdmeta:generate(entities=storage-lvm; group=pool-conf; key=lvm.mount_timeout)
+		// Mounting or unmounting a volume isn't retried once this many seconds have passed, even if
+		// {config:option}`storage-lvm-pool-conf:lvm.mount_attempts` isn't reached.
+		// ---
+		//  type: integer
+		//  defaultdesc: `10`
+		//  shortdesc: Time in seconds to retry mounting or unmounting a volume for
+		"lvm.mount_timeout": validate.Optional(validate.IsUint32),
 	}
 
 	err := d.validatePool(config, rules, d.commonVolumeRules())
@@ -555,9 +859,38 @@ func (d *lvm) Validate(config map[string]string) error {
 			return fmt.Errorf("The key lvm.use_thinpool cannot be set to false when lvm.thinpool_name is set")
 		}
 
+		if config["lvm.image_thinpool_name"] != "" {
+			return fmt.Errorf("The key lvm.use_thinpool cannot be set to false when lvm.image_thinpool_name is set")
+		}
+
 		if config["lvm.thinpool_metadata_size"] != "" {
 			return fmt.Errorf("The key lvm.use_thinpool cannot be set to false when lvm.thinpool_metadata_size is set")
 		}
+
+		if config["lvm.thinpool_metadata_autoextend"] != "" {
+			return fmt.Errorf("The key lvm.use_thinpool cannot be set to false when lvm.thinpool_metadata_autoextend is set")
+		}
+
+		if config["lvm.thinpool_chunk_size"] != "" {
+			return fmt.Errorf("The key lvm.use_thinpool cannot be set to false when lvm.thinpool_chunk_size is set")
+		}
+
+		if config["lvm.thinpool_reclaim"] != "" {
+			return fmt.Errorf("The key lvm.use_thinpool cannot be set to false when lvm.thinpool_reclaim is set")
+		}
+
+		if config["volume.lvm.preallocate"] != "" {
+			return fmt.Errorf("The key lvm.use_thinpool cannot be set to false when volume.lvm.preallocate is set")
+		}
+	}
+
+	thinpoolName := config["lvm.thinpool_name"]
+	if thinpoolName == "" {
+		thinpoolName = lvmThinpoolDefaultName
+	}
+
+	if config["lvm.image_thinpool_name"] == thinpoolName {
+		return fmt.Errorf("The key lvm.image_thinpool_name cannot be set to the same thin pool as lvm.thinpool_name")
 	}
 
 	return nil
@@ -575,6 +908,11 @@ func (d *lvm) Update(changedConfig map[string]string) error {
 		return fmt.Errorf("lvm.thinpool_metadata_size cannot be changed")
 	}
 
+	_, changed = changedConfig["lvm.thinpool_chunk_size"]
+	if changed {
+		return fmt.Errorf("lvm.thinpool_chunk_size cannot be changed")
+	}
+
 	_, changed = changedConfig["volume.lvm.stripes"]
 	if changed && d.usesThinpool() {
 		return fmt.Errorf("volume.lvm.stripes cannot be changed when using thin pool")
@@ -586,16 +924,41 @@ func (d *lvm) Update(changedConfig map[string]string) error {
 	}
 
 	if changedConfig["lvm.vg_name"] != "" {
-		_, err := shared.TryRunCommand("vgrename", d.config["lvm.vg_name"], changedConfig["lvm.vg_name"])
+		oldVgExists, _, err := d.volumeGroupExists(d.config["lvm.vg_name"])
+		if err != nil {
+			return err
+		}
+
+		newVgExists, _, err := d.volumeGroupExists(changedConfig["lvm.vg_name"])
 		if err != nil {
-			return fmt.Errorf("Error renaming LVM volume group from %q to %q: %w", d.config["lvm.vg_name"], changedConfig["lvm.vg_name"], err)
+			return err
+		}
+
+		if !oldVgExists && newVgExists {
+			// The volume group has already been renamed outside of LXD, so only start using the new name
+			// once it has been checked to be the pool's volume group.
+			err = d.checkRenamedVolumeGroup(changedConfig["lvm.vg_name"])
+			if err != nil {
+				return fmt.Errorf("Cannot use LVM volume group %q renamed from %q: %w", changedConfig["lvm.vg_name"], d.config["lvm.vg_name"], err)
+			}
+
+			d.logger.Info("Using volume group renamed outside of LXD", logger.Ctx{"vg_name": d.config["lvm.vg_name"], "new_vg_name": changedConfig["lvm.vg_name"]})
+		} else {
+			// Hold the volume group lock so that the rename doesn't happen while other LVM commands are run on it.
+			_, err := d.tryRunVolumeGroupCommand(d.config["lvm.vg_name"], "vgrename", d.config["lvm.vg_name"], changedConfig["lvm.vg_name"])
+			if err != nil {
+				return fmt.Errorf("Error renaming LVM volume group from %q to %q: %w", d.config["lvm.vg_name"], changedConfig["lvm.vg_name"], err)
+			}
+
+			d.logger.Debug("Volume group renamed", logger.Ctx{"vg_name": d.config["lvm.vg_name"], "new_vg_name": changedConfig["lvm.vg_name"]})
 		}
 
-		d.logger.Debug("Volume group renamed", logger.Ctx{"vg_name": d.config["lvm.vg_name"], "new_vg_name": changedConfig["lvm.vg_name"]})
+		// Forget any state recorded for the old volume group name, such as it being missing.
+		d.forgetVolumeGroup(d.config["lvm.vg_name"])
 	}
 
 	if changedConfig["lvm.thinpool_name"] != "" {
-		_, err := shared.TryRunCommand("lvrename", d.config["lvm.vg_name"], d.thinpoolName(), changedConfig["lvm.thinpool_name"])
+		_, err := d.tryRunVolumeGroupCommand(d.config["lvm.vg_name"], "lvrename", d.config["lvm.vg_name"], d.thinpoolName(), changedConfig["lvm.thinpool_name"])
 		if err != nil {
 			return fmt.Errorf("Error renaming LVM thin pool from %q to %q: %w", d.thinpoolName(), changedConfig["lvm.thinpool_name"], err)
 		}
@@ -603,6 +966,18 @@ func (d *lvm) Update(changedConfig map[string]string) error {
 		d.logger.Debug("Thin pool volume renamed", logger.Ctx{"vg_name": d.config["lvm.vg_name"], "thinpool": d.thinpoolName(), "new_thinpool": changedConfig["lvm.thinpool_name"]})
 	}
 
+	if changedConfig["lvm.image_thinpool_name"] != "" {
+		vgName := d.config["lvm.vg_name"]
+		if changedConfig["lvm.vg_name"] != "" {
+			vgName = changedConfig["lvm.vg_name"]
+		}
+
+		err := d.checkImageThinpool(vgName, changedConfig["lvm.image_thinpool_name"])
+		if err != nil {
+			return err
+		}
+	}
+
 	size, ok := changedConfig["size"]
 	if ok {
 		// Figure out loop path
@@ -638,7 +1013,7 @@ func (d *lvm) Update(changedConfig map[string]string) error {
 		}
 
 		// Resize physical volume so that lvresize is able to resize as well.
-		_, err = shared.RunCommand("pvresize", "-y", loopDevPath)
+		_, err = d.runLVMCommand("pvresize", "-y", loopDevPath)
 		if err != nil {
 			return err
 		}
@@ -647,7 +1022,7 @@ func (d *lvm) Update(changedConfig map[string]string) error {
 			lvPath := d.lvmDevPath(d.config["lvm.vg_name"], "", "", d.thinpoolName())
 
 			// Use the remaining space in the volume group.
-			_, err = shared.RunCommand("lvresize", "-f", "-l", "+100%FREE", lvPath)
+			_, err = d.tryRunVolumeGroupCommand(d.config["lvm.vg_name"], "lvresize", "-f", "-l", "+100%FREE", lvPath)
 			if err != nil {
 				return err
 			}
@@ -693,18 +1068,21 @@ func (d *lvm) Mount() (bool, error) {
 				}
 
 				if time.Now().After(waitUntil) {
-					return false, fmt.Errorf("Volume group %q not found", d.config["lvm.vg_name"])
+					return false, fmt.Errorf("Failed to find volume group %q: %w", d.config["lvm.vg_name"], ErrVolumeGroupNotFound)
 				}
 
 				time.Sleep(1 * time.Second)
 			}
 		}
 	} else if !vgExists {
-		return false, fmt.Errorf("Volume group %s not found", d.config["lvm.vg_name"])
+		return false, fmt.Errorf("Failed to find volume group %q: %w", d.config["lvm.vg_name"], ErrVolumeGroupNotFound)
 	}
 
+	d.markVolumeGroupAvailable(d.config["lvm.vg_name"])
+
 	// Ensure thinpool exists if needed for storage pool.
-	if d.usesThinpool() {
+	// A reclaimed thin pool is expected to be missing until the next volume is created.
+	if d.usesThinpool() && shared.IsFalseOrEmpty(d.config["lvm.thinpool_reclaim"]) {
 		waitUntil := time.Now().Add(waitDuration)
 		for {
 			thinpoolExists, _ := d.thinpoolExists(d.config["lvm.vg_name"], d.thinpoolName())
@@ -720,6 +1098,16 @@ func (d *lvm) Mount() (bool, error) {
 		}
 	}
 
+	// Report any inconsistencies in the storage pool to help diagnosing a broken setup.
+	report, err := d.Check()
+	if err != nil {
+		d.logger.Warn("Failed checking storage pool", logger.Ctx{"err": err})
+	} else {
+		for _, problem := range report.Problems() {
+			d.logger.Warn("Storage pool problem found", logger.Ctx{"problem": problem})
+		}
+	}
+
 	revert.Success()
 	return ourMount, nil
 }
@@ -734,9 +1122,29 @@ func (d *lvm) Unmount() (bool, error) {
 func (d *lvm) GetResources() (*api.ResourcesStoragePool, error) {
 	res := api.ResourcesStoragePool{}
 
+	// Report the volume group disappearing explicitly rather than as a failure to parse the usage below.
+	vgExists, _, err := d.volumeGroupExists(d.config["lvm.vg_name"])
+	if err != nil {
+		return nil, err
+	}
+
+	if !vgExists {
+		return nil, api.StatusErrorf(http.StatusNotFound, "LVM volume group %q not found", d.config["lvm.vg_name"])
+	}
+
+	reclaimed, err := d.thinpoolReclaimed()
+	if err != nil {
+		return nil, err
+	}
+
+	res.LVM, err = d.volumeGroupUsage()
+	if err != nil {
+		return nil, err
+	}
+
 	// Thinpools will always report zero free space on the volume group, so calculate approx
 	// used space using the thinpool logical volume allocated (data and meta) percentages.
-	if d.usesThinpool() {
+	if d.usesThinpool() && !reclaimed {
 		volDevPath := d.lvmDevPath(d.config["lvm.vg_name"], "", "", d.thinpoolName())
 		totalSize, usedSize, err := d.thinPoolVolumeUsage(volDevPath)
 		if err != nil {
@@ -747,38 +1155,8 @@ func (d *lvm) GetResources() (*api.ResourcesStoragePool, error) {
 		res.Space.Used = usedSize
 	} else {
 		// If thinpools are not in use, calculate used space in volume group.
-		args := []string{
-			d.config["lvm.vg_name"],
-			"--noheadings",
-			"--units", "b",
-			"--nosuffix",
-			"--separator", ",",
-			"-o", "vg_size,vg_free",
-		}
-
-		out, err := shared.RunCommand("vgs", args...)
-		if err != nil {
-			return nil, err
-		}
-
-		parts := strings.Split(strings.TrimSpace(out), ",")
-		if len(parts) < 2 {
-			return nil, fmt.Errorf("Unexpected output from vgs command")
-		}
-
-		total, err := strconv.ParseUint(parts[0], 10, 64)
-		if err != nil {
-			return nil, err
-		}
-
-		res.Space.Total = total
-
-		free, err := strconv.ParseUint(parts[1], 10, 64)
-		if err != nil {
-			return nil, err
-		}
-
-		res.Space.Used = total - free
+		res.Space.Total = res.LVM.VolumeGroupTotal
+		res.Space.Used = res.LVM.VolumeGroupTotal - res.LVM.VolumeGroupFree
 	}
 
 	return &res, nil
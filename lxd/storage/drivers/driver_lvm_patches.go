@@ -2,15 +2,17 @@ package drivers
 
 import (
 	"fmt"
+	"net/http"
 	"strings"
 
 	"github.com/canonical/lxd/shared"
+	"github.com/canonical/lxd/shared/api"
 	"github.com/canonical/lxd/shared/logger"
 )
 
 // patchStorageSkipActivation set skipactivation=y on all LXD LVM logical volumes (excluding thin pool volumes).
 func (d *lvm) patchStorageSkipActivation() error {
-	out, err := shared.RunCommand("lvs", "--noheadings", "-o", "lv_name,lv_attr", d.config["lvm.vg_name"])
+	out, err := d.runLVMCommand("lvs", "--noheadings", "-o", "lv_name,lv_attr", d.config["lvm.vg_name"])
 	if err != nil {
 		return fmt.Errorf("Error getting LVM logical volume list for storage pool %q: %w", d.config["lvm.vg_name"], err)
 	}
@@ -36,7 +38,7 @@ func (d *lvm) patchStorageSkipActivation() error {
 		}
 
 		// Set the --setactivationskip flag enabled on the volume.
-		_, err = shared.RunCommand("lvchange", "--setactivationskip", "y", fmt.Sprintf("%s/%s", d.config["lvm.vg_name"], volName))
+		_, err = d.runLVMCommand("lvchange", "--setactivationskip", "y", fmt.Sprintf("%s/%s", d.config["lvm.vg_name"], volName))
 		if err != nil {
 			return fmt.Errorf("Error setting setactivationskip=y on LVM logical volume %q for storage pool %q: %w", volName, d.config["lvm.vg_name"], err)
 		}
@@ -46,3 +48,61 @@ func (d *lvm) patchStorageSkipActivation() error {
 
 	return nil
 }
+
+// patchStorageTagVolumes adds the lvmVolumeMarker tag to all existing LXD LVM logical volumes (excluding thin pool
+// volumes), so that they can be told apart from other volumes in the same volume group. As the volume group may
+// be shared, only logical volumes that have a matching volume record in the database are tagged.
+func (d *lvm) patchStorageTagVolumes() error {
+	out, err := d.runLVMCommand("lvs", "--noheadings", "-o", "lv_name,lv_tags", d.config["lvm.vg_name"])
+	if err != nil {
+		return fmt.Errorf("Error getting LVM logical volume list for storage pool %q: %w", d.config["lvm.vg_name"], err)
+	}
+
+	for _, line := range strings.Split(out, "\n") {
+		rawName, tags := d.parseLogicalVolumeTags(line)
+
+		// Skip volumes that are already tagged.
+		if rawName == "" || shared.ValueInSlice(lvmVolumeMarker, tags) {
+			continue
+		}
+
+		// Ignore non-LXD prefixes, and thinpool volumes.
+		var volType VolumeType
+		var volName string
+
+		for _, volumeType := range d.Info().VolumeTypes {
+			prefix := fmt.Sprintf("%s_", volumeType)
+			if strings.HasPrefix(rawName, prefix) {
+				volType = volumeType
+				volName = strings.TrimPrefix(rawName, prefix)
+			}
+		}
+
+		if volType == "" {
+			continue
+		}
+
+		volName = lvNameToVolName(volName)
+		volName = strings.TrimSuffix(volName, lvmBlockVolSuffix)
+		volName = strings.TrimSuffix(volName, lvmISOVolSuffix)
+
+		_, err = d.getVolID(volType, volName)
+		if err != nil {
+			if api.StatusErrorCheck(err, http.StatusNotFound) {
+				logger.Infof("Skipping volume %q without a database record in pool %q", rawName, d.config["lvm.vg_name"])
+				continue
+			}
+
+			return fmt.Errorf("Error getting database record of LVM logical volume %q for storage pool %q: %w", rawName, d.config["lvm.vg_name"], err)
+		}
+
+		_, err = d.runLVMCommand("lvchange", "--addtag", lvmVolumeMarker, fmt.Sprintf("%s/%s", d.config["lvm.vg_name"], rawName))
+		if err != nil {
+			return fmt.Errorf("Error tagging LVM logical volume %q for storage pool %q: %w", rawName, d.config["lvm.vg_name"], err)
+		}
+
+		logger.Infof("Added %q tag to volume %q in pool %q", lvmVolumeMarker, rawName, d.config["lvm.vg_name"])
+	}
+
+	return nil
+}
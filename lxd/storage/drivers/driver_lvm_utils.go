@@ -2,17 +2,24 @@ package drivers
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io/fs"
+	"math"
 	"net/http"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/kballard/go-shellquote"
+
 	"github.com/canonical/lxd/lxd/locking"
 	"github.com/canonical/lxd/lxd/operations"
+	"github.com/canonical/lxd/lxd/rsync"
 	"github.com/canonical/lxd/lxd/storage/filesystem"
 	"github.com/canonical/lxd/shared"
 	"github.com/canonical/lxd/shared/api"
@@ -37,6 +44,73 @@ const lvmEscapedHyphen = "--"
 // lvmThinpoolDefaultName is the default name for the thinpool volume.
 const lvmThinpoolDefaultName = "LXDThinPool"
 
+// lvmThinpoolFullMessages are fragments of the error messages reported by the LVM tools when a thin pool has run
+// out of data space.
+var lvmThinpoolFullMessages = []string{
+	"free space in thin pool",
+	"out of data space",
+	"thin pool is full",
+}
+
+// lvmVolumeInUseMessages are fragments of the error messages reported by the LVM tools when a logical volume
+// can't be removed or deactivated because its device is held open.
+var lvmVolumeInUseMessages = []string{
+	"in use",
+	"open logical volume",
+	"device or resource busy",
+}
+
+// lvmThinpoolMetadataFullMessages are fragments of the error messages reported by the LVM tools when a thin pool
+// has run out of metadata space.
+var lvmThinpoolMetadataFullMessages = []string{
+	"free metadata space in thin pool",
+	"out of metadata space",
+}
+
+// lvmVolumeGroupProbeInterval is how often a volume group found missing is probed again before failing operations
+// on it without running LVM commands.
+const lvmVolumeGroupProbeInterval = 30 * time.Second
+
+// lvmMissingVolumeGroups records the volume groups found missing while running LVM commands, along with the time
+// they were last probed, so that operations on them fail early until they are back.
+var lvmMissingVolumeGroups = map[string]time.Time{}
+var lvmMissingVolumeGroupsMu sync.Mutex
+
+// lvmUsageCacheTTL is how long the usage of a volume group gathered for the pool resources is reused for, so that
+// frequent polling of the resources doesn't run the LVM tools every time.
+const lvmUsageCacheTTL = 5 * time.Second
+
+// lvmUsageCacheEntry is the usage of a volume group along with the time it was gathered.
+type lvmUsageCacheEntry struct {
+	usage    api.ResourcesStoragePoolLVM
+	gathered time.Time
+}
+
+// lvmUsageCache records the last usage gathered for each volume group and thin pool (keyed on "vg/thinpool").
+var lvmUsageCache = map[string]lvmUsageCacheEntry{}
+var lvmUsageCacheMu sync.Mutex
+
+// lvmThinpoolMetadataAutoextendPercent is the metadata usage percentage from which the thin pool metadata volume is
+// extended when lvm.thinpool_metadata_autoextend is enabled.
+const lvmThinpoolMetadataAutoextendPercent = 80
+
+// lvmThinpoolMetadataMaxSize is the largest thin pool metadata volume LVM supports (about 15.88GiB).
+const lvmThinpoolMetadataMaxSize = 255 * ((1 << 14) - 64) * 4 * 1024
+
+// lvmAction is an LVM command that modifies the volume group, used to review destructive operations before
+// running them.
+type lvmAction struct {
+	command     string
+	args        []string
+	description string // What the action does, used in errors.
+	done        string // Message logged once the action is done.
+}
+
+// String returns the command line of the action.
+func (a lvmAction) String() string {
+	return strings.Join(append([]string{a.command}, a.args...), " ")
+}
+
 // usesThinpool indicates whether the config specifies to use a thin pool or not.
 func (d *lvm) usesThinpool() bool {
 	// Default is to use a thinpool.
@@ -52,6 +126,16 @@ func (d *lvm) thinpoolName() string {
 	return lvmThinpoolDefaultName
 }
 
+// volumeThinpoolName returns the thin pool that the volume is created in. Image volumes use the
+// lvm.image_thinpool_name thin pool if it is set, all other volumes use the pool's thin pool.
+func (d *lvm) volumeThinpoolName(vol Volume) string {
+	if vol.volType == VolumeTypeImage && d.config["lvm.image_thinpool_name"] != "" {
+		return d.config["lvm.image_thinpool_name"]
+	}
+
+	return d.thinpoolName()
+}
+
 // openLoopFile opens a loop device and returns the device path.
 func (d *lvm) openLoopFile(source string) (string, error) {
 	if source == "" {
@@ -93,9 +177,507 @@ func (d *lvm) isLVMNotFoundExitError(err error) bool {
 	return false
 }
 
+// parseLogicalVolumeTags parses a line of "lvs -o lv_name,lv_tags" output into the volume name and its tags.
+func (d *lvm) parseLogicalVolumeTags(line string) (string, []string) {
+	fields := strings.Fields(strings.TrimSpace(line))
+	if len(fields) == 0 {
+		return "", nil
+	}
+
+	if len(fields) < 2 {
+		return fields[0], nil
+	}
+
+	return fields[0], strings.Split(fields[1], ",")
+}
+
+// volNameToLVName converts a volume name to a name suitable for use in a logical volume name. Hyphens in the name
+// are escaped as lvmEscapedHyphen, so that the snapshot delimiter can be encoded as a lone lvmSnapshotSeparator.
+// The encoding doesn't depend on the value of shared.SnapshotDelimiter, only on lvmSnapshotSeparator.
+func volNameToLVName(volName string) string {
+	parentName, snapshotName, isSnap := api.GetParentAndSnapshotName(volName)
+
+	lvName := strings.ReplaceAll(parentName, "-", lvmEscapedHyphen)
+	if isSnap {
+		lvName += lvmSnapshotSeparator + strings.ReplaceAll(snapshotName, "-", lvmEscapedHyphen)
+	}
+
+	return lvName
+}
+
+// lvNameToVolName converts a name encoded by volNameToLVName back to the volume name. An escaped hyphen is decoded
+// to a hyphen and a lone lvmSnapshotSeparator to shared.SnapshotDelimiter.
+func lvNameToVolName(lvName string) string {
+	var volName strings.Builder
+	for i := 0; i < len(lvName); i++ {
+		if strings.HasPrefix(lvName[i:], lvmEscapedHyphen) {
+			volName.WriteString("-")
+			i += len(lvmEscapedHyphen) - 1
+		} else if strings.HasPrefix(lvName[i:], lvmSnapshotSeparator) {
+			volName.WriteString(shared.SnapshotDelimiter)
+		} else {
+			volName.WriteByte(lvName[i])
+		}
+	}
+
+	return volName.String()
+}
+
+// runVolumeHook runs the hook command configured in the server's local config key for an instance volume.
+// The hooks are part of the server configuration rather than of the storage pool configuration, as they run
+// programs as root on the host.
+func (d *lvm) runVolumeHook(key string, vol Volume) error {
+	if d.state == nil || d.state.LocalConfig == nil {
+		return nil
+	}
+
+	var command string
+	switch key {
+	case "storage.lvm.post_create_hook":
+		command = d.state.LocalConfig.StorageLVMPostCreateHook()
+	case "storage.lvm.pre_delete_hook":
+		command = d.state.LocalConfig.StorageLVMPreDeleteHook()
+	}
+
+	return d.runVolumeHookCommand(key, command, vol, lvmVolumeHookTimeout)
+}
+
+// runVolumeHookCommand runs a hook command for an instance volume.
+// The volume is described to the command using the LXD_POOL, LXD_VOLUME_NAME, LXD_VOLUME_TYPE and LXD_LV_PATH
+// environment variables. The command is killed if it runs for longer than the timeout. Volumes other than
+// instance volumes don't run hooks.
+func (d *lvm) runVolumeHookCommand(key string, command string, vol Volume, timeout time.Duration) error {
+	if command == "" || vol.IsSnapshot() {
+		return nil
+	}
+
+	// Only run once per instance, for VMs on the block volume rather than on its filesystem volume.
+	if vol.volType != VolumeTypeContainer && !vol.IsVMBlock() {
+		return nil
+	}
+
+	args, err := shellquote.Split(command)
+	if err != nil || len(args) == 0 {
+		return fmt.Errorf("Invalid %s command %q", key, command)
+	}
+
+	volDevPath := d.lvmDevPath(d.config["lvm.vg_name"], vol.volType, vol.contentType, vol.name)
+
+	env := append(os.Environ(),
+		"LXD_POOL="+d.name,
+		"LXD_VOLUME_NAME="+vol.name,
+		"LXD_VOLUME_TYPE="+string(vol.volType),
+		"LXD_LV_PATH="+volDevPath,
+	)
+
+	d.logger.Debug("Running volume hook", logger.Ctx{"key": key, "volName": vol.name, "command": args})
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	_, _, err = shared.RunCommandSplit(ctx, env, nil, args[0], args[1:]...)
+	if err != nil {
+		if ctx.Err() != nil {
+			return fmt.Errorf("Timed out running %s command after %v", key, timeout)
+		}
+
+		return fmt.Errorf("Failed running %s command: %w", key, err)
+	}
+
+	return nil
+}
+
+// validateLVMOvercommitRatio validates a thin pool overcommit ratio, which must be a finite number of at least 1.
+func validateLVMOvercommitRatio(value string) error {
+	ratio, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return fmt.Errorf("Invalid overcommit ratio %q: %w", value, err)
+	}
+
+	if math.IsNaN(ratio) || math.IsInf(ratio, 0) {
+		return fmt.Errorf("Overcommit ratio must be a finite number")
+	}
+
+	if ratio < 1 {
+		return fmt.Errorf("Overcommit ratio cannot be lower than 1")
+	}
+
+	return nil
+}
+
+// validateLVMName validates the name of an LVM volume group or logical volume.
+// LVM only allows the characters a-z, A-Z, 0-9, "+", "_", "." and "-" in names, and names cannot start with "-".
+func validateLVMName(value string) error {
+	if len(value) > 127 {
+		return fmt.Errorf("Name cannot be longer than 127 characters")
+	}
+
+	if value == "." || value == ".." {
+		return fmt.Errorf("Name cannot be %q", value)
+	}
+
+	if strings.HasPrefix(value, "-") {
+		return fmt.Errorf("Name cannot start with a hyphen")
+	}
+
+	for _, r := range value {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || strings.ContainsRune("+_.-", r) {
+			continue
+		}
+
+		return fmt.Errorf("Name contains invalid character %q", r)
+	}
+
+	return nil
+}
+
+// validateLVMThinpoolChunkSize validates the chunk size of a thin pool.
+// LVM requires it to be a multiple of 64KiB between 64KiB and 1GiB, and only powers of two are accepted here.
+func validateLVMThinpoolChunkSize(value string) error {
+	sizeBytes, err := units.ParseByteSizeString(value)
+	if err != nil {
+		return err
+	}
+
+	if sizeBytes < 64*1024 || sizeBytes > 1024*1024*1024 {
+		return fmt.Errorf("Chunk size must be between 64KiB and 1GiB")
+	}
+
+	if sizeBytes&(sizeBytes-1) != 0 {
+		return fmt.Errorf("Chunk size must be a power of two")
+	}
+
+	return nil
+}
+
+// lvmVolumeGroupLockName returns the name of the lock used to serialise the commands modifying a volume group.
+func lvmVolumeGroupLockName(vgName string) string {
+	return fmt.Sprintf("lvm/vg/%s", vgName)
+}
+
+// tryRunVolumeGroupCommand runs an LVM command that modifies the volume group (such as creating, snapshotting,
+// removing, renaming or resizing a logical volume) using runVolumeGroupCommand, retrying it up to 20 times if it
+// fails. The volume group is only locked while each attempt runs so that the commands of other volumes on the
+// same volume group aren't held up by the delay between the attempts.
+func (d *lvm) tryRunVolumeGroupCommand(vgName string, name string, args ...string) (string, error) {
+	run := func() (string, error) { return d.runVolumeGroupCommand(vgName, name, args...) }
+
+	// Retrying won't help if the volume group is gone.
+	vgNotFound := func(err error) bool { return errors.Is(err, ErrVolumeGroupNotFound) }
+
+	return retryLVMCommand(run, vgNotFound)
+}
+
+// runVolumeGroupCommand runs an LVM command that modifies the volume group once. The volume group is locked for
+// the duration of the command so that mutating commands on the same volume group never run concurrently, as the
+// device mapper can fail sporadically when they do. Read-only queries do not need to use this.
+func (d *lvm) runVolumeGroupCommand(vgName string, name string, args ...string) (string, error) {
+	err := d.checkVolumeGroupAvailable(vgName)
+	if err != nil {
+		return "", err
+	}
+
+	unlock, err := locking.Lock(context.TODO(), lvmVolumeGroupLockName(vgName))
+	if err != nil {
+		return "", err
+	}
+
+	defer unlock()
+
+	output, err := d.runLVMCommand(name, args...)
+	if err != nil && d.isLVMVolumeGroupNotFoundError(err, vgName) {
+		d.markVolumeGroupMissing(vgName)
+		return "", fmt.Errorf("%w: %w", ErrVolumeGroupNotFound, err)
+	}
+
+	return output, err
+}
+
+// isLVMVolumeGroupNotFoundError checks whether the supplied error is from an LVM command that failed because the
+// volume group doesn't exist.
+func (d *lvm) isLVMVolumeGroupNotFoundError(err error, vgName string) bool {
+	vgName = strings.ToLower(vgName)
+
+	return d.lvmErrorContains(err, []string{
+		fmt.Sprintf("volume group \"%s\" not found", vgName),
+		fmt.Sprintf("cannot process volume group %s", vgName),
+	})
+}
+
+// markVolumeGroupMissing records that the volume group is missing, so that the operations on it fail early.
+func (d *lvm) markVolumeGroupMissing(vgName string) {
+	lvmMissingVolumeGroupsMu.Lock()
+	_, found := lvmMissingVolumeGroups[vgName]
+	lvmMissingVolumeGroups[vgName] = time.Now()
+	lvmMissingVolumeGroupsMu.Unlock()
+
+	if !found {
+		d.logger.Error("LVM volume group is missing, operations on it will fail until it is available again", logger.Ctx{"vg_name": vgName})
+	}
+}
+
+// markVolumeGroupAvailable clears the missing state of the volume group, if set.
+func (d *lvm) markVolumeGroupAvailable(vgName string) {
+	lvmMissingVolumeGroupsMu.Lock()
+	_, found := lvmMissingVolumeGroups[vgName]
+	delete(lvmMissingVolumeGroups, vgName)
+	lvmMissingVolumeGroupsMu.Unlock()
+
+	if found {
+		d.logger.Info("LVM volume group is available again", logger.Ctx{"vg_name": vgName})
+	}
+}
+
+// forgetVolumeGroup removes the state recorded for the volume group, such as it being missing or its usage.
+// This is used when the pool stops using a volume group name.
+func (d *lvm) forgetVolumeGroup(vgName string) {
+	lvmMissingVolumeGroupsMu.Lock()
+	delete(lvmMissingVolumeGroups, vgName)
+	lvmMissingVolumeGroupsMu.Unlock()
+
+	lvmUsageCacheMu.Lock()
+	for cacheKey := range lvmUsageCache {
+		if strings.HasPrefix(cacheKey, vgName+"/") {
+			delete(lvmUsageCache, cacheKey)
+		}
+	}
+
+	lvmUsageCacheMu.Unlock()
+}
+
+// checkRenamedVolumeGroup checks that the specified volume group, which the pool's volume group was renamed to
+// outside of LXD, contains the pool's thin pool (if used) and a logical volume for each of the pool's instances.
+// The pool's instances are found from their mount paths so that this doesn't rely on the volume group itself.
+func (d *lvm) checkRenamedVolumeGroup(vgName string) error {
+	if d.usesThinpool() {
+		thinPoolExists, err := d.thinpoolExists(vgName, d.thinpoolName())
+		if err != nil {
+			return err
+		}
+
+		if !thinPoolExists {
+			return fmt.Errorf("LVM thin pool %q not found in volume group %q", d.thinpoolName(), vgName)
+		}
+	}
+
+	// List the volumes in the new volume group using a copy of the driver using the new volume group name.
+	renamed := *d
+	renamed.config = make(map[string]string, len(d.config))
+	for k, v := range d.config {
+		renamed.config[k] = v
+	}
+
+	renamed.config["lvm.vg_name"] = vgName
+
+	vols, err := renamed.ListVolumes()
+	if err != nil {
+		return err
+	}
+
+	found := make(map[string]bool, len(vols))
+	for _, vol := range vols {
+		found[filepath.Join(string(vol.Type()), vol.Name())] = true
+	}
+
+	var missing []string
+	for _, volType := range []VolumeType{VolumeTypeContainer, VolumeTypeVM} {
+		entries, err := os.ReadDir(GetVolumeMountPath(d.name, volType, ""))
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+
+		for _, entry := range entries {
+			volKey := filepath.Join(string(volType), entry.Name())
+			if entry.IsDir() && !found[volKey] {
+				missing = append(missing, volKey)
+			}
+		}
+	}
+
+	if len(missing) > 0 {
+		return fmt.Errorf("Logical volumes missing from volume group %q: %s", vgName, strings.Join(missing, ", "))
+	}
+
+	return nil
+}
+
+// checkVolumeGroupAvailable returns ErrVolumeGroupNotFound if the volume group was found missing. The volume group
+// is probed again if it wasn't for lvmVolumeGroupProbeInterval, clearing the missing state if it is back.
+func (d *lvm) checkVolumeGroupAvailable(vgName string) error {
+	lvmMissingVolumeGroupsMu.Lock()
+	lastProbe, found := lvmMissingVolumeGroups[vgName]
+	lvmMissingVolumeGroupsMu.Unlock()
+
+	if !found {
+		return nil
+	}
+
+	if time.Since(lastProbe) >= lvmVolumeGroupProbeInterval {
+		exists, _, err := d.volumeGroupExists(vgName)
+		if err != nil {
+			return err
+		}
+
+		if exists {
+			d.markVolumeGroupAvailable(vgName)
+			return nil
+		}
+
+		d.markVolumeGroupMissing(vgName)
+	}
+
+	return fmt.Errorf("LVM volume group %q is missing: %w", vgName, ErrVolumeGroupNotFound)
+}
+
+// runLVMCommand runs an LVM command and returns its stdout. All LVM commands should be run through it so that
+// they are logged according to lvm.command_log_level. Warnings printed by the command are logged even when it
+// succeeds (unless lvm.log_warnings is disabled) as they can give early notice of problems such as the thin pool
+// running out of space.
+func (d *lvm) runLVMCommand(name string, args ...string) (string, error) {
+	start := d.lvmCommandStarted(name, args)
+	cmd, cmdArgs := lvmCommand(name, args...)
+	stdout, stderr, err := shared.RunCommandSplit(context.TODO(), nil, nil, cmd, cmdArgs...)
+	d.lvmCommandFinished(name, start, err)
+	if err == nil && shared.IsTrueOrEmpty(d.config["lvm.log_warnings"]) {
+		for _, warning := range d.parseLVMWarnings(stderr) {
+			d.logger.Warn("LVM command warning", logger.Ctx{"cmd": name, "warning": warning})
+		}
+	}
+
+	return stdout, err
+}
+
+// tryRunLVMCommand runs an LVM command using runLVMCommand, retrying it up to 20 times if it fails.
+func (d *lvm) tryRunLVMCommand(name string, args ...string) (string, error) {
+	return retryLVMCommand(func() (string, error) { return d.runLVMCommand(name, args...) }, nil)
+}
+
+// retryLVMCommand calls run up to 20 times with a 500ms delay between each call until it runs without an error,
+// in the same way as shared.TryRunCommand. If abort is set and returns true for the error of a failed call, the
+// command isn't retried and that error is returned straight away.
+func retryLVMCommand(run func() (string, error), abort func(err error) bool) (string, error) {
+	var err error
+	var output string
+
+	for i := 0; i < 20; i++ {
+		output, err = run()
+		if err == nil || (abort != nil && abort(err)) {
+			break
+		}
+
+		time.Sleep(500 * time.Millisecond)
+	}
+
+	return output, err
+}
+
+// logLVMCommand logs a message about an LVM command at the level set by lvm.command_log_level.
+func (d *lvm) logLVMCommand(msg string, ctx logger.Ctx) {
+	switch d.config["lvm.command_log_level"] {
+	case "none":
+		return
+	case "info":
+		d.logger.Info(msg, ctx)
+	default:
+		d.logger.Debug(msg, ctx)
+	}
+}
+
+// lvmCommandStarted logs the LVM command about to be run with its arguments and returns the start time to pass
+// to lvmCommandFinished.
+func (d *lvm) lvmCommandStarted(name string, args []string) time.Time {
+	d.logLVMCommand("Running LVM command", logger.Ctx{"cmd": name, "args": args})
+
+	return time.Now()
+}
+
+// lvmCommandFinished logs the exit status of an LVM command started at start and reports its duration.
+func (d *lvm) lvmCommandFinished(name string, start time.Time, err error) {
+	observeCommand(name, start)
+
+	exitCode := 0
+	if err != nil {
+		exitCode = -1
+
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			exitCode = exitErr.ExitCode()
+		}
+	}
+
+	d.logLVMCommand("LVM command finished", logger.Ctx{"cmd": name, "exitCode": exitCode, "duration": time.Since(start)})
+}
+
+// mountRetryPolicy returns the policy used to retry mounting and unmounting volumes, based on the
+// lvm.mount_attempts and lvm.mount_timeout settings.
+func (d *lvm) mountRetryPolicy() mountRetryPolicy {
+	policy := mountRetryPolicy{attempts: 20, timeout: 10 * time.Second}
+
+	attempts, err := strconv.Atoi(d.config["lvm.mount_attempts"])
+	if err == nil && attempts > 0 {
+		policy.attempts = attempts
+	}
+
+	timeout, err := strconv.ParseUint(d.config["lvm.mount_timeout"], 10, 32)
+	if err == nil {
+		policy.timeout = time.Duration(timeout) * time.Second
+	}
+
+	return policy
+}
+
+// parseLVMWarnings returns the warnings in the stderr output of an LVM command.
+func (d *lvm) parseLVMWarnings(stderr string) []string {
+	var warnings []string
+
+	for _, line := range strings.Split(stderr, "\n") {
+		_, warning, found := strings.Cut(line, "WARNING:")
+		if found {
+			warnings = append(warnings, strings.TrimSpace(warning))
+		}
+	}
+
+	return warnings
+}
+
+// isLVMThinpoolFullError checks whether the supplied error is from an LVM command that failed because the thin
+// pool has run out of data space.
+func (d *lvm) isLVMThinpoolFullError(err error) bool {
+	return d.lvmErrorContains(err, lvmThinpoolFullMessages)
+}
+
+// isLVMThinpoolMetadataFullError checks whether the supplied error is from an LVM command that failed because the
+// thin pool has run out of metadata space.
+func (d *lvm) isLVMThinpoolMetadataFullError(err error) bool {
+	return d.lvmErrorContains(err, lvmThinpoolMetadataFullMessages)
+}
+
+// isLVMVolumeInUseError checks whether the supplied error is from an LVM command that failed because the logical
+// volume's device is held open.
+func (d *lvm) isLVMVolumeInUseError(err error) bool {
+	return d.lvmErrorContains(err, lvmVolumeInUseMessages)
+}
+
+// lvmErrorContains checks whether the stderr output of the failed LVM command contains any of the messages.
+func (d *lvm) lvmErrorContains(err error, messages []string) bool {
+	var runErr shared.RunError
+	if !errors.As(err, &runErr) || runErr.StdErr() == nil {
+		return false
+	}
+
+	stderr := strings.ToLower(runErr.StdErr().String())
+	for _, msg := range messages {
+		if strings.Contains(stderr, msg) {
+			return true
+		}
+	}
+
+	return false
+}
+
 // pysicalVolumeExists checks if an LVM Physical Volume exists.
 func (d *lvm) pysicalVolumeExists(pvName string) (bool, error) {
-	_, err := shared.RunCommand("pvs", "--noheadings", "-o", "pv_name", pvName)
+	_, err := d.runLVMCommand("pvs", "--noheadings", "-o", "pv_name", pvName)
 	if err != nil {
 		if d.isLVMNotFoundExitError(err) {
 			return false, nil
@@ -109,7 +691,7 @@ func (d *lvm) pysicalVolumeExists(pvName string) (bool, error) {
 
 // volumeGroupExists checks if an LVM Volume Group exists and returns any tags on that volume group.
 func (d *lvm) volumeGroupExists(vgName string) (bool, []string, error) {
-	output, err := shared.RunCommand("vgs", "--noheadings", "-o", "vg_tags", vgName)
+	output, err := d.runLVMCommand("vgs", "--noheadings", "-o", "vg_tags", vgName)
 	if err != nil {
 		if d.isLVMNotFoundExitError(err) {
 			return false, nil, nil
@@ -126,7 +708,22 @@ func (d *lvm) volumeGroupExists(vgName string) (bool, []string, error) {
 
 // volumeGroupExtentSize gets the volume group's physical extent size in bytes.
 func (d *lvm) volumeGroupExtentSize(vgName string) (int64, error) {
-	output, err := shared.RunCommand("vgs", "--noheadings", "--nosuffix", "--units", "b", "-o", "vg_extent_size", vgName)
+	output, err := d.runLVMCommand("vgs", "--noheadings", "--nosuffix", "--units", "b", "-o", "vg_extent_size", vgName)
+	if err != nil {
+		if d.isLVMNotFoundExitError(err) {
+			return -1, api.StatusErrorf(http.StatusNotFound, "LVM volume group not found")
+		}
+
+		return -1, err
+	}
+
+	output = strings.TrimSpace(output)
+	return strconv.ParseInt(output, 10, 64)
+}
+
+// volumeGroupFree gets the free space of a volume group in bytes.
+func (d *lvm) volumeGroupFree(vgName string) (int64, error) {
+	output, err := d.runLVMCommand("vgs", "--noheadings", "--nosuffix", "--units", "b", "-o", "vg_free", vgName)
 	if err != nil {
 		if d.isLVMNotFoundExitError(err) {
 			return -1, api.StatusErrorf(http.StatusNotFound, "LVM volume group not found")
@@ -141,7 +738,7 @@ func (d *lvm) volumeGroupExtentSize(vgName string) (int64, error) {
 
 // countLogicalVolumes gets the count of volumes (both normal and thin) in a volume group.
 func (d *lvm) countLogicalVolumes(vgName string) (int, error) {
-	output, err := shared.RunCommand("vgs", "--noheadings", "-o", "lv_count", vgName)
+	output, err := d.runLVMCommand("vgs", "--noheadings", "-o", "lv_count", vgName)
 	if err != nil {
 		if d.isLVMNotFoundExitError(err) {
 			return -1, api.StatusErrorf(http.StatusNotFound, "LVM volume group not found")
@@ -156,7 +753,7 @@ func (d *lvm) countLogicalVolumes(vgName string) (int, error) {
 
 // countThinVolumes gets the count of thin volumes in a thin pool.
 func (d *lvm) countThinVolumes(vgName, poolName string) (int, error) {
-	output, err := shared.RunCommand("lvs", "--noheadings", "-o", "thin_count", fmt.Sprintf("%s/%s", vgName, poolName))
+	output, err := d.runLVMCommand("lvs", "--noheadings", "-o", "thin_count", fmt.Sprintf("%s/%s", vgName, poolName))
 	if err != nil {
 		if d.isLVMNotFoundExitError(err) {
 			return -1, api.StatusErrorf(http.StatusNotFound, "LVM volume group not found")
@@ -171,7 +768,7 @@ func (d *lvm) countThinVolumes(vgName, poolName string) (int, error) {
 
 // thinpoolExists checks whether the specified thinpool exists in a volume group.
 func (d *lvm) thinpoolExists(vgName string, poolName string) (bool, error) {
-	output, err := shared.RunCommand("lvs", "--noheadings", "-o", "lv_attr", fmt.Sprintf("%s/%s", vgName, poolName))
+	output, err := d.runLVMCommand("lvs", "--noheadings", "-o", "lv_attr", fmt.Sprintf("%s/%s", vgName, poolName))
 	if err != nil {
 		if d.isLVMNotFoundExitError(err) {
 			return false, nil
@@ -186,28 +783,492 @@ func (d *lvm) thinpoolExists(vgName string, poolName string) (bool, error) {
 		return true, nil
 	}
 
-	return false, fmt.Errorf("LVM volume named %q exists but is not a thin pool", poolName)
+	return false, fmt.Errorf("LVM volume named %q exists but is not a thin pool (use lvm.thinpool_name to pick a different name)", poolName)
 }
 
-// logicalVolumeExists checks whether the specified logical volume exists.
-func (d *lvm) logicalVolumeExists(volDevPath string) (bool, error) {
-	_, err := shared.RunCommand("lvs", "--noheadings", "-o", "lv_name", volDevPath)
+// checkImageThinpool checks that the thin pool to use for image volumes exists in the volume group.
+func (d *lvm) checkImageThinpool(vgName string, poolName string) error {
+	thinPoolExists, err := d.thinpoolExists(vgName, poolName)
 	if err != nil {
-		if d.isLVMNotFoundExitError(err) {
-			return false, nil
-		}
+		return err
+	}
 
-		return false, fmt.Errorf("Error checking for LVM logical volume %q: %w", volDevPath, err)
+	if !thinPoolExists {
+		return fmt.Errorf("The image thin pool %q does not exist in volume group %q", poolName, vgName)
 	}
 
-	return true, nil
+	return nil
 }
 
-// createDefaultThinPool creates the default thinpool in the pool's volume group.
+// logicalVolumeThinpool returns the name of the thin pool that the logical volume is in.
+// An empty string is returned for logical volumes that aren't thin volumes.
+func (d *lvm) logicalVolumeThinpool(volDevPath string) (string, error) {
+	output, err := d.runLVMCommand("lvs", "--noheadings", "-o", "pool_lv", volDevPath)
+	if err != nil {
+		return "", fmt.Errorf("Error getting thin pool of LVM logical volume %q: %w", volDevPath, err)
+	}
+
+	return strings.TrimSpace(output), nil
+}
+
+// thinpoolTags returns the tags of the specified thin pool.
+func (d *lvm) thinpoolTags(vgName string, poolName string) ([]string, error) {
+	output, err := d.runLVMCommand("lvs", "--noheadings", "-o", "lv_tags", fmt.Sprintf("%s/%s", vgName, poolName))
+	if err != nil {
+		return nil, fmt.Errorf("Error getting tags of LVM thin pool %q: %w", poolName, err)
+	}
+
+	return strings.Split(strings.TrimSpace(output), ","), nil
+}
+
+// thinpoolDiscards returns how the specified thin pool handles discards, one of ignore, nopassdown or passdown.
+func (d *lvm) thinpoolDiscards(vgName string, poolName string) (string, error) {
+	output, err := d.runLVMCommand("lvs", "--noheadings", "-o", "discards", fmt.Sprintf("%s/%s", vgName, poolName))
+	if err != nil {
+		return "", fmt.Errorf("Error getting discards mode of LVM thin pool %q: %w", poolName, err)
+	}
+
+	return strings.TrimSpace(output), nil
+}
+
+// thinpoolReclaimed returns true if the pool's thin pool is missing because it was reclaimed.
+func (d *lvm) thinpoolReclaimed() (bool, error) {
+	if !d.usesThinpool() || shared.IsFalseOrEmpty(d.config["lvm.thinpool_reclaim"]) {
+		return false, nil
+	}
+
+	thinPoolExists, err := d.thinpoolExists(d.config["lvm.vg_name"], d.thinpoolName())
+	if err != nil {
+		return false, err
+	}
+
+	return !thinPoolExists, nil
+}
+
+// ensureThinpool re-creates the pool's thin pool if it was reclaimed after its last volume was removed.
+func (d *lvm) ensureThinpool() error {
+	reclaimed, err := d.thinpoolReclaimed()
+	if err != nil || !reclaimed {
+		return err
+	}
+
+	var thinpoolSizeBytes int64
+
+	// If not using loop file then the size setting controls the size of the thinpool volume.
+	if !filepath.IsAbs(d.config["source"]) || shared.IsBlockdevPath(d.config["source"]) {
+		thinpoolSizeBytes, err = d.roundedSizeBytesString(d.config["size"])
+		if err != nil {
+			return fmt.Errorf("Invalid size: %w", err)
+		}
+	}
+
+	err = d.createDefaultThinPool(d.Info().Version, d.thinpoolName(), thinpoolSizeBytes)
+	if err != nil {
+		return err
+	}
+
+	d.logger.Debug("Thin pool re-created", logger.Ctx{"vg_name": d.config["lvm.vg_name"], "thinpool_name": d.thinpoolName()})
+
+	return nil
+}
+
+// reclaimThinpool removes the pool's thin pool if lvm.thinpool_reclaim is enabled, the thin pool was created by
+// LXD and it has no more thin volumes. This returns the thin pool's space to the volume group.
+// The volume group is locked from counting the thin volumes until the thin pool is removed, so that a volume can't
+// be created in it in the meantime. The thin pool is removed without forcing it, so LVM refuses to remove it if it
+// has thin volumes anyway.
+func (d *lvm) reclaimThinpool() error {
+	if !d.usesThinpool() || shared.IsFalseOrEmpty(d.config["lvm.thinpool_reclaim"]) {
+		return nil
+	}
+
+	vgName := d.config["lvm.vg_name"]
+	poolName := d.thinpoolName()
+
+	unlock, err := locking.Lock(context.TODO(), lvmVolumeGroupLockName(vgName))
+	if err != nil {
+		return err
+	}
+
+	defer unlock()
+
+	thinVolCount, err := d.countThinVolumes(vgName, poolName)
+	if err != nil {
+		if api.StatusErrorCheck(err, http.StatusNotFound) {
+			return nil
+		}
+
+		return err
+	}
+
+	if thinVolCount > 0 {
+		return nil
+	}
+
+	// Never remove a thin pool that was not created by LXD.
+	tags, err := d.thinpoolTags(vgName, poolName)
+	if err != nil {
+		return err
+	}
+
+	if !shared.ValueInSlice(lvmThinpoolMarker, tags) {
+		return nil
+	}
+
+	// Removing an active logical volume asks for confirmation, so deactivate the thin pool first. This fails if
+	// any of its thin volumes are active.
+	poolDevPath := d.lvmDevPath(vgName, "", "", poolName)
+	_, err = d.runLVMCommand("lvchange", "--activate", "n", poolDevPath)
+	if err != nil {
+		return fmt.Errorf("Failed to deactivate thin pool %q in volume group %q before reclaiming it: %w", poolName, vgName, err)
+	}
+
+	_, err = d.runLVMCommand("lvremove", poolDevPath)
+	if err != nil {
+		return fmt.Errorf("Failed to reclaim thin pool %q from volume group %q: %w", poolName, vgName, err)
+	}
+
+	d.logger.Debug("Thin pool reclaimed", logger.Ctx{"vg_name": vgName, "thinpool_name": poolName})
+
+	return nil
+}
+
+// thinpoolDataUsage returns the percentage of the thin pool's data space that is in use.
+func (d *lvm) thinpoolDataUsage(vgName string, poolName string) (float64, error) {
+	output, err := d.runLVMCommand("lvs", "--noheadings", "-o", "data_percent", fmt.Sprintf("%s/%s", vgName, poolName))
+	if err != nil {
+		return -1, fmt.Errorf("Error getting data usage of LVM thin pool %q: %w", poolName, err)
+	}
+
+	return d.parseThinpoolUsage(output)
+}
+
+// thinpoolMetadataUsage returns the percentage of the thin pool's metadata space that is in use.
+func (d *lvm) thinpoolMetadataUsage(vgName string, poolName string) (float64, error) {
+	output, err := d.runLVMCommand("lvs", "--noheadings", "-o", "metadata_percent", fmt.Sprintf("%s/%s", vgName, poolName))
+	if err != nil {
+		return -1, fmt.Errorf("Error getting metadata usage of LVM thin pool %q: %w", poolName, err)
+	}
+
+	return d.parseThinpoolUsage(output)
+}
+
+// parseThinpoolUsage parses the data_percent or metadata_percent output of the lvs command for a thin pool.
+func (d *lvm) parseThinpoolUsage(output string) (float64, error) {
+	output = strings.TrimSpace(output)
+	if output == "" {
+		return -1, fmt.Errorf("No usage reported for LVM thin pool")
+	}
+
+	usedPerc, err := strconv.ParseFloat(output, 64)
+	if err != nil {
+		return -1, fmt.Errorf("Failed parsing thin pool used percentage (%q): %w", output, err)
+	}
+
+	return usedPerc, nil
+}
+
+// volumeGroupUsage returns the size, free space and logical volume count of the volume group and, if the pool uses
+// a thin pool, the thin pool's data and metadata usage. The result is cached for lvmUsageCacheTTL.
+func (d *lvm) volumeGroupUsage() (*api.ResourcesStoragePoolLVM, error) {
+	vgName := d.config["lvm.vg_name"]
+
+	thinPoolName := ""
+	if d.usesThinpool() {
+		thinPoolName = d.thinpoolName()
+	}
+
+	cacheKey := fmt.Sprintf("%s/%s", vgName, thinPoolName)
+
+	lvmUsageCacheMu.Lock()
+	entry, found := lvmUsageCache[cacheKey]
+	lvmUsageCacheMu.Unlock()
+
+	if found && time.Since(entry.gathered) < lvmUsageCacheTTL {
+		usage := entry.usage
+		return &usage, nil
+	}
+
+	out, err := d.runLVMCommand("vgs", vgName, "--noheadings", "--units", "b", "--nosuffix", "--separator", ",", "-o", "vg_size,vg_free,lv_count")
+	if err != nil {
+		return nil, fmt.Errorf("Failed getting usage of LVM volume group %q: %w", vgName, err)
+	}
+
+	usage, err := d.parseVolumeGroupUsage(out)
+	if err != nil {
+		return nil, err
+	}
+
+	if thinPoolName != "" {
+		exists, err := d.thinpoolExists(vgName, thinPoolName)
+		if err != nil {
+			return nil, err
+		}
+
+		// A reclaimed thin pool has no usage to report until it is re-created.
+		if exists {
+			out, err := d.runLVMCommand("lvs", "--noheadings", "--separator", ",", "-o", "data_percent,metadata_percent", fmt.Sprintf("%s/%s", vgName, thinPoolName))
+			if err != nil {
+				return nil, fmt.Errorf("Failed getting usage of LVM thin pool %q: %w", thinPoolName, err)
+			}
+
+			usage.ThinPoolDataUsage, usage.ThinPoolMetadataUsage, err = d.parseThinpoolDataMetadataUsage(out)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	lvmUsageCacheMu.Lock()
+	lvmUsageCache[cacheKey] = lvmUsageCacheEntry{usage: *usage, gathered: time.Now()}
+	lvmUsageCacheMu.Unlock()
+
+	return usage, nil
+}
+
+// parseVolumeGroupUsage parses the "vg_size,vg_free,lv_count" output of the vgs command.
+func (d *lvm) parseVolumeGroupUsage(output string) (*api.ResourcesStoragePoolLVM, error) {
+	parts := strings.Split(strings.TrimSpace(output), ",")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("Unexpected output from vgs command: %q", strings.TrimSpace(output))
+	}
+
+	values := make([]uint64, 0, len(parts))
+	for _, part := range parts {
+		value, err := strconv.ParseUint(strings.TrimSpace(part), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("Failed parsing vgs output %q: %w", strings.TrimSpace(output), err)
+		}
+
+		values = append(values, value)
+	}
+
+	return &api.ResourcesStoragePoolLVM{
+		VolumeGroupTotal: values[0],
+		VolumeGroupFree:  values[1],
+		LogicalVolumes:   values[2],
+	}, nil
+}
+
+// parseThinpoolDataMetadataUsage parses the "data_percent,metadata_percent" output of the lvs command for a thin pool.
+func (d *lvm) parseThinpoolDataMetadataUsage(output string) (float64, float64, error) {
+	parts := strings.Split(strings.TrimSpace(output), ",")
+	if len(parts) != 2 {
+		return -1, -1, fmt.Errorf("Unexpected output from lvs command: %q", strings.TrimSpace(output))
+	}
+
+	dataPerc, err := d.parseThinpoolUsage(parts[0])
+	if err != nil {
+		return -1, -1, err
+	}
+
+	metaPerc, err := d.parseThinpoolUsage(parts[1])
+	if err != nil {
+		return -1, -1, err
+	}
+
+	return dataPerc, metaPerc, nil
+}
+
+// thinpoolFullError returns an ErrThinPoolFull error describing which thin pool is full and its data usage.
+func (d *lvm) thinpoolFullError(vgName string, poolName string, dataPerc float64) error {
+	if dataPerc < 0 {
+		return fmt.Errorf("LVM thin pool %q in volume group %q has run out of data space: %w", poolName, vgName, ErrThinPoolFull)
+	}
+
+	return fmt.Errorf("LVM thin pool %q in volume group %q has run out of data space (%.2f%% used): %w", poolName, vgName, dataPerc, ErrThinPoolFull)
+}
+
+// thinpoolMetadataFullError returns an ErrThinPoolMetadataFull error describing which thin pool is full and its
+// metadata usage.
+func (d *lvm) thinpoolMetadataFullError(vgName string, poolName string, metaPerc float64) error {
+	if metaPerc < 0 {
+		return fmt.Errorf("LVM thin pool %q in volume group %q has run out of metadata space: %w", poolName, vgName, ErrThinPoolMetadataFull)
+	}
+
+	return fmt.Errorf("LVM thin pool %q in volume group %q has run out of metadata space (%.2f%% used): %w", poolName, vgName, metaPerc, ErrThinPoolMetadataFull)
+}
+
+// thinpoolSpaceError converts an error from an LVM command that failed because the thin pool ran out of data or
+// metadata space into an ErrThinPoolFull or ErrThinPoolMetadataFull error. Other errors are returned unchanged.
+func (d *lvm) thinpoolSpaceError(vgName string, poolName string, err error) error {
+	if d.isLVMThinpoolMetadataFullError(err) {
+		metaPerc, _ := d.thinpoolMetadataUsage(vgName, poolName)
+		return d.thinpoolMetadataFullError(vgName, poolName, metaPerc)
+	}
+
+	if d.isLVMThinpoolFullError(err) {
+		dataPerc, _ := d.thinpoolDataUsage(vgName, poolName)
+		return d.thinpoolFullError(vgName, poolName, dataPerc)
+	}
+
+	return err
+}
+
+// extendThinpoolMetadata doubles the size of the thin pool's metadata volume, up to the maximum size LVM supports.
+// Once the metadata volume is at its maximum size a warning is logged and it is left as is.
+func (d *lvm) extendThinpoolMetadata(vgName string, poolName string) error {
+	lvmThinPool := fmt.Sprintf("%s/%s", vgName, poolName)
+
+	output, err := d.runLVMCommand("lvs", "--noheadings", "--nosuffix", "--units", "b", "-o", "lv_metadata_size", lvmThinPool)
+	if err != nil {
+		return fmt.Errorf("Error getting metadata size of LVM thin pool %q: %w", poolName, err)
+	}
+
+	metadataSizeBytes, err := strconv.ParseInt(strings.TrimSpace(output), 10, 64)
+	if err != nil {
+		return fmt.Errorf("Failed parsing thin pool metadata size (%q): %w", strings.TrimSpace(output), err)
+	}
+
+	if metadataSizeBytes >= lvmThinpoolMetadataMaxSize {
+		d.logger.Warn("LVM thin pool metadata is at its maximum size and cannot be extended", logger.Ctx{"vg_name": vgName, "thinpool_name": poolName, "size": fmt.Sprintf("%db", metadataSizeBytes)})
+		return nil
+	}
+
+	newMetadataSizeBytes := min(metadataSizeBytes*2, lvmThinpoolMetadataMaxSize)
+
+	_, err = d.tryRunVolumeGroupCommand(vgName, "lvextend", "--poolmetadatasize", fmt.Sprintf("%db", newMetadataSizeBytes), lvmThinPool)
+	if err != nil {
+		return fmt.Errorf("Error extending metadata of LVM thin pool %q: %w", poolName, err)
+	}
+
+	if newMetadataSizeBytes == lvmThinpoolMetadataMaxSize {
+		d.logger.Warn("LVM thin pool metadata extended to its maximum size", logger.Ctx{"vg_name": vgName, "thinpool_name": poolName, "size": fmt.Sprintf("%db", newMetadataSizeBytes)})
+	} else {
+		d.logger.Debug("Thin pool metadata extended", logger.Ctx{"vg_name": vgName, "thinpool_name": poolName, "size": fmt.Sprintf("%db", newMetadataSizeBytes)})
+	}
+
+	return nil
+}
+
+// checkThinpoolSpace returns an ErrThinPoolFull or ErrThinPoolMetadataFull error if the thin pool has no data or
+// metadata space left. If lvm.thinpool_metadata_autoextend is enabled, the metadata volume is extended when its
+// usage reaches lvmThinpoolMetadataAutoextendPercent.
+func (d *lvm) checkThinpoolSpace(vgName string, poolName string) error {
+	metaPerc, err := d.thinpoolMetadataUsage(vgName, poolName)
+	if err != nil {
+		return err
+	}
+
+	if metaPerc >= lvmThinpoolMetadataAutoextendPercent {
+		if shared.IsTrue(d.config["lvm.thinpool_metadata_autoextend"]) {
+			err = d.extendThinpoolMetadata(vgName, poolName)
+			if err != nil {
+				return err
+			}
+
+			metaPerc, err = d.thinpoolMetadataUsage(vgName, poolName)
+			if err != nil {
+				return err
+			}
+		} else {
+			d.logger.Warn("LVM thin pool metadata usage is high", logger.Ctx{"vg_name": vgName, "thinpool_name": poolName, "metadata_percent": metaPerc})
+		}
+	}
+
+	if metaPerc >= 100 {
+		return d.thinpoolMetadataFullError(vgName, poolName, metaPerc)
+	}
+
+	dataPerc, err := d.thinpoolDataUsage(vgName, poolName)
+	if err != nil {
+		return err
+	}
+
+	if dataPerc >= 100 {
+		return d.thinpoolFullError(vgName, poolName, dataPerc)
+	}
+
+	return nil
+}
+
+// checkThinpoolOvercommit checks that adding newSizeBytes to the virtual size of the thin pool (by creating,
+// snapshotting or growing a thin volume) doesn't take the total virtual size of its thin volumes over
+// lvm.max_overcommit_ratio times the size of the thin pool.
+func (d *lvm) checkThinpoolOvercommit(vgName string, poolName string, newSizeBytes int64) error {
+	if d.config["lvm.max_overcommit_ratio"] == "" {
+		return nil
+	}
+
+	maxRatio, err := strconv.ParseFloat(d.config["lvm.max_overcommit_ratio"], 64)
+	if err != nil {
+		return fmt.Errorf("Invalid lvm.max_overcommit_ratio: %w", err)
+	}
+
+	output, err := d.runLVMCommand("lvs", "--noheadings", "--units", "b", "--nosuffix", "--separator", ",", "-o", "lv_name,lv_size,pool_lv", vgName)
+	if err != nil {
+		return fmt.Errorf("Failed getting logical volume sizes in volume group %q: %w", vgName, err)
+	}
+
+	poolSize, virtualSize, err := d.parseThinpoolVirtualSize(output, poolName)
+	if err != nil {
+		return fmt.Errorf("Failed getting virtual size of LVM thin pool %q in volume group %q: %w", poolName, vgName, err)
+	}
+
+	ratio := float64(virtualSize) / float64(poolSize)
+	newRatio := float64(virtualSize+newSizeBytes) / float64(poolSize)
+	if newRatio > maxRatio {
+		return api.StatusErrorf(http.StatusInsufficientStorage, "Adding %d bytes of thin volumes would overcommit LVM thin pool %q in volume group %q to a ratio of %.2f (currently %.2f), exceeding the lvm.max_overcommit_ratio limit of %.2f", newSizeBytes, poolName, vgName, newRatio, ratio, maxRatio)
+	}
+
+	return nil
+}
+
+// parseThinpoolVirtualSize parses the output of "lvs --units b --nosuffix --separator , -o lv_name,lv_size,pool_lv"
+// and returns the size of the specified thin pool and the total virtual size of the thin volumes in it.
+func (d *lvm) parseThinpoolVirtualSize(output string, poolName string) (int64, int64, error) {
+	var poolSize, virtualSize int64
+
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Split(strings.TrimSpace(line), ",")
+		if len(fields) != 3 {
+			continue
+		}
+
+		if fields[0] != poolName && fields[2] != poolName {
+			continue
+		}
+
+		size, err := strconv.ParseInt(fields[1], 10, 64)
+		if err != nil {
+			return -1, -1, fmt.Errorf("Invalid size %q of logical volume %q: %w", fields[1], fields[0], err)
+		}
+
+		if fields[0] == poolName {
+			poolSize = size
+		} else {
+			virtualSize += size
+		}
+	}
+
+	if poolSize <= 0 {
+		return -1, -1, fmt.Errorf("Thin pool %q not found", poolName)
+	}
+
+	return poolSize, virtualSize, nil
+}
+
+// logicalVolumeExists checks whether the specified logical volume exists.
+func (d *lvm) logicalVolumeExists(volDevPath string) (bool, error) {
+	_, err := d.runLVMCommand("lvs", "--noheadings", "-o", "lv_name", volDevPath)
+	if err != nil {
+		if d.isLVMNotFoundExitError(err) {
+			return false, nil
+		}
+
+		return false, fmt.Errorf("Error checking for LVM logical volume %q: %w", volDevPath, err)
+	}
+
+	return true, nil
+}
+
+// createDefaultThinPool creates the default thinpool in the pool's volume group.
 // If thinpoolSizeBytes >0 will manually set the thinpool volume size. Otherwise it will use 100% of the free space
 // in the volume group.
 // If pool lvm.thinpool_metadata_size setting >0 will manually set metadata size for the thinpool, otherwise LVM
 // will pick an appropriate size.
+// If pool lvm.thinpool_chunk_size setting >0 will manually set the chunk size for the thinpool, otherwise LVM
+// will pick an appropriate size.
 func (d *lvm) createDefaultThinPool(lvmVersion, thinPoolName string, thinpoolSizeBytes int64) error {
 	isRecent, err := d.lvmVersionIsAtLeast(lvmVersion, "2.02.99")
 	if err != nil {
@@ -219,6 +1280,7 @@ func (d *lvm) createDefaultThinPool(lvmVersion, thinPoolName string, thinpoolSiz
 	args := []string{
 		"--yes",
 		"--wipesignatures", "y",
+		"--addtag", lvmThinpoolMarker,
 		"--thinpool", lvmThinPool,
 	}
 
@@ -231,7 +1293,26 @@ func (d *lvm) createDefaultThinPool(lvmVersion, thinPoolName string, thinpoolSiz
 		args = append(args, "--poolmetadatasize", fmt.Sprintf("%db", thinpoolMetadataSizeBytes))
 	}
 
+	thinpoolChunkSizeBytes, err := units.ParseByteSizeString(d.config["lvm.thinpool_chunk_size"])
+	if err != nil {
+		return fmt.Errorf("Invalid lvm.thinpool_chunk_size: %w", err)
+	}
+
+	if thinpoolChunkSizeBytes > 0 {
+		args = append(args, "--chunksize", fmt.Sprintf("%db", thinpoolChunkSizeBytes))
+	}
+
 	if thinpoolSizeBytes > 0 {
+		// Check the volume group has room for the thin pool to give a clear error rather than lvcreate's.
+		vgFreeBytes, err := d.volumeGroupFree(d.config["lvm.vg_name"])
+		if err != nil {
+			return fmt.Errorf("Error getting free space of LVM volume group %q: %w", d.config["lvm.vg_name"], err)
+		}
+
+		if thinpoolSizeBytes > vgFreeBytes {
+			return fmt.Errorf("Not enough free space in LVM volume group %q for thin pool %q (%d bytes requested, %d bytes free)", d.config["lvm.vg_name"], thinPoolName, thinpoolSizeBytes, vgFreeBytes)
+		}
+
 		args = append(args, "--size", fmt.Sprintf("%db", thinpoolSizeBytes))
 	} else if isRecent {
 		args = append(args, "--extents", "100%FREE")
@@ -255,14 +1336,14 @@ func (d *lvm) createDefaultThinPool(lvmVersion, thinPoolName string, thinpoolSiz
 	}
 
 	// Create the thin pool volume.
-	_, err = shared.TryRunCommand("lvcreate", args...)
+	_, err = d.tryRunVolumeGroupCommand(d.config["lvm.vg_name"], "lvcreate", args...)
 	if err != nil {
 		return fmt.Errorf("Error creating LVM thin pool named %q: %w", thinPoolName, err)
 	}
 
 	if !isRecent && thinpoolSizeBytes <= 0 {
 		// Grow it to the maximum VG size (two step process required by old LVM).
-		_, err = shared.TryRunCommand("lvextend", "--alloc", "anywhere", "-l", "100%FREE", lvmThinPool)
+		_, err = d.tryRunVolumeGroupCommand(d.config["lvm.vg_name"], "lvextend", "--alloc", "anywhere", "-l", "100%FREE", lvmThinPool)
 		if err != nil {
 			return fmt.Errorf("Error growing LVM thin pool named %q: %w", thinPoolName, err)
 		}
@@ -319,147 +1400,647 @@ func (d *lvm) roundedSizeBytesString(size string) (int64, error) {
 func (d *lvm) createLogicalVolume(vgName, thinPoolName string, vol Volume, makeThinLv bool) error {
 	var err error
 
-	lvSizeBytes, err := d.roundedSizeBytesString(vol.ConfigSize())
-	if err != nil {
-		return err
+	lvFullName := d.lvmFullVolumeName(vol.volType, vol.contentType, vol.name)
+
+	lvSizeBytes, err := d.roundedSizeBytesString(vol.ConfigSize())
+	if err != nil {
+		return fmt.Errorf("Invalid size for LVM logical volume %q in volume group %q: %w", lvFullName, vgName, err)
+	}
+
+	args := []string{
+		"--name", lvFullName,
+		"--yes",
+		"--wipesignatures", "y",
+		"--addtag", lvmVolumeMarker,
+	}
+
+	if makeThinLv {
+		// Only the pool's thin pool is reclaimed, a separate image thin pool is managed outside of LXD.
+		if thinPoolName == d.thinpoolName() {
+			err = d.ensureThinpool()
+			if err != nil {
+				return fmt.Errorf("Failed ensuring LVM thin pool %q in volume group %q for logical volume %q: %w", thinPoolName, vgName, lvFullName, err)
+			}
+		}
+
+		// Check the thin pool is still there and still a thin pool, in case it was changed outside of LXD.
+		thinPoolExists, err := d.thinpoolExists(vgName, thinPoolName)
+		if err != nil {
+			return fmt.Errorf("Failed checking LVM thin pool %q in volume group %q for logical volume %q: %w", thinPoolName, vgName, lvFullName, err)
+		}
+
+		if !thinPoolExists {
+			return api.StatusErrorf(http.StatusNotFound, "LVM thin pool %q not found in volume group %q", thinPoolName, vgName)
+		}
+
+		// Check the thin pool has data space left before trying to create a volume in it.
+		err = d.checkThinpoolSpace(vgName, thinPoolName)
+		if err != nil {
+			return err
+		}
+
+		err = d.checkThinpoolOvercommit(vgName, thinPoolName, lvSizeBytes)
+		if err != nil {
+			return err
+		}
+
+		targetVg := fmt.Sprintf("%s/%s", vgName, thinPoolName)
+		args = append(args,
+			"--thin",
+			"--virtualsize", fmt.Sprintf("%db", lvSizeBytes),
+			targetVg,
+		)
+	} else {
+		args = append(args,
+			"--size", fmt.Sprintf("%db", lvSizeBytes),
+			vgName,
+		)
+
+		// As we are creating a normal logical volume we can apply stripes settings if specified.
+		stripes := vol.ExpandedConfig("lvm.stripes")
+		if stripes != "" {
+			args = append(args, "--stripes", stripes)
+
+			stripeSize := vol.ExpandedConfig("lvm.stripes.size")
+			if stripeSize != "" {
+				stripSizeBytes, err := d.roundedSizeBytesString(stripeSize)
+				if err != nil {
+					return fmt.Errorf("Invalid volume stripe size %q: %w", stripeSize, err)
+				}
+
+				args = append(args, "--stripesize", fmt.Sprintf("%db", stripSizeBytes))
+			}
+		}
+	}
+
+	_, err = d.tryRunVolumeGroupCommand(vgName, "lvcreate", args...)
+	if err != nil {
+		if makeThinLv {
+			return fmt.Errorf("Error creating LVM logical volume %q in thin pool %q of volume group %q: %w", lvFullName, thinPoolName, vgName, d.thinpoolSpaceError(vgName, thinPoolName, err))
+		}
+
+		return fmt.Errorf("Error creating LVM logical volume %q in volume group %q: %w", lvFullName, vgName, err)
+	}
+
+	volDevPath := d.lvmDevPath(vgName, vol.volType, vol.contentType, vol.name)
+
+	// Allocate all of the thin volume's blocks up front if requested, so that the first writes to the
+	// volume don't have to wait for the thin pool to allocate them.
+	preallocate := makeThinLv && shared.IsTrue(vol.ExpandedConfig("lvm.preallocate"))
+	if preallocate {
+		d.logger.Debug("Pre-allocating logical volume", logger.Ctx{"dev": volDevPath, "size": fmt.Sprintf("%db", lvSizeBytes)})
+
+		err = zeroBlockDevice(volDevPath, lvSizeBytes)
+		if err != nil {
+			return fmt.Errorf("Failed pre-allocating LVM logical volume %q: %w", lvFullName, err)
+		}
+	}
+
+	if vol.contentType == ContentTypeFS {
+		// Don't let mkfs discard the blocks that were just allocated.
+		// Label the filesystem with the volume name to help identifying it outside of LXD.
+		fsType := vol.ConfigBlockFilesystem()
+		_, err = makeFSType(volDevPath, fsType, &mkfsOptions{NoDiscard: preallocate, Label: filesystemLabel(fsType, vol.name)})
+		if err != nil {
+			return fmt.Errorf("Error making %s filesystem on LVM logical volume %q: %w", fsType, volDevPath, err)
+		}
+	}
+
+	isRecent, err := d.lvmVersionIsAtLeast(lvmVersion, "2.02.99")
+	if err != nil {
+		return fmt.Errorf("Error checking LVM version: %w", err)
+	}
+
+	if isRecent {
+		// Disable auto activation of volume on LVM versions that support it.
+		// Must be done after volume create so that zeroing and signature wiping can take place.
+		_, err := d.runVolumeGroupCommand(vgName, "lvchange", "--setactivationskip", "y", volDevPath)
+		if err != nil {
+			return fmt.Errorf("Failed to set activation skip on LVM logical volume %q: %w", volDevPath, err)
+		}
+	}
+
+	d.logger.Debug("Logical volume created", logger.Ctx{"vg_name": vgName, "lv_name": lvFullName, "size": fmt.Sprintf("%db", lvSizeBytes), "fs": vol.ConfigBlockFilesystem()})
+	return nil
+}
+
+// createLogicalVolumeSnapshot creates a snapshot of a logical volume.
+func (d *lvm) createLogicalVolumeSnapshot(vgName string, srcVol Volume, snapVol Volume, readonly bool, makeThinLv bool) (string, error) {
+	srcVolDevPath := d.lvmDevPath(vgName, srcVol.volType, srcVol.contentType, srcVol.name)
+	isRecent, err := d.lvmVersionIsAtLeast(lvmVersion, "2.02.99")
+	if err != nil {
+		return "", fmt.Errorf("Error checking LVM version: %w", err)
+	}
+
+	snapLvName := d.lvmFullVolumeName(snapVol.volType, snapVol.contentType, snapVol.name)
+	logCtx := logger.Ctx{"vg_name": vgName, "lv_name": snapLvName, "src_dev": srcVolDevPath, "thin": makeThinLv}
+	args := []string{"-n", snapLvName, "--addtag", lvmVolumeMarker, "-s", srcVolDevPath}
+
+	if isRecent {
+		args = append(args, "--setactivationskip", "y")
+	}
+
+	// If the source is not a thin volume the size needs to be specified.
+	// Create snapshot at 100% the size of the origin to allow restoring it to the origin volume without
+	// filling up the CoW snapshot volume and causing it to become invalid.
+	if !makeThinLv {
+		args = append(args, "-l", "100%ORIGIN")
+	}
+
+	if readonly {
+		args = append(args, "-pr")
+	} else {
+		args = append(args, "-prw")
+	}
+
+	// A thin snapshot adds the size of its origin to the virtual size of the thin pool.
+	if makeThinLv {
+		srcSizeBytes, err := d.logicalVolumeSize(srcVolDevPath)
+		if err != nil {
+			return "", err
+		}
+
+		err = d.checkThinpoolOvercommit(vgName, d.volumeThinpoolName(srcVol), srcSizeBytes)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	revert := revert.New()
+	defer revert.Fail()
+
+	_, err = d.tryRunVolumeGroupCommand(vgName, "lvcreate", args...)
+	if err != nil {
+		if makeThinLv {
+			return "", fmt.Errorf("Error creating LVM logical volume snapshot %q of %q in thin pool %q of volume group %q: %w", snapLvName, srcVolDevPath, d.volumeThinpoolName(srcVol), vgName, d.thinpoolSpaceError(vgName, d.volumeThinpoolName(srcVol), err))
+		}
+
+		return "", fmt.Errorf("Error creating LVM logical volume snapshot %q of %q in volume group %q: %w", snapLvName, srcVolDevPath, vgName, err)
+	}
+
+	d.logger.Debug("Logical volume snapshot created", logCtx)
+
+	revert.Add(func() {
+		_ = d.removeLogicalVolume(d.lvmDevPath(vgName, snapVol.volType, snapVol.contentType, snapVol.name))
+	})
+
+	targetVolDevPath := d.lvmDevPath(vgName, snapVol.volType, snapVol.contentType, snapVol.name)
+
+	if readonly {
+		err = d.ensureLogicalVolumeReadOnly(vgName, targetVolDevPath)
+		if err != nil {
+			return "", fmt.Errorf("Failed making LVM logical volume snapshot %q in volume group %q read-only: %w", snapLvName, vgName, err)
+		}
+	}
+
+	revert.Success()
+	return targetVolDevPath, nil
+}
+
+// logicalVolumeReadOnly checks whether the specified logical volume has its read-only permission set.
+func (d *lvm) logicalVolumeReadOnly(volDevPath string) (bool, error) {
+	output, err := d.runLVMCommand("lvs", "--noheadings", "-o", "lv_attr", volDevPath)
+	if err != nil {
+		return false, fmt.Errorf("Error getting attributes of LVM logical volume %q: %w", volDevPath, err)
+	}
+
+	return d.parseLogicalVolumeReadOnly(output), nil
+}
+
+// parseLogicalVolumeReadOnly parses the output of "lvs -o lv_attr" and returns whether the permissions attribute
+// (the second character) indicates a read-only volume.
+func (d *lvm) parseLogicalVolumeReadOnly(attr string) bool {
+	attr = strings.TrimSpace(attr)
+
+	return len(attr) > 1 && attr[1] == 'r'
+}
+
+// ensureLogicalVolumeReadOnly verifies that the specified logical volume is read-only and re-applies the
+// read-only permission if it came up writable.
+func (d *lvm) ensureLogicalVolumeReadOnly(vgName string, volDevPath string) error {
+	readonly, err := d.logicalVolumeReadOnly(volDevPath)
+	if err != nil {
+		return err
+	}
+
+	if readonly {
+		return nil
+	}
+
+	d.logger.Warn("Logical volume snapshot is writable, setting it read-only", logger.Ctx{"dev": volDevPath})
+
+	_, err = d.tryRunVolumeGroupCommand(vgName, "lvchange", "-pr", volDevPath)
+	if err != nil {
+		return fmt.Errorf("Error setting LVM logical volume %q read-only: %w", volDevPath, err)
+	}
+
+	readonly, err = d.logicalVolumeReadOnly(volDevPath)
+	if err != nil {
+		return err
+	}
+
+	if !readonly {
+		return fmt.Errorf("LVM logical volume %q is still writable after setting it read-only", volDevPath)
+	}
+
+	return nil
+}
+
+// logicalVolumeOrigin returns the name of the logical volume the specified snapshot volume was taken from.
+// An empty string is returned if the volume is not a snapshot or its origin has been removed.
+func (d *lvm) logicalVolumeOrigin(volDevPath string) (string, error) {
+	output, err := d.runLVMCommand("lvs", "--noheadings", "-o", "origin", volDevPath)
+	if err != nil {
+		return "", fmt.Errorf("Error getting origin of LVM logical volume %q: %w", volDevPath, err)
+	}
+
+	return strings.TrimSpace(output), nil
+}
+
+// checkRestoreSnapshot checks that the snapshot can be restored onto the volume, so that restores that can't work
+// are rejected before the volume is modified. The snapshot must belong to the volume, be on the same pool (and so in
+// the same volume group) and have its logical volumes (including the filesystem one for VMs) present.
+// The origin of the snapshot logical volumes isn't checked as it can legitimately be a logical volume of another
+// volume (when the snapshots were copied) or have been removed (after restoring thin volumes).
+func (d *lvm) checkRestoreSnapshot(vol Volume, snapVol Volume, lvExists func(volDevPath string) (bool, error)) error {
+	parentName, snapshotName, isSnap := api.GetParentAndSnapshotName(snapVol.name)
+	if !isSnap || parentName != vol.name || snapVol.volType != vol.volType {
+		return api.StatusErrorf(http.StatusBadRequest, "Volume %q isn't a snapshot of volume %q", snapVol.name, vol.name)
+	}
+
+	if snapVol.pool != vol.pool {
+		return api.StatusErrorf(http.StatusBadRequest, "Snapshot %q is on pool %q rather than in volume group %q of volume %q", snapVol.name, snapVol.pool, d.config["lvm.vg_name"], vol.name)
+	}
+
+	vols := []Volume{vol}
+	if vol.IsVMBlock() {
+		vols = append(vols, vol.NewVMBlockFilesystemVolume())
+	}
+
+	for _, v := range vols {
+		snapDevPath := d.lvmDevPath(d.config["lvm.vg_name"], v.volType, v.contentType, GetSnapshotVolumeName(v.name, snapshotName))
+
+		exists, err := lvExists(snapDevPath)
+		if err != nil {
+			return err
+		}
+
+		if !exists {
+			return api.StatusErrorf(http.StatusNotFound, "Logical volume %q of snapshot %q not found in volume group %q", d.lvmFullVolumeName(v.volType, v.contentType, GetSnapshotVolumeName(v.name, snapshotName)), snapVol.name, d.config["lvm.vg_name"])
+		}
+	}
+
+	return nil
+}
+
+// canMergeLogicalVolumeSnapshot checks whether the snapshot volume can be merged back onto the volume, which is
+// only possible if the snapshot was taken from the volume's current logical volume.
+func (d *lvm) canMergeLogicalVolumeSnapshot(vol Volume, snapVol Volume) (bool, error) {
+	snapVolDevPath := d.lvmDevPath(d.config["lvm.vg_name"], snapVol.volType, snapVol.contentType, snapVol.name)
+
+	origin, err := d.logicalVolumeOrigin(snapVolDevPath)
+	if err != nil {
+		return false, err
+	}
+
+	return origin == d.lvmFullVolumeName(vol.volType, vol.contentType, vol.name), nil
+}
+
+// mergeLogicalVolumeSnapshot restores a volume from its snapshot by merging the snapshot back onto the volume's
+// logical volume using "lvconvert --merge". As merging consumes the snapshot, it is then re-created from the
+// restored volume. The volume must not be mounted.
+// Returns false if the merge could not be started, in which case the volume and snapshot are left untouched.
+// Returns an ErrSnapshotLost error if the volume was restored but the snapshot could not be re-created.
+func (d *lvm) mergeLogicalVolumeSnapshot(vol Volume, snapVol Volume) (bool, error) {
+	vgName := d.config["lvm.vg_name"]
+	snapVolDevPath := d.lvmDevPath(vgName, snapVol.volType, snapVol.contentType, snapVol.name)
+
+	// The volume needs to be active (but not in use) for the merge to happen straight away rather than being
+	// deferred until its next activation.
+	_, err := d.activateVolume(vol)
+	if err != nil {
+		return false, err
+	}
+
+	// Without the --background flag lvconvert waits for the merge to complete.
+	_, err = d.runVolumeGroupCommand(vgName, "lvconvert", "--merge", snapVolDevPath)
+	if err != nil {
+		// If the snapshot still exists the merge didn't start.
+		snapExists, _ := d.logicalVolumeExists(snapVolDevPath)
+		if snapExists {
+			d.logger.Warn("Failed merging LVM logical volume snapshot", logger.Ctx{"dev": snapVolDevPath, "err": err})
+			return false, nil
+		}
+
+		return true, fmt.Errorf("Error merging LVM logical volume snapshot %q: %w", snapVolDevPath, err)
+	}
+
+	d.logger.Debug("Logical volume snapshot merged", logger.Ctx{"dev": snapVolDevPath})
+
+	// Re-create the snapshot consumed by the merge, its contents being the same as the restored volume.
+	_, err = d.createLogicalVolumeSnapshot(vgName, vol, snapVol, true, d.usesThinpool())
+	if err != nil {
+		return true, ErrSnapshotLost{Err: fmt.Errorf("Error re-creating LVM logical volume snapshot after merge: %w", err)}
+	}
+
+	_, err = d.deactivateVolume(vol)
+	if err != nil {
+		return true, err
+	}
+
+	return true, nil
+}
+
+// removeLogicalVolume removes a logical volume.
+func (d *lvm) removeLogicalVolume(volDevPath string) error {
+	vgName := d.config["lvm.vg_name"]
+
+	_, err := d.tryRunVolumeGroupCommand(vgName, "lvremove", "-f", volDevPath)
+	if err != nil && d.isLVMVolumeInUseError(err) {
+		// The device may be held open by a stale activation, so deactivate it and retry once.
+		d.logger.Debug("Logical volume in use, deactivating before retrying removal", logger.Ctx{"dev": volDevPath})
+
+		_, deactivateErr := d.runVolumeGroupCommand(vgName, "lvchange", "--activate", "n", "--ignoreactivationskip", volDevPath)
+		if deactivateErr == nil {
+			_, err = d.runVolumeGroupCommand(vgName, "lvremove", "-f", volDevPath)
+		}
+
+		if err != nil && d.isLVMVolumeInUseError(err) {
+			return fmt.Errorf("Failed removing LVM logical volume %q from volume group %q as its device is in use, make sure any instance using it is stopped: %w: %w", volDevPath, vgName, ErrInUse, err)
+		}
+	}
+
+	if err != nil {
+		return fmt.Errorf("Failed removing LVM logical volume %q from volume group %q: %w", volDevPath, vgName, err)
+	}
+
+	d.logger.Debug("Logical volume removed", logger.Ctx{"dev": volDevPath})
+
+	return nil
+}
+
+// checkMaxSnapshots returns an error if the parent volume already has as many snapshots as the pool's
+// lvm.max_snapshots setting allows.
+func (d *lvm) checkMaxSnapshots(parentVol Volume, op *operations.Operation) error {
+	if d.config["lvm.max_snapshots"] == "" {
+		return nil
+	}
+
+	maxSnapshots, err := strconv.ParseUint(d.config["lvm.max_snapshots"], 10, 32)
+	if err != nil {
+		return fmt.Errorf("Invalid lvm.max_snapshots value %q: %w", d.config["lvm.max_snapshots"], err)
+	}
+
+	if maxSnapshots == 0 {
+		return nil
+	}
+
+	snapshots, err := d.VolumeSnapshots(parentVol, op)
+	if err != nil {
+		return err
+	}
+
+	count := 0
+	for _, snapName := range snapshots {
+		// Temporary writable snapshots used for mounting aren't counted.
+		if !strings.HasSuffix(snapName, tmpVolSuffix) {
+			count++
+		}
+	}
+
+	if uint64(count) >= maxSnapshots {
+		return api.StatusErrorf(http.StatusBadRequest, "Volume %q already has %d snapshots, the maximum allowed by lvm.max_snapshots is %d", parentVol.name, count, maxSnapshots)
+	}
+
+	return nil
+}
+
+// freezeDuring runs fn with the volume's filesystem frozen if lvm.freeze_on_snapshot is enabled and the volume is
+// a mounted filesystem volume. The filesystem is always unfrozen again, even if fn fails.
+func (d *lvm) freezeDuring(vol Volume, fn func() error) error {
+	mountPath := vol.MountPath()
+	if !shared.IsTrue(d.config["lvm.freeze_on_snapshot"]) || vol.contentType != ContentTypeFS || !filesystem.IsMountPoint(mountPath) {
+		return fn()
+	}
+
+	unfreezeFS, err := d.filesystemFreeze(mountPath)
+	if err != nil {
+		return err
+	}
+
+	err = fn()
+	unfreezeErr := unfreezeFS()
+	if err != nil {
+		if unfreezeErr != nil {
+			d.logger.Error("Failed unfreezing volume filesystem", logger.Ctx{"volName": vol.name, "err": unfreezeErr})
+		}
+
+		return err
+	}
+
+	return unfreezeErr
+}
+
+// volumeFilesystem returns the filesystem type to mount the volume's device with. The type is detected from the
+// device so that mounting doesn't depend on the block.filesystem setting matching the existing filesystem (for
+// example after the pool's default was changed). The configured type is used if it can't be detected, unless the
+// volume requires probing.
+func (d *lvm) volumeFilesystem(vol Volume, volDevPath string) (string, error) {
+	fsType, err := fsProbe(volDevPath)
+	if vol.mountFilesystemProbe {
+		if err != nil {
+			return "", fmt.Errorf("Failed probing filesystem: %w", err)
+		}
+
+		return fsType, nil
+	}
+
+	configFsType := vol.ConfigBlockFilesystem()
+	if err != nil || fsType == "" {
+		d.logger.Debug("Failed detecting filesystem, using configured filesystem", logger.Ctx{"dev": volDevPath, "fs": configFsType, "err": err})
+		return configFsType, nil
+	}
+
+	if fsType != configFsType {
+		d.logger.Warn("Detected filesystem differs from configured filesystem", logger.Ctx{"dev": volDevPath, "fs": fsType, "configuredFs": configFsType})
+	}
+
+	return fsType, nil
+}
+
+// lvmCheckReport contains the problems found when checking the state of an LVM storage pool.
+type lvmCheckReport struct {
+	VolumeGroupMissing bool     // Whether the volume group is missing.
+	ThinpoolMissing    bool     // Whether the thin pool is missing or isn't a thin pool.
+	MissingVolumes     []string // Mount paths of volumes that don't have a logical volume.
+}
+
+// Problems returns a description of each problem in the report.
+func (r *lvmCheckReport) Problems() []string {
+	problems := []string{}
+
+	if r.VolumeGroupMissing {
+		problems = append(problems, "Volume group is missing")
 	}
 
-	lvFullName := d.lvmFullVolumeName(vol.volType, vol.contentType, vol.name)
+	if r.ThinpoolMissing {
+		problems = append(problems, "Thin pool is missing or isn't a thin pool")
+	}
 
-	args := []string{
-		"--name", lvFullName,
-		"--yes",
-		"--wipesignatures", "y",
+	for _, mountPath := range r.MissingVolumes {
+		problems = append(problems, fmt.Sprintf("Logical volume for %q is missing", mountPath))
 	}
 
-	if makeThinLv {
-		targetVg := fmt.Sprintf("%s/%s", vgName, thinPoolName)
-		args = append(args,
-			"--thin",
-			"--virtualsize", fmt.Sprintf("%db", lvSizeBytes),
-			targetVg,
-		)
-	} else {
-		args = append(args,
-			"--size", fmt.Sprintf("%db", lvSizeBytes),
-			vgName,
-		)
+	return problems
+}
 
-		// As we are creating a normal logical volume we can apply stripes settings if specified.
-		stripes := vol.ExpandedConfig("lvm.stripes")
-		if stripes != "" {
-			args = append(args, "--stripes", stripes)
+// Check validates the state of the storage pool: that the volume group and thin pool exist and that every volume
+// mount path in the storage pool has a logical volume. It never modifies anything.
+func (d *lvm) Check() (*lvmCheckReport, error) {
+	report := &lvmCheckReport{}
+	vgName := d.config["lvm.vg_name"]
 
-			stripeSize := vol.ExpandedConfig("lvm.stripes.size")
-			if stripeSize != "" {
-				stripSizeBytes, err := d.roundedSizeBytesString(stripeSize)
-				if err != nil {
-					return fmt.Errorf("Invalid volume stripe size %q: %w", stripeSize, err)
-				}
+	vgExists, _, err := d.volumeGroupExists(vgName)
+	if err != nil {
+		return nil, err
+	}
 
-				args = append(args, "--stripesize", fmt.Sprintf("%db", stripSizeBytes))
-			}
+	if !vgExists {
+		report.VolumeGroupMissing = true
+		return report, nil
+	}
+
+	if d.usesThinpool() {
+		thinpoolExists, err := d.thinpoolExists(vgName, d.thinpoolName())
+		if (err != nil || !thinpoolExists) && shared.IsFalseOrEmpty(d.config["lvm.thinpool_reclaim"]) {
+			report.ThinpoolMissing = true
 		}
 	}
 
-	_, err = shared.TryRunCommand("lvcreate", args...)
+	output, err := d.runLVMCommand("lvs", "--noheadings", "-o", "lv_name", vgName)
 	if err != nil {
-		return fmt.Errorf("Error creating LVM logical volume %q: %w", lvFullName, err)
+		return nil, fmt.Errorf("Failed listing logical volumes in volume group %q: %w", vgName, err)
 	}
 
-	volDevPath := d.lvmDevPath(vgName, vol.volType, vol.contentType, vol.name)
-
-	if vol.contentType == ContentTypeFS {
-		_, err = makeFSType(volDevPath, vol.ConfigBlockFilesystem(), nil)
+	volNames := map[VolumeType][]string{}
+	for _, volType := range []VolumeType{VolumeTypeContainer, VolumeTypeVM, VolumeTypeCustom, VolumeTypeImage} {
+		entries, err := os.ReadDir(GetVolumeMountPath(d.name, volType, ""))
 		if err != nil {
-			return fmt.Errorf("Error making filesystem on LVM logical volume: %w", err)
+			if errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+
+			return nil, err
 		}
-	}
 
-	isRecent, err := d.lvmVersionIsAtLeast(lvmVersion, "2.02.99")
-	if err != nil {
-		return fmt.Errorf("Error checking LVM version: %w", err)
+		for _, entry := range entries {
+			if entry.IsDir() {
+				volNames[volType] = append(volNames[volType], entry.Name())
+			}
+		}
 	}
 
-	if isRecent {
-		// Disable auto activation of volume on LVM versions that support it.
-		// Must be done after volume create so that zeroing and signature wiping can take place.
-		_, err := shared.RunCommand("lvchange", "--setactivationskip", "y", volDevPath)
-		if err != nil {
-			return fmt.Errorf("Failed to set activation skip on LVM logical volume %q: %w", volDevPath, err)
+	report.MissingVolumes = d.findMissingVolumes(strings.Fields(output), volNames)
+
+	return report, nil
+}
+
+// findMissingVolumes returns the mount paths of the volumes (grouped by volume type) that don't have a logical
+// volume in the supplied list of logical volume names. The content type of a volume isn't known from its mount
+// path, so any content type is accepted.
+func (d *lvm) findMissingVolumes(lvNames []string, volNames map[VolumeType][]string) []string {
+	missing := []string{}
+
+	for _, volType := range []VolumeType{VolumeTypeContainer, VolumeTypeVM, VolumeTypeCustom, VolumeTypeImage} {
+		for _, volName := range volNames[volType] {
+			found := false
+			for _, contentType := range []ContentType{ContentTypeFS, ContentTypeBlock, ContentTypeISO} {
+				if shared.ValueInSlice(d.lvmFullVolumeName(volType, contentType, volName), lvNames) {
+					found = true
+					break
+				}
+			}
+
+			if !found {
+				missing = append(missing, GetVolumeMountPath(d.name, volType, volName))
+			}
 		}
 	}
 
-	d.logger.Debug("Logical volume created", logger.Ctx{"vg_name": vgName, "lv_name": lvFullName, "size": fmt.Sprintf("%db", lvSizeBytes), "fs": vol.ConfigBlockFilesystem()})
-	return nil
+	return missing
 }
 
-// createLogicalVolumeSnapshot creates a snapshot of a logical volume.
-func (d *lvm) createLogicalVolumeSnapshot(vgName string, srcVol Volume, snapVol Volume, readonly bool, makeThinLv bool) (string, error) {
-	srcVolDevPath := d.lvmDevPath(vgName, srcVol.volType, srcVol.contentType, srcVol.name)
-	isRecent, err := d.lvmVersionIsAtLeast(lvmVersion, "2.02.99")
+// wipeLogicalVolume wipes the contents of a logical volume before it is removed so that its data isn't left
+// behind in the volume group. Thin volumes are discarded if their thin pool passes the discards down, which returns
+// their blocks to the thin pool, whereas other volumes are zeroed.
+func (d *lvm) wipeLogicalVolume(vol Volume) error {
+	_, err := d.activateVolume(vol)
 	if err != nil {
-		return "", fmt.Errorf("Error checking LVM version: %w", err)
+		return err
 	}
 
-	snapLvName := d.lvmFullVolumeName(snapVol.volType, snapVol.contentType, snapVol.name)
-	logCtx := logger.Ctx{"vg_name": vgName, "lv_name": snapLvName, "src_dev": srcVolDevPath, "thin": makeThinLv}
-	args := []string{"-n", snapLvName, "-s", srcVolDevPath}
+	volDevPath := d.lvmDevPath(d.config["lvm.vg_name"], vol.volType, vol.contentType, vol.name)
 
-	if isRecent {
-		args = append(args, "--setactivationskip", "y")
+	poolName, err := d.logicalVolumeThinpool(volDevPath)
+	if err != nil {
+		return err
 	}
 
-	// If the source is not a thin volume the size needs to be specified.
-	// Create snapshot at 100% the size of the origin to allow restoring it to the origin volume without
-	// filling up the CoW snapshot volume and causing it to become invalid.
-	if !makeThinLv {
-		args = append(args, "-l", "100%ORIGIN")
-	}
+	// Discarding only wipes the blocks of a thin volume if the thin pool passes the discards on, so that the
+	// blocks are unmapped and read back as zeroes. Otherwise the volume is overwritten with zeroes.
+	zeroOut := true
+	if poolName != "" {
+		discards, err := d.thinpoolDiscards(d.config["lvm.vg_name"], poolName)
+		if err != nil {
+			return err
+		}
 
-	if readonly {
-		args = append(args, "-pr")
-	} else {
-		args = append(args, "-prw")
+		zeroOut = discards != "passdown" && discards != "nopassdown"
 	}
 
-	revert := revert.New()
-	defer revert.Fail()
+	args := []string{volDevPath}
+	if zeroOut {
+		args = append([]string{"--zeroout"}, args...)
+	}
 
-	_, err = shared.TryRunCommand("lvcreate", args...)
+	start := time.Now()
+	_, err = d.runLVMCommand("blkdiscard", args...)
 	if err != nil {
-		return "", err
+		return fmt.Errorf("Failed wiping logical volume %q: %w", volDevPath, err)
 	}
 
-	d.logger.Debug("Logical volume snapshot created", logCtx)
-
-	revert.Add(func() {
-		_ = d.removeLogicalVolume(d.lvmDevPath(vgName, snapVol.volType, snapVol.contentType, snapVol.name))
-	})
-
-	targetVolDevPath := d.lvmDevPath(vgName, snapVol.volType, snapVol.contentType, snapVol.name)
+	d.logger.Info("Wiped logical volume", logger.Ctx{"dev": volDevPath, "duration": time.Since(start)})
 
-	revert.Success()
-	return targetVolDevPath, nil
+	return nil
 }
 
-// removeLogicalVolume removes a logical volume.
-func (d *lvm) removeLogicalVolume(volDevPath string) error {
-	_, err := shared.TryRunCommand("lvremove", "-f", volDevPath)
+// removeTemporarySnapshotVolume removes the temporary writable snapshot volume used to mount a snapshot volume
+// (if it exists).
+func (d *lvm) removeTemporarySnapshotVolume(snapVol Volume) error {
+	tmpVolName := fmt.Sprintf("%s%s", snapVol.name, tmpVolSuffix)
+	tmpVolDevPath := d.lvmDevPath(d.config["lvm.vg_name"], snapVol.volType, snapVol.contentType, tmpVolName)
+	exists, err := d.logicalVolumeExists(tmpVolDevPath)
 	if err != nil {
-		return err
+		return fmt.Errorf("Failed to check existence of temporary LVM snapshot volume %q: %w", tmpVolDevPath, err)
 	}
 
-	d.logger.Debug("Logical volume removed", logger.Ctx{"dev": volDevPath})
+	if exists {
+		err = d.removeLogicalVolume(tmpVolDevPath)
+		if err != nil {
+			return fmt.Errorf("Failed to remove temporary LVM snapshot volume %q: %w", tmpVolDevPath, err)
+		}
+	}
 
 	return nil
 }
 
 // renameLogicalVolume renames a logical volume.
 func (d *lvm) renameLogicalVolume(volDevPath string, newVolDevPath string) error {
-	_, err := shared.TryRunCommand("lvrename", volDevPath, newVolDevPath)
+	vgName := d.config["lvm.vg_name"]
+
+	_, err := d.tryRunVolumeGroupCommand(vgName, "lvrename", volDevPath, newVolDevPath)
 	if err != nil {
-		return err
+		return fmt.Errorf("Failed renaming LVM logical volume %q to %q in volume group %q: %w", volDevPath, newVolDevPath, vgName, err)
 	}
 
 	d.logger.Debug("Logical volume renamed", logger.Ctx{"dev": volDevPath, "new_dev": newVolDevPath})
@@ -483,25 +2064,30 @@ func (d *lvm) lvmFullVolumeName(volType VolumeType, contentType ContentType, vol
 		contentTypeSuffix = lvmISOVolSuffix
 	}
 
-	// Escape the volume name to a name suitable for using as a logical volume.
-	lvName := strings.Replace(strings.Replace(volName, "-", lvmEscapedHyphen, -1), shared.SnapshotDelimiter, lvmSnapshotSeparator, -1)
-
-	return fmt.Sprintf("%s_%s%s", volType, lvName, contentTypeSuffix)
+	return fmt.Sprintf("%s_%s%s", volType, volNameToLVName(volName), contentTypeSuffix)
 }
 
 // lvmDevPath returns the path to the LVM volume device. Empty string is returned if invalid volType supplied.
+// The canonical /dev/mapper path is used rather than /dev/<vg>/<lv> as the latter relies on symlinks created by
+// udev. The LVM tools accept either form.
 func (d *lvm) lvmDevPath(vgName string, volType VolumeType, contentType ContentType, volName string) string {
 	fullVolName := d.lvmFullVolumeName(volType, contentType, volName)
 	if fullVolName == "" {
 		return "" // Invalid volType supplied.
 	}
 
-	return fmt.Sprintf("/dev/%s/%s", vgName, fullVolName)
+	return filepath.Join("/dev/mapper", lvmMapperName(vgName, fullVolName))
+}
+
+// lvmMapperName returns the device-mapper name of a logical volume. This is the volume group and logical volume
+// names joined by a hyphen, with any hyphens in the names themselves doubled.
+func lvmMapperName(vgName string, lvName string) string {
+	return fmt.Sprintf("%s-%s", strings.ReplaceAll(vgName, "-", "--"), strings.ReplaceAll(lvName, "-", "--"))
 }
 
 // resizeLogicalVolume resizes an LVM logical volume. This function does not resize any filesystem inside the LV.
 func (d *lvm) resizeLogicalVolume(lvPath string, sizeBytes int64) error {
-	_, err := shared.TryRunCommand("lvresize", "-L", fmt.Sprintf("%db", sizeBytes), "-f", lvPath)
+	_, err := d.tryRunVolumeGroupCommand(d.config["lvm.vg_name"], "lvresize", "-L", fmt.Sprintf("%db", sizeBytes), "-f", lvPath)
 	if err != nil {
 		return err
 	}
@@ -510,6 +2096,22 @@ func (d *lvm) resizeLogicalVolume(lvPath string, sizeBytes int64) error {
 	return nil
 }
 
+// sameThinpool returns whether the source volume is in the thin pool that the volume would be created in, so that
+// it can be copied using a thin snapshot. Only image volumes can be in a different thin pool, when
+// lvm.image_thinpool_name is set.
+func (d *lvm) sameThinpool(vol Volume, srcVol Volume) (bool, error) {
+	if !d.usesThinpool() || srcVol.volType != VolumeTypeImage || srcVol.IsSnapshot() || d.config["lvm.image_thinpool_name"] == "" {
+		return true, nil
+	}
+
+	srcThinpool, err := d.logicalVolumeThinpool(d.lvmDevPath(d.config["lvm.vg_name"], srcVol.volType, srcVol.contentType, srcVol.name))
+	if err != nil {
+		return false, err
+	}
+
+	return srcThinpool == d.volumeThinpoolName(vol), nil
+}
+
 // copyThinpoolVolume makes an optimised copy of a thinpool volume by using thinpool snapshots.
 func (d *lvm) copyThinpoolVolume(vol, srcVol Volume, srcSnapshots []string, refresh bool) error {
 	revert := revert.New()
@@ -632,6 +2234,12 @@ func (d *lvm) copyThinpoolVolume(vol, srcVol Volume, srcSnapshots []string, refr
 			}
 		}
 
+		// Label the filesystem with the name of the new volume rather than the one it was copied from.
+		err = d.setVolumeFilesystemLabel(vol)
+		if err != nil {
+			d.logger.Warn("Failed setting filesystem label", logger.Ctx{"dev": volDevPath, "err": err})
+		}
+
 		// Mount the volume and ensure the permissions are set correctly inside the mounted volume.
 		err = vol.MountTask(func(_ string, _ *operations.Operation) error {
 			return vol.EnsureMountPath()
@@ -662,7 +2270,7 @@ func (d *lvm) copyThinpoolVolume(vol, srcVol Volume, srcSnapshots []string, refr
 
 // logicalVolumeSize gets the size in bytes of a logical volume.
 func (d *lvm) logicalVolumeSize(volDevPath string) (int64, error) {
-	output, err := shared.RunCommand("lvs", "--noheadings", "--nosuffix", "--units", "b", "-o", "lv_size", volDevPath)
+	output, err := d.runLVMCommand("lvs", "--noheadings", "--nosuffix", "--units", "b", "-o", "lv_size", volDevPath)
 	if err != nil {
 		if d.isLVMNotFoundExitError(err) {
 			return -1, api.StatusErrorf(http.StatusNotFound, "LVM volume not found")
@@ -685,7 +2293,7 @@ func (d *lvm) thinPoolVolumeUsage(volDevPath string) (uint64, uint64, error) {
 		"-o", "lv_size,data_percent,metadata_percent",
 	}
 
-	out, err := shared.RunCommand("lvs", args...)
+	out, err := d.runLVMCommand("lvs", args...)
 	if err != nil {
 		return 0, 0, err
 	}
@@ -733,33 +2341,28 @@ func (d *lvm) thinPoolVolumeUsage(volDevPath string) (uint64, uint64, error) {
 // been used for naming logical volumes meaning that additional context of the parent is required to accurately
 // recognise snapshot volumes that belong to the parent.
 func (d *lvm) parseLogicalVolumeSnapshot(parent Volume, lvmVolName string) string {
-	fullVolName := d.lvmFullVolumeName(parent.volType, parent.contentType, parent.name)
-
-	// If block volume, remove the block suffix ready for comparison with LV list.
+	// If block volume, remove the block suffix ready for comparison with the parent's name.
 	if parent.IsVMBlock() || (parent.volType == VolumeTypeCustom && parent.contentType == ContentTypeBlock) {
 		if !strings.HasSuffix(lvmVolName, lvmBlockVolSuffix) {
 			return ""
 		}
 
-		// Remove the block suffix so that snapshot names can be compared and extracted without the suffix.
-		fullVolName = strings.TrimSuffix(fullVolName, lvmBlockVolSuffix)
 		lvmVolName = strings.TrimSuffix(lvmVolName, lvmBlockVolSuffix)
 	}
 
-	// Prefix we would expect for a snapshot of the parent volume.
-	snapPrefix := fmt.Sprintf("%s%s", fullVolName, lvmSnapshotSeparator)
-
-	// Prefix used when escaping "-" in volume names. Doesn't indicate a snapshot of parent.
-	badPrefix := fmt.Sprintf("%s%s", fullVolName, lvmEscapedHyphen)
+	prefix := fmt.Sprintf("%s_", parent.volType)
+	if !strings.HasPrefix(lvmVolName, prefix) {
+		return ""
+	}
 
-	// Check the volume matches the snapshot prefix, but doesn't match the prefix that indicates a similarly
-	// named volume that just has escaped "-" characters in it.
-	if strings.HasPrefix(lvmVolName, snapPrefix) && !strings.HasPrefix(lvmVolName, badPrefix) {
-		// Remove volume name prefix (including snapshot delimiter) and unescape snapshot name.
-		return strings.Replace(strings.TrimPrefix(lvmVolName, snapPrefix), lvmEscapedHyphen, "-", -1)
+	// Decoding the whole name rather than matching prefixes of the encoded name ensures that a similarly named
+	// volume with escaped "-" characters in it isn't mistaken for a snapshot.
+	parentName, snapshotName, isSnap := api.GetParentAndSnapshotName(lvNameToVolName(strings.TrimPrefix(lvmVolName, prefix)))
+	if !isSnap || parentName != parent.name {
+		return ""
 	}
 
-	return ""
+	return snapshotName
 }
 
 // activateVolume activates an LVM logical volume if not already present. Returns true if activated, false if not.
@@ -775,7 +2378,7 @@ func (d *lvm) activateVolume(vol Volume) (bool, error) {
 	}
 
 	if !shared.PathExists(volDevPath) {
-		_, err := shared.RunCommand("lvchange", "--activate", "y", "--ignoreactivationskip", volDevPath)
+		_, err := d.runVolumeGroupCommand(d.config["lvm.vg_name"], "lvchange", "--activate", "y", "--ignoreactivationskip", volDevPath)
 		if err != nil {
 			return false, fmt.Errorf("Failed to activate LVM logical volume %q: %w", volDevPath, err)
 		}
@@ -788,6 +2391,24 @@ func (d *lvm) activateVolume(vol Volume) (bool, error) {
 	return false, nil
 }
 
+// setVolumeFilesystemLabel sets the label of an unmounted filesystem volume to one derived from its name.
+// The logical volume is activated for this if needed and deactivated again afterwards.
+func (d *lvm) setVolumeFilesystemLabel(vol Volume) error {
+	activated, err := d.activateVolume(vol)
+	if err != nil {
+		return err
+	}
+
+	if activated {
+		defer func() { _, _ = d.deactivateVolume(vol) }()
+	}
+
+	fsType := vol.ConfigBlockFilesystem()
+	volDevPath := d.lvmDevPath(d.config["lvm.vg_name"], vol.volType, vol.contentType, vol.name)
+
+	return setFilesystemLabel(fsType, volDevPath, filesystemLabel(fsType, vol.name))
+}
+
 // deactivateVolume deactivates an LVM logical volume if present. Returns true if deactivated, false if not.
 func (d *lvm) deactivateVolume(vol Volume) (bool, error) {
 	var volDevPath string
@@ -811,17 +2432,7 @@ func (d *lvm) deactivateVolume(vol Volume) (bool, error) {
 
 	if shared.PathExists(volDevPath) {
 		// Keep trying to deactivate a few times in case the device is still being flushed.
-		var err error
-		for i := 0; i < 20; i++ {
-			_, err = shared.RunCommand("lvchange", "--activate", "n", "--ignoreactivationskip", volDevPath)
-			if err == nil {
-				break
-			}
-
-			logger.Debug("Failed to deactivate LVM logical volume", logger.Ctx{"path": volDevPath, "attempt": i, "err": err})
-			time.Sleep(500 * time.Millisecond)
-		}
-
+		_, err := d.tryRunVolumeGroupCommand(d.config["lvm.vg_name"], "lvchange", "--activate", "n", "--ignoreactivationskip", volDevPath)
 		if err != nil {
 			return false, fmt.Errorf("Failed to deactivate LVM logical volume %q: %w", volDevPath, err)
 		}
@@ -832,3 +2443,230 @@ func (d *lvm) deactivateVolume(vol Volume) (bool, error) {
 
 	return false, nil
 }
+
+// btrfsSnapshotPath returns the path of the btrfs subvolume of a snapshot inside its mounted parent volume.
+func btrfsSnapshotPath(parentMountPath string, snapVol Volume) string {
+	_, snapshotName, _ := api.GetParentAndSnapshotName(snapVol.name)
+
+	return filepath.Join(parentMountPath, nestedSnapshotsDir, snapshotName)
+}
+
+// canUseBtrfsSnapshots returns true if snapshots of the volume can be btrfs subvolume snapshots stored inside it.
+// This is only the case for container filesystem volumes formatted as btrfs.
+func canUseBtrfsSnapshots(vol Volume) bool {
+	return vol.volType == VolumeTypeContainer && vol.contentType == ContentTypeFS && vol.ConfigBlockFilesystem() == "btrfs"
+}
+
+// usesBtrfsSnapshot returns true if the snapshot is a btrfs subvolume snapshot inside its parent volume rather
+// than an LVM snapshot. When creating is true it returns whether a new snapshot should be a btrfs snapshot.
+// Snapshots that have a logical volume are always LVM snapshots so that they keep working when the
+// lvm.btrfs_snapshots setting is changed.
+func (d *lvm) usesBtrfsSnapshot(snapVol Volume, creating bool) (bool, error) {
+	if !canUseBtrfsSnapshots(snapVol) {
+		return false, nil
+	}
+
+	if creating {
+		return shared.IsTrue(d.config["lvm.btrfs_snapshots"]), nil
+	}
+
+	lvExists, err := d.logicalVolumeExists(d.lvmDevPath(d.config["lvm.vg_name"], snapVol.volType, snapVol.contentType, snapVol.name))
+	if err != nil {
+		return false, err
+	}
+
+	return !lvExists, nil
+}
+
+// snapshotParentVolume returns the parent volume of a snapshot.
+func (d *lvm) snapshotParentVolume(snapVol Volume) Volume {
+	parentName, _, _ := api.GetParentAndSnapshotName(snapVol.name)
+
+	return NewVolume(d, d.name, snapVol.volType, snapVol.contentType, parentName, snapVol.config, snapVol.poolConfig)
+}
+
+// createBtrfsSnapshot creates a read-only btrfs subvolume snapshot of the parent volume's filesystem inside it.
+func (d *lvm) createBtrfsSnapshot(snapVol Volume, op *operations.Operation) error {
+	parentVol := d.snapshotParentVolume(snapVol)
+
+	return parentVol.MountTask(func(mountPath string, op *operations.Operation) error {
+		err := os.MkdirAll(filepath.Join(mountPath, nestedSnapshotsDir), 0700)
+		if err != nil {
+			return fmt.Errorf("Failed creating snapshots directory: %w", err)
+		}
+
+		snapPath := btrfsSnapshotPath(mountPath, snapVol)
+		_, err = shared.RunCommand("btrfs", "subvolume", "snapshot", "-r", mountPath, snapPath)
+		if err != nil {
+			return fmt.Errorf("Failed creating btrfs snapshot %q: %w", snapPath, err)
+		}
+
+		d.logger.Debug("Created btrfs snapshot", logger.Ctx{"volName": snapVol.name, "path": snapPath})
+
+		return nil
+	}, op)
+}
+
+// deleteBtrfsSnapshot removes the btrfs subvolume of a snapshot from its parent volume, if it exists.
+func (d *lvm) deleteBtrfsSnapshot(snapVol Volume, op *operations.Operation) error {
+	parentVol := d.snapshotParentVolume(snapVol)
+
+	return parentVol.MountTask(func(mountPath string, op *operations.Operation) error {
+		snapPath := btrfsSnapshotPath(mountPath, snapVol)
+		if !shared.PathExists(snapPath) {
+			return nil
+		}
+
+		_, err := shared.RunCommand("btrfs", "subvolume", "delete", snapPath)
+		if err != nil {
+			return fmt.Errorf("Failed deleting btrfs snapshot %q: %w", snapPath, err)
+		}
+
+		d.logger.Debug("Deleted btrfs snapshot", logger.Ctx{"volName": snapVol.name, "path": snapPath})
+
+		return nil
+	}, op)
+}
+
+// renameBtrfsSnapshot renames the btrfs subvolume of a snapshot inside its parent volume.
+func (d *lvm) renameBtrfsSnapshot(snapVol Volume, newSnapVol Volume, op *operations.Operation) error {
+	parentVol := d.snapshotParentVolume(snapVol)
+
+	return parentVol.MountTask(func(mountPath string, op *operations.Operation) error {
+		oldPath := btrfsSnapshotPath(mountPath, snapVol)
+		newPath := btrfsSnapshotPath(mountPath, newSnapVol)
+
+		err := os.Rename(oldPath, newPath)
+		if err != nil {
+			return fmt.Errorf("Failed renaming btrfs snapshot from %q to %q: %w", oldPath, newPath, err)
+		}
+
+		return nil
+	}, op)
+}
+
+// mountBtrfsSnapshot mounts the parent volume of a btrfs snapshot and bind mounts the snapshot's subvolume
+// read-only onto the snapshot's mount path.
+func (d *lvm) mountBtrfsSnapshot(snapVol Volume, op *operations.Operation) error {
+	parentVol := d.snapshotParentVolume(snapVol)
+
+	err := d.MountVolume(parentVol, op)
+	if err != nil {
+		return err
+	}
+
+	revert := revert.New()
+	defer revert.Fail()
+
+	revert.Add(func() { _, _ = d.UnmountVolume(parentVol, false, op) })
+
+	snapPath := btrfsSnapshotPath(parentVol.MountPath(), snapVol)
+	if !shared.PathExists(snapPath) {
+		return api.StatusErrorf(http.StatusNotFound, "Btrfs snapshot %q not found", snapPath)
+	}
+
+	err = snapVol.EnsureMountPath()
+	if err != nil {
+		return err
+	}
+
+	_, err = mountReadOnly(snapPath, snapVol.MountPath())
+	if err != nil {
+		return fmt.Errorf("Failed to mount btrfs snapshot %q: %w", snapPath, err)
+	}
+
+	d.logger.Debug("Mounted btrfs snapshot", logger.Ctx{"volName": snapVol.name, "path": snapVol.MountPath()})
+
+	revert.Success()
+	return nil
+}
+
+// unmountBtrfsSnapshot removes the bind mount of a btrfs snapshot and unmounts its parent volume.
+func (d *lvm) unmountBtrfsSnapshot(snapVol Volume, op *operations.Operation) error {
+	lazy, err := d.mountRetryPolicy().unmountOrDetach(snapVol.MountPath())
+	if err != nil {
+		return fmt.Errorf("Failed to unmount btrfs snapshot: %w", err)
+	}
+
+	d.logger.Debug("Unmounted btrfs snapshot", logger.Ctx{"volName": snapVol.name, "path": snapVol.MountPath(), "lazy": lazy})
+
+	_, err = d.UnmountVolume(d.snapshotParentVolume(snapVol), false, op)
+	if err != nil && !errors.Is(err, ErrInUse) {
+		return err
+	}
+
+	return nil
+}
+
+// btrfsSnapshots returns the names of the btrfs snapshots stored inside the volume.
+func (d *lvm) btrfsSnapshots(vol Volume, op *operations.Operation) ([]string, error) {
+	if !canUseBtrfsSnapshots(vol) {
+		return nil, nil
+	}
+
+	lvExists, err := d.logicalVolumeExists(d.lvmDevPath(d.config["lvm.vg_name"], vol.volType, vol.contentType, vol.name))
+	if err != nil || !lvExists {
+		return nil, err
+	}
+
+	var snapshots []string
+
+	err = vol.MountTask(func(mountPath string, op *operations.Operation) error {
+		entries, err := os.ReadDir(filepath.Join(mountPath, nestedSnapshotsDir))
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+
+			return err
+		}
+
+		for _, entry := range entries {
+			if entry.IsDir() {
+				snapshots = append(snapshots, entry.Name())
+			}
+		}
+
+		return nil
+	}, op)
+	if err != nil {
+		return nil, fmt.Errorf("Failed listing btrfs snapshots of volume %q: %w", vol.name, err)
+	}
+
+	return snapshots, nil
+}
+
+// btrfsSnapshotsExcludePaths returns the paths that a generic transfer of a volume with the given btrfs snapshots
+// must skip. The btrfs snapshots are transferred as separate snapshot volumes, so the directory holding them is
+// excluded when the volume has any.
+func btrfsSnapshotsExcludePaths(btrfsSnapshots []string) []string {
+	if len(btrfsSnapshots) == 0 {
+		return nil
+	}
+
+	return []string{nestedSnapshotsDir}
+}
+
+// restoreBtrfsSnapshot restores a volume from one of its btrfs snapshots by copying the snapshot's content over
+// the volume's, leaving the other snapshots stored in the volume untouched.
+func (d *lvm) restoreBtrfsSnapshot(vol Volume, snapVol Volume, op *operations.Operation) error {
+	parentName, _, isSnap := api.GetParentAndSnapshotName(snapVol.name)
+	if !isSnap || parentName != vol.name || snapVol.volType != vol.volType {
+		return api.StatusErrorf(http.StatusBadRequest, "Volume %q isn't a snapshot of volume %q", snapVol.name, vol.name)
+	}
+
+	err := vol.MountTask(func(mountPath string, op *operations.Operation) error {
+		return snapVol.MountTask(func(srcMountPath string, op *operations.Operation) error {
+			bwlimit := d.config["rsync.bwlimit"]
+			d.Logger().Debug("Copying btrfs snapshot", logger.Ctx{"sourcePath": srcMountPath, "targetPath": mountPath, "bwlimit": bwlimit})
+			_, err := rsync.LocalCopy(srcMountPath, mountPath, bwlimit, true, "--exclude", "/"+nestedSnapshotsDir)
+
+			return err
+		}, op)
+	}, op)
+	if err != nil {
+		return fmt.Errorf("Error restoring btrfs snapshot: %w", err)
+	}
+
+	return nil
+}
@@ -1,7 +1,21 @@
 package drivers
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/canonical/lxd/shared"
+	"github.com/canonical/lxd/shared/logger"
 )
 
 func Example_lvm_parseLogicalVolumeName() {
@@ -66,3 +80,824 @@ func Example_lvm_parseLogicalVolumeName() {
 	// custom_proj_testvol--with--hyphens.block: Unrecognised
 	// custom_proj_testvol--with--hyphens.block-snap1--with--hyphens.block: snap1-with-hyphens.block
 }
+
+func Example_lvm_thinpoolFull() {
+	d := &lvm{}
+	d.name = "pool"
+
+	// Mocked output of "lvs --noheadings -o data_percent vg/LXDThinPool".
+	for _, output := range []string{"  42.17\n", "  100.00\n", "\n"} {
+		dataPerc, err := d.parseThinpoolUsage(output)
+		if err != nil {
+			fmt.Printf("%q: %v\n", output, err)
+			continue
+		}
+
+		if dataPerc >= 100 {
+			err = d.thinpoolFullError("vg", "LXDThinPool", dataPerc)
+			fmt.Printf("%q: %v (full: %t)\n", output, err, errors.Is(err, ErrThinPoolFull))
+		} else {
+			fmt.Printf("%q: %.2f%%\n", output, dataPerc)
+		}
+	}
+
+	// Mocked lvcreate failures.
+	for _, stderr := range []string{
+		"  Cannot create new thin volume, free space in thin pool vg/LXDThinPool reached threshold.\n",
+		"  Cannot create new thin volume, free metadata space in thin pool vg/LXDThinPool reached threshold.\n",
+		"  Volume group \"vg\" not found\n",
+	} {
+		err := fmt.Errorf("Failed creating volume: %w", shared.NewRunError("lvcreate", nil, errors.New("exit status 5"), nil, bytes.NewBufferString(stderr)))
+		fmt.Printf("data full: %t, metadata full: %t\n", d.isLVMThinpoolFullError(err), d.isLVMThinpoolMetadataFullError(err))
+	}
+
+	err := d.thinpoolMetadataFullError("vg", "LXDThinPool", 100)
+	fmt.Printf("%v (metadata full: %t)\n", err, errors.Is(err, ErrThinPoolMetadataFull))
+
+	// Output: "  42.17\n": 42.17%
+	// "  100.00\n": LVM thin pool "LXDThinPool" in volume group "vg" has run out of data space (100.00% used): Thin pool is full (full: true)
+	// "\n": No usage reported for LVM thin pool
+	// data full: true, metadata full: false
+	// data full: false, metadata full: true
+	// data full: false, metadata full: false
+	// LVM thin pool "LXDThinPool" in volume group "vg" has run out of metadata space (100.00% used): Thin pool metadata is full (metadata full: true)
+}
+
+func Example_lvm_parseLogicalVolumeTags() {
+	d := &lvm{}
+	d.name = "pool"
+
+	// Mocked output lines of "lvs --noheadings -o lv_name,lv_tags vg".
+	for _, line := range []string{
+		"  containers_c1           lxd_volume\n",
+		"  LXDThinPool             lxd_thinpool\n",
+		"  other                   backup,lxd_volume\n",
+		"  unrelated\n",
+		"\n",
+	} {
+		name, tags := d.parseLogicalVolumeTags(line)
+		fmt.Printf("%q: %v (managed: %t)\n", name, tags, shared.ValueInSlice(lvmVolumeMarker, tags))
+	}
+
+	// Output: "containers_c1": [lxd_volume] (managed: true)
+	// "LXDThinPool": [lxd_thinpool] (managed: false)
+	// "other": [backup lxd_volume] (managed: true)
+	// "unrelated": [] (managed: false)
+	// "": [] (managed: false)
+}
+
+// Test that the LVM commands of logical volumes created and removed concurrently on the same volume group never
+// run at the same time.
+func TestLVMConcurrentCreateRemove(t *testing.T) {
+	d := &lvm{}
+	d.name = "pool"
+	d.config = map[string]string{"lvm.vg_name": "vg", "lvm.use_thinpool": "false"}
+	d.logger = logger.NewMemoryLogger()
+
+	dir := t.TempDir()
+	runningDir := filepath.Join(dir, "running")
+	racedFile := filepath.Join(dir, "raced")
+
+	// Mock the LVM tools so that each command marks itself as running for a short while and records whether
+	// another one was already running.
+	tool := filepath.Join(dir, "lvm-tool")
+	script := fmt.Sprintf("#!/bin/sh\nmkdir %[1]s 2>/dev/null || touch %[2]s\nsleep 0.01\nrmdir %[1]s 2>/dev/null || true\n", runningDir, racedFile)
+	assert.NoError(t, os.WriteFile(tool, []byte(script), 0755))
+
+	lvmToolPaths = map[string]string{"lvchange": tool, "lvcreate": tool, "lvremove": tool}
+	defer func() { lvmToolPaths = map[string]string{} }()
+
+	oldLVMVersion := lvmVersion
+	lvmVersion = "2.03.11"
+	defer func() { lvmVersion = oldLVMVersion }()
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 40)
+
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			vol := NewVolume(d, d.name, VolumeTypeVM, ContentTypeBlock, fmt.Sprintf("v%d", i), map[string]string{"size": "8MiB"}, d.config)
+
+			errs <- d.createLogicalVolume("vg", "", vol, false)
+			errs <- d.removeLogicalVolume(d.lvmDevPath("vg", vol.volType, vol.contentType, vol.name))
+		}()
+	}
+
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		assert.NoError(t, err)
+	}
+
+	assert.NoFileExists(t, racedFile)
+	assert.NoDirExists(t, runningDir)
+}
+
+// Test that a missing volume group fails the later operations on it without running LVM commands.
+func TestLVMMissingVolumeGroup(t *testing.T) {
+	l := logger.NewMemoryLogger()
+
+	d := &lvm{}
+	d.name = "pool"
+	d.logger = l
+
+	defer d.markVolumeGroupAvailable("vgtest")
+
+	_, err := d.tryRunVolumeGroupCommand("vgtest", "sh", "-c", `echo '  Volume group "vgtest" not found' >&2; exit 5`)
+	assert.ErrorIs(t, err, ErrVolumeGroupNotFound)
+
+	ranFile := filepath.Join(t.TempDir(), "ran")
+
+	_, err = d.runVolumeGroupCommand("vgtest", "touch", ranFile)
+	assert.ErrorIs(t, err, ErrVolumeGroupNotFound)
+	assert.NoFileExists(t, ranFile)
+	assert.Len(t, l.EntriesAtLevel(logrus.ErrorLevel), 1)
+
+	// Other volume groups aren't affected.
+	_, err = d.runVolumeGroupCommand("other", "touch", ranFile)
+	assert.NoError(t, err)
+	assert.FileExists(t, ranFile)
+
+	d.markVolumeGroupAvailable("vgtest")
+	assert.Len(t, l.EntriesAtLevel(logrus.InfoLevel), 1)
+
+	_, err = d.runVolumeGroupCommand("vgtest", "true")
+	assert.NoError(t, err)
+}
+
+// Test that the LVM commands are resolved from LXD_LVM_PATH when it is set.
+func TestResolveLVMTools(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("LXD_LVM_PATH", dir)
+
+	for _, tool := range lvmTools {
+		err := os.WriteFile(filepath.Join(dir, tool), []byte("#!/bin/sh\n"), 0755)
+		assert.NoError(t, err)
+	}
+
+	paths, err := resolveLVMTools()
+	assert.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, "lvcreate"), paths["lvcreate"])
+
+	// A command without an executable of its own is run through the lvm command.
+	assert.NoError(t, os.Remove(filepath.Join(dir, "vgs")))
+
+	paths, err = resolveLVMTools()
+	assert.NoError(t, err)
+	assert.NotContains(t, paths, "vgs")
+
+	lvmToolPaths = paths
+	defer func() { lvmToolPaths = map[string]string{} }()
+
+	cmd, args := lvmCommand("vgs", "--noheadings")
+	assert.Equal(t, filepath.Join(dir, "lvm"), cmd)
+	assert.Equal(t, []string{"vgs", "--noheadings"}, args)
+
+	// Only a missing lvm command is an error.
+	assert.NoError(t, os.Remove(filepath.Join(dir, "lvm")))
+
+	_, err = resolveLVMTools()
+	assert.ErrorContains(t, err, fmt.Sprintf("Required tool %q is missing", filepath.Join(dir, "lvm")))
+}
+
+// Image volumes use the image thin pool when one is set.
+func Example_lvm_volumeThinpoolName() {
+	d := &lvm{common: common{config: map[string]string{}}}
+	image := Volume{volType: VolumeTypeImage}
+	container := Volume{volType: VolumeTypeContainer}
+
+	fmt.Println(d.volumeThinpoolName(image), d.volumeThinpoolName(container))
+
+	d.config["lvm.image_thinpool_name"] = "images"
+	fmt.Println(d.volumeThinpoolName(image), d.volumeThinpoolName(container))
+
+	// Output: LXDThinPool LXDThinPool
+	// images LXDThinPool
+}
+
+// Test that a missing filesystem tool is reported by name and that found tools are only looked up once.
+func TestCheckLVMFilesystemTools(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("PATH", dir)
+	lvmCheckedFilesystems = map[string]bool{}
+
+	for _, tool := range []string{"blkid", "mkfs.ext4"} {
+		err := os.WriteFile(filepath.Join(dir, tool), []byte("#!/bin/sh\n"), 0755)
+		assert.NoError(t, err)
+	}
+
+	err := checkLVMFilesystemTools("")
+	assert.ErrorContains(t, err, `Required tool "e2fsck" for "ext4" filesystems is missing`)
+
+	err = os.WriteFile(filepath.Join(dir, "e2fsck"), []byte("#!/bin/sh\n"), 0755)
+	assert.NoError(t, err)
+
+	err = checkLVMFilesystemTools("ext4")
+	assert.NoError(t, err)
+
+	// The result is remembered even if a tool is removed afterwards.
+	assert.NoError(t, os.Remove(filepath.Join(dir, "blkid")))
+	assert.NoError(t, checkLVMFilesystemTools("ext4"))
+
+	err = checkLVMFilesystemTools("xfs")
+	assert.ErrorContains(t, err, `Required tool "blkid" for "xfs" filesystems is missing`)
+}
+
+func Example_validateLVMName() {
+	for _, name := range []string{"vg0", "my-vg_1.data+", "my vg", "vg;rm", "vgé", "-vg", "..", ""} {
+		err := validateLVMName(name)
+		if err != nil {
+			fmt.Printf("%q: %v\n", name, err)
+		} else {
+			fmt.Printf("%q: valid\n", name)
+		}
+	}
+
+	// Output: "vg0": valid
+	// "my-vg_1.data+": valid
+	// "my vg": Name contains invalid character ' '
+	// "vg;rm": Name contains invalid character ';'
+	// "vgé": Name contains invalid character 'é'
+	// "-vg": Name cannot start with a hyphen
+	// "..": Name cannot be ".."
+	// "": valid
+}
+
+func Example_lvm_parseLogicalVolumeReadOnly() {
+	d := &lvm{}
+
+	// Mocked output of "lvs --noheadings -o lv_attr" for a read-only and a writable thin snapshot, followed by
+	// a read-only, a writable and a read-only activated (but not read-only) classic snapshot.
+	for _, attr := range []string{"  Vri---tz-k\n", "  Vwi---tz-k\n", "  sri-a-s---\n", "  swi-a-s---\n", "  sRi-a-s---\n", ""} {
+		fmt.Println(d.parseLogicalVolumeReadOnly(attr))
+	}
+
+	// Output: true
+	// false
+	// true
+	// false
+	// false
+	// false
+}
+
+func Example_lvm_lvmDevPath() {
+	d := &lvm{}
+
+	fmt.Println(d.lvmDevPath("vg", VolumeTypeContainer, ContentTypeFS, "c1"))
+	fmt.Println(d.lvmDevPath("my-vg", VolumeTypeContainer, ContentTypeFS, "my-c1"))
+	fmt.Println(d.lvmDevPath("vg", VolumeTypeVM, ContentTypeBlock, "v1/snap-0"))
+
+	// Output: /dev/mapper/vg-containers_c1
+	// /dev/mapper/my--vg-containers_my----c1
+	// /dev/mapper/vg-virtual--machines_v1--snap----0.block
+}
+
+func Example_lvm_isLVMVolumeInUseError() {
+	d := &lvm{}
+
+	// Mocked lvremove failures, all of which exit with status 5.
+	for _, stderr := range []string{
+		"  Logical volume vg/containers_c1 in use.\n",
+		"  Can't remove open logical volume \"containers_c1\"\n",
+		"  Failed to find logical volume \"vg/containers_c1\"\n",
+	} {
+		err := fmt.Errorf("Failed removing volume: %w", shared.NewRunError("lvremove", nil, errors.New("exit status 5"), nil, bytes.NewBufferString(stderr)))
+		fmt.Println(d.isLVMVolumeInUseError(err))
+	}
+
+	fmt.Println(d.isLVMVolumeInUseError(errors.New("Logical volume in use")))
+
+	// Output: true
+	// true
+	// false
+	// false
+}
+
+func Example_lvm_findMissingVolumes() {
+	d := &lvm{}
+	d.name = "pool"
+
+	// Mocked output of "lvs --noheadings -o lv_name vg".
+	lvNames := strings.Fields(`  LXDThinPool
+  containers_c1
+  containers_c1-snap0
+  virtual-machines_v1
+  virtual-machines_v1.block
+  custom_default_vol1.iso
+`)
+
+	volNames := map[VolumeType][]string{
+		VolumeTypeContainer: {"c1", "c2"},
+		VolumeTypeVM:        {"v1"},
+		VolumeTypeCustom:    {"default_vol1", "default_vol2"},
+	}
+
+	for _, mountPath := range d.findMissingVolumes(lvNames, volNames) {
+		fmt.Println(strings.TrimPrefix(mountPath, GetPoolMountPath(d.name)))
+	}
+
+	// Output: /containers/c2
+	// /custom/default_vol2
+}
+
+func Example_lvm_parseLVMWarnings() {
+	d := &lvm{}
+
+	// Mocked stderr output of a successful lvcreate.
+	stderr := `  WARNING: Sum of all thin volume sizes (20.00 GiB) exceeds the size of thin pool vg/LXDThinPool (10.00 GiB).
+  WARNING: You have not turned on protection against thin pools running out of space.
+  Logical volume "containers_c1" created.
+`
+
+	for _, warning := range d.parseLVMWarnings(stderr) {
+		fmt.Println(warning)
+	}
+
+	fmt.Println(len(d.parseLVMWarnings("")))
+
+	// Output: Sum of all thin volume sizes (20.00 GiB) exceeds the size of thin pool vg/LXDThinPool (10.00 GiB).
+	// You have not turned on protection against thin pools running out of space.
+	// 0
+}
+
+func Example_validateLVMThinpoolChunkSize() {
+	for _, size := range []string{"64KiB", "512KiB", "1GiB", "32KiB", "2GiB", "96KiB", "foo"} {
+		err := validateLVMThinpoolChunkSize(size)
+		if err != nil {
+			fmt.Printf("%s: %v\n", size, err)
+		} else {
+			fmt.Printf("%s: valid\n", size)
+		}
+	}
+
+	// Output: 64KiB: valid
+	// 512KiB: valid
+	// 1GiB: valid
+	// 32KiB: Chunk size must be between 64KiB and 1GiB
+	// 2GiB: Chunk size must be between 64KiB and 1GiB
+	// 96KiB: Chunk size must be a power of two
+	// foo: Invalid value: foo
+}
+
+func Example_lvm_checkRestoreSnapshot() {
+	d := &lvm{}
+	d.config = map[string]string{"lvm.vg_name": "vg"}
+
+	// Mocked logical volumes in the volume group.
+	lvExists := func(volDevPath string) (bool, error) {
+		return shared.ValueInSlice(volDevPath, []string{
+			"/dev/mapper/vg-virtual--machines_v1--snap0.block",
+			"/dev/mapper/vg-virtual--machines_v1--snap0",
+			"/dev/mapper/vg-virtual--machines_v1--snap1.block",
+		}), nil
+	}
+
+	vol := Volume{pool: "pool", volType: VolumeTypeVM, contentType: ContentTypeBlock, name: "v1"}
+
+	for _, snapVol := range []Volume{
+		{pool: "pool", volType: VolumeTypeVM, contentType: ContentTypeBlock, name: "v1/snap0"},
+		{pool: "pool", volType: VolumeTypeVM, contentType: ContentTypeBlock, name: "v1/snap1"},
+		{pool: "pool", volType: VolumeTypeVM, contentType: ContentTypeBlock, name: "v1/snap2"},
+		{pool: "other", volType: VolumeTypeVM, contentType: ContentTypeBlock, name: "v1/snap0"},
+		{pool: "pool", volType: VolumeTypeVM, contentType: ContentTypeBlock, name: "v2/snap0"},
+	} {
+		fmt.Println(d.checkRestoreSnapshot(vol, snapVol, lvExists))
+	}
+
+	// Output: <nil>
+	// Logical volume "virtual-machines_v1-snap1" of snapshot "v1/snap1" not found in volume group "vg"
+	// Logical volume "virtual-machines_v1-snap2.block" of snapshot "v1/snap2" not found in volume group "vg"
+	// Snapshot "v1/snap0" is on pool "other" rather than in volume group "vg" of volume "v1"
+	// Volume "v2/snap0" isn't a snapshot of volume "v1"
+}
+
+func Example_parseLVMVersion() {
+	// Mocked output of "lvm version", preceded by a warning.
+	output := `  WARNING: Failed to connect to lvmetad. Falling back to device scanning.
+  LVM version:     2.03.11(2) (2021-01-08)
+  Library version: 1.02.175 (2021-01-08)
+  Driver version:  4.45.0
+  Configuration:   ./configure --build=x86_64-linux-gnu --prefix=/usr
+`
+
+	fmt.Println(parseLVMVersion(output))
+	fmt.Printf("%q\n", parseLVMVersion("  Configuration:   ./configure\n"))
+
+	// Output: 2.03.11(2) (2021-01-08) / 1.02.175 (2021-01-08) / 4.45.0
+	// ""
+}
+
+// Test that creating a thin volume fails early when the thin pool is missing or isn't a thin pool.
+func TestLVMCreateLogicalVolumeThinpoolCheck(t *testing.T) {
+	d := &lvm{}
+	d.name = "pool"
+	d.config = map[string]string{"lvm.vg_name": "vg", "lvm.thinpool_name": "thin"}
+	d.logger = logger.NewMemoryLogger()
+
+	// Mock lvs so that "vg/thin" is a linear logical volume and any other logical volume is missing.
+	lvs := filepath.Join(t.TempDir(), "lvs")
+	script := `#!/bin/sh
+case "$*" in
+  *vg/thin) echo "  -wi-a-----" ;;
+  *) exit 5 ;;
+esac
+`
+	assert.NoError(t, os.WriteFile(lvs, []byte(script), 0755))
+
+	lvmToolPaths = map[string]string{"lvs": lvs}
+	defer func() { lvmToolPaths = map[string]string{} }()
+
+	vol := NewVolume(d, d.name, VolumeTypeContainer, ContentTypeFS, "c1", map[string]string{"size": "1GiB"}, d.config)
+
+	err := d.createLogicalVolume("vg", "thin", vol, true)
+	assert.ErrorContains(t, err, `LVM volume named "thin" exists but is not a thin pool`)
+
+	err = d.createLogicalVolume("vg", "missing", vol, true)
+	assert.ErrorContains(t, err, `LVM thin pool "missing" not found in volume group "vg"`)
+}
+
+// Test that failed logical volume operations report the volume group and devices involved and keep the original error.
+func TestLVMLogicalVolumeErrorContext(t *testing.T) {
+	d := &lvm{}
+	d.name = "pool"
+	d.config = map[string]string{"lvm.vg_name": "vgctx"}
+	d.logger = logger.NewMemoryLogger()
+
+	// Mock the LVM tools so that they fail as if the volume group had been removed.
+	tool := filepath.Join(t.TempDir(), "lvm-tool")
+	script := `#!/bin/sh
+echo '  Volume group "vgctx" not found' >&2
+exit 5
+`
+	assert.NoError(t, os.WriteFile(tool, []byte(script), 0755))
+
+	lvmToolPaths = map[string]string{"lvremove": tool, "lvrename": tool}
+	defer func() {
+		lvmToolPaths = map[string]string{}
+
+		lvmMissingVolumeGroupsMu.Lock()
+		delete(lvmMissingVolumeGroups, "vgctx")
+		lvmMissingVolumeGroupsMu.Unlock()
+	}()
+
+	err := d.removeLogicalVolume("/dev/vgctx/containers_c1")
+	assert.ErrorContains(t, err, `Failed removing LVM logical volume "/dev/vgctx/containers_c1" from volume group "vgctx"`)
+	assert.ErrorIs(t, err, ErrVolumeGroupNotFound)
+
+	var runErr shared.RunError
+	assert.ErrorAs(t, err, &runErr)
+
+	// Forget the volume group is missing so that the rename runs the command again.
+	lvmMissingVolumeGroupsMu.Lock()
+	delete(lvmMissingVolumeGroups, "vgctx")
+	lvmMissingVolumeGroupsMu.Unlock()
+
+	err = d.renameLogicalVolume("/dev/vgctx/containers_c1", "/dev/vgctx/containers_c2")
+	assert.ErrorContains(t, err, `Failed renaming LVM logical volume "/dev/vgctx/containers_c1" to "/dev/vgctx/containers_c2" in volume group "vgctx"`)
+	assert.ErrorIs(t, err, ErrVolumeGroupNotFound)
+}
+
+// Test that volume hooks run without a shell and receive the volume details through the environment.
+func TestLVMRunVolumeHook(t *testing.T) {
+	dir := t.TempDir()
+	hook := filepath.Join(dir, "hook")
+	outFile := filepath.Join(dir, "out")
+
+	script := `#!/bin/sh
+echo "$1 $LXD_POOL $LXD_VOLUME_NAME $LXD_VOLUME_TYPE $LXD_LV_PATH" > "$2"
+`
+	assert.NoError(t, os.WriteFile(hook, []byte(script), 0755))
+
+	d := &lvm{}
+	d.name = "pool"
+	d.config = map[string]string{"lvm.vg_name": "vg"}
+	d.logger = logger.NewMemoryLogger()
+
+	vol := NewVolume(d, d.name, VolumeTypeContainer, ContentTypeFS, "c1", map[string]string{}, d.config)
+
+	command := hook + " 'a b;' " + outFile
+	err := d.runVolumeHookCommand("storage.lvm.post_create_hook", command, vol, time.Minute)
+	assert.NoError(t, err)
+
+	out, err := os.ReadFile(outFile)
+	assert.NoError(t, err)
+	assert.Equal(t, "a b; pool c1 containers /dev/mapper/vg-containers_c1\n", string(out))
+
+	// Custom volumes don't run hooks.
+	assert.NoError(t, os.Remove(outFile))
+
+	customVol := NewVolume(d, d.name, VolumeTypeCustom, ContentTypeFS, "data", map[string]string{}, d.config)
+	err = d.runVolumeHookCommand("storage.lvm.post_create_hook", command, customVol, time.Minute)
+	assert.NoError(t, err)
+	assert.NoFileExists(t, outFile)
+
+	err = d.runVolumeHookCommand("storage.lvm.post_create_hook", "/bin/false", vol, time.Minute)
+	assert.ErrorContains(t, err, "Failed running storage.lvm.post_create_hook command")
+
+	err = d.runVolumeHookCommand("storage.lvm.post_create_hook", "/bin/sleep 10", vol, 100*time.Millisecond)
+	assert.ErrorContains(t, err, "Timed out running storage.lvm.post_create_hook command after 100ms")
+}
+
+// Test that btrfs snapshots are only used for btrfs container volumes and that existing LVM snapshots are kept.
+func TestLVMUsesBtrfsSnapshot(t *testing.T) {
+	d := &lvm{}
+	d.name = "pool"
+	d.config = map[string]string{"lvm.vg_name": "vg"}
+	d.logger = logger.NewMemoryLogger()
+
+	// Mock lvs so that only the logical volume of snapshot "snap0" exists.
+	lvs := filepath.Join(t.TempDir(), "lvs")
+	script := `#!/bin/sh
+case "$*" in
+  *snap0) echo "  containers_c1-snap0" ;;
+  *) exit 5 ;;
+esac
+`
+	assert.NoError(t, os.WriteFile(lvs, []byte(script), 0755))
+
+	lvmToolPaths = map[string]string{"lvs": lvs}
+	defer func() { lvmToolPaths = map[string]string{} }()
+
+	btrfsConfig := map[string]string{"block.filesystem": "btrfs"}
+	lvmSnap := NewVolume(d, d.name, VolumeTypeContainer, ContentTypeFS, "c1/snap0", btrfsConfig, d.config)
+	btrfsSnap := NewVolume(d, d.name, VolumeTypeContainer, ContentTypeFS, "c1/snap1", btrfsConfig, d.config)
+	ext4Snap := NewVolume(d, d.name, VolumeTypeContainer, ContentTypeFS, "c1/snap1", map[string]string{"block.filesystem": "ext4"}, d.config)
+	vmSnap := NewVolume(d, d.name, VolumeTypeVM, ContentTypeBlock, "v1/snap1", btrfsConfig, d.config)
+
+	for _, snapVol := range []Volume{lvmSnap, ext4Snap, vmSnap} {
+		btrfs, err := d.usesBtrfsSnapshot(snapVol, false)
+		assert.NoError(t, err)
+		assert.False(t, btrfs, snapVol.name)
+	}
+
+	btrfs, err := d.usesBtrfsSnapshot(btrfsSnap, false)
+	assert.NoError(t, err)
+	assert.True(t, btrfs)
+
+	// New snapshots are only btrfs snapshots when enabled.
+	btrfs, err = d.usesBtrfsSnapshot(btrfsSnap, true)
+	assert.NoError(t, err)
+	assert.False(t, btrfs)
+
+	d.config["lvm.btrfs_snapshots"] = "true"
+	btrfs, err = d.usesBtrfsSnapshot(btrfsSnap, true)
+	assert.NoError(t, err)
+	assert.True(t, btrfs)
+
+	btrfs, err = d.usesBtrfsSnapshot(ext4Snap, true)
+	assert.NoError(t, err)
+	assert.False(t, btrfs)
+
+	assert.Equal(t, "/mnt/c1/.lxd-snapshots/snap1", btrfsSnapshotPath("/mnt/c1", btrfsSnap))
+}
+
+func Example_lvm_parseVolumeGroupUsage() {
+	d := &lvm{}
+
+	// Mocked output of "vgs vg --noheadings --units b --nosuffix --separator , -o vg_size,vg_free,lv_count".
+	usage, err := d.parseVolumeGroupUsage("  21470642176,4290772992,7\n")
+	if err != nil {
+		fmt.Println(err)
+	}
+
+	fmt.Printf("total %d, free %d, volumes %d\n", usage.VolumeGroupTotal, usage.VolumeGroupFree, usage.LogicalVolumes)
+
+	// Mocked output of "lvs --noheadings --separator , -o data_percent,metadata_percent vg/LXDThinPool".
+	dataPerc, metaPerc, err := d.parseThinpoolDataMetadataUsage("  42.50,12.81\n")
+	if err != nil {
+		fmt.Println(err)
+	}
+
+	fmt.Printf("data %.2f%%, metadata %.2f%%\n", dataPerc, metaPerc)
+
+	_, err = d.parseVolumeGroupUsage("  21470642176,4290772992\n")
+	fmt.Println(err)
+
+	// Output: total 21470642176, free 4290772992, volumes 7
+	// data 42.50%, metadata 12.81%
+	// Unexpected output from vgs command: "21470642176,4290772992"
+}
+
+// Test that snapshots of volumes that aren't mounted are taken without freezing.
+func TestLVMFreezeDuringUnmounted(t *testing.T) {
+	d := &lvm{}
+	d.name = "pool"
+	d.config = map[string]string{"lvm.vg_name": "vg", "lvm.freeze_on_snapshot": "true"}
+	d.logger = logger.NewMemoryLogger()
+
+	vol := NewVolume(d, d.name, VolumeTypeContainer, ContentTypeFS, "c1", map[string]string{}, d.config)
+
+	calls := 0
+	err := d.freezeDuring(vol, func() error {
+		calls++
+		return errors.New("snapshot failed")
+	})
+
+	assert.EqualError(t, err, "snapshot failed")
+	assert.Equal(t, 1, calls)
+}
+
+// Test that volume names survive the round trip through their logical volume names, and that snapshots are
+// recognised from the decoded name.
+func TestLVMVolumeNameRoundTrip(t *testing.T) {
+	d := &lvm{}
+	d.name = "pool"
+
+	tests := map[string]string{
+		"c1":                   "c1",
+		"proj_c1-with-hyphens": "proj_c1--with--hyphens",
+		"c1/snap0":             "c1-snap0",
+		"c1-/snap0":            "c1---snap0",
+		"c--1/snap--0-":        "c----1-snap----0--",
+		"c1.block/snap0.block": "c1.block-snap0.block",
+	}
+
+	for volName, lvName := range tests {
+		assert.Equal(t, lvName, volNameToLVName(volName), volName)
+		assert.Equal(t, volName, lvNameToVolName(lvName), lvName)
+	}
+
+	parent := NewVolume(d, d.name, VolumeTypeContainer, ContentTypeFS, "c1", nil, nil)
+	hyphenParent := NewVolume(d, d.name, VolumeTypeContainer, ContentTypeFS, "c1-", nil, nil)
+
+	assert.Equal(t, "snap-0", d.parseLogicalVolumeSnapshot(parent, "containers_c1-snap--0"))
+	assert.Equal(t, "", d.parseLogicalVolumeSnapshot(parent, "containers_c1---snap0"))
+	assert.Equal(t, "snap0", d.parseLogicalVolumeSnapshot(hyphenParent, "containers_c1---snap0"))
+	assert.Equal(t, "", d.parseLogicalVolumeSnapshot(parent, "custom_c1-snap0"))
+}
+
+func Example_lvm_parseThinpoolVirtualSize() {
+	d := &lvm{}
+
+	// Mocked output of "lvs --noheadings --units b --nosuffix --separator , -o lv_name,lv_size,pool_lv vg".
+	output := `  LXDThinPool,10737418240,
+  containers_c1,10737418240,LXDThinPool
+  containers_c1-snap0,10737418240,LXDThinPool
+  other,4194304,
+  images_abc,5368709120,OtherPool
+`
+
+	poolSize, virtualSize, err := d.parseThinpoolVirtualSize(output, "LXDThinPool")
+	fmt.Println(poolSize, virtualSize, err)
+
+	_, _, err = d.parseThinpoolVirtualSize(output, "missing")
+	fmt.Println(err)
+
+	fmt.Println(validateLVMOvercommitRatio("1.5"), validateLVMOvercommitRatio("0.5"))
+	fmt.Println(validateLVMOvercommitRatio("NaN"), validateLVMOvercommitRatio("+Inf"))
+
+	// Output: 10737418240 21474836480 <nil>
+	// Thin pool "missing" not found
+	// <nil> Overcommit ratio cannot be lower than 1
+	// Overcommit ratio must be a finite number Overcommit ratio must be a finite number
+}
+
+// Test that thin volumes are only discarded when their thin pool passes the discards down.
+func TestLVMWipeLogicalVolume(t *testing.T) {
+	d := &lvm{}
+	d.name = "pool"
+	d.config = map[string]string{"lvm.vg_name": "vg"}
+	d.logger = logger.NewMemoryLogger()
+
+	dir := t.TempDir()
+	argsFile := filepath.Join(dir, "args")
+
+	// Mock lvs so that the "c1" volume is in a thin pool that ignores discards, "c2" is in one that passes them
+	// down and "c3" isn't a thin volume.
+	tools := map[string]string{
+		"lvchange": "true",
+		"lvs": `case "$*" in
+  *pool_lv*c1) echo "  ThinIgnore" ;;
+  *pool_lv*c2) echo "  ThinPassdown" ;;
+  *pool_lv*) echo "" ;;
+  *ThinIgnore) echo "  ignore" ;;
+  *ThinPassdown) echo "  passdown" ;;
+  *) exit 5 ;;
+esac`,
+		"blkdiscard": fmt.Sprintf(`echo "$*" > %s`, argsFile),
+	}
+
+	lvmToolPaths = map[string]string{}
+	defer func() { lvmToolPaths = map[string]string{} }()
+
+	for name, script := range tools {
+		path := filepath.Join(dir, name)
+		assert.NoError(t, os.WriteFile(path, []byte("#!/bin/sh\n"+script+"\n"), 0755))
+		lvmToolPaths[name] = path
+	}
+
+	tests := map[string]string{
+		"c1": "--zeroout /dev/mapper/vg-containers_c1",
+		"c2": "/dev/mapper/vg-containers_c2",
+		"c3": "--zeroout /dev/mapper/vg-containers_c3",
+	}
+
+	for volName, args := range tests {
+		vol := NewVolume(d, d.name, VolumeTypeContainer, ContentTypeFS, volName, nil, d.config)
+
+		err := d.wipeLogicalVolume(vol)
+		assert.NoError(t, err)
+
+		content, err := os.ReadFile(argsFile)
+		assert.NoError(t, err)
+		assert.Equal(t, args, strings.TrimSpace(string(content)), volName)
+	}
+}
+
+// Test that a volume group renamed outside of LXD is only accepted if it contains the pool's instance volumes.
+func TestLVMCheckRenamedVolumeGroup(t *testing.T) {
+	t.Setenv("LXD_DIR", t.TempDir())
+
+	d := &lvm{}
+	d.name = "pool"
+	d.config = map[string]string{"lvm.vg_name": "vgold", "lvm.use_thinpool": "false"}
+	d.logger = logger.NewMemoryLogger()
+
+	for _, volName := range []string{"c1", "my-c2"} {
+		assert.NoError(t, os.MkdirAll(GetVolumeMountPath(d.name, VolumeTypeContainer, volName), 0711))
+	}
+
+	assert.NoError(t, os.MkdirAll(GetVolumeMountPath(d.name, VolumeTypeVM, "v1"), 0711))
+
+	// Mock lvs so that "vgnew" is missing the logical volume of "my-c2" and "vgfull" contains all of them.
+	lvs := filepath.Join(t.TempDir(), "lvs")
+	script := `#!/bin/sh
+case "$*" in
+  *vgnew) printf '  containers_c1 lxd_volume\n  virtual-machines_v1.block lxd_volume\n' ;;
+  *vgfull) printf '  containers_c1 lxd_volume\n  containers_my--c2 lxd_volume\n  virtual-machines_v1 lxd_volume\n  virtual-machines_v1.block lxd_volume\n' ;;
+  *) exit 5 ;;
+esac
+`
+	assert.NoError(t, os.WriteFile(lvs, []byte(script), 0755))
+
+	lvmToolPaths = map[string]string{"lvs": lvs}
+	defer func() { lvmToolPaths = map[string]string{} }()
+
+	err := d.checkRenamedVolumeGroup("vgnew")
+	assert.ErrorContains(t, err, `Logical volumes missing from volume group "vgnew": containers/my-c2`)
+
+	err = d.checkRenamedVolumeGroup("vgfull")
+	assert.NoError(t, err)
+	assert.Equal(t, "vgold", d.config["lvm.vg_name"])
+}
+
+// Test that merging a snapshot re-creates it afterwards and reports whether it was lost when that fails.
+func TestLVMMergeLogicalVolumeSnapshot(t *testing.T) {
+	d := &lvm{}
+	d.name = "pool"
+	d.config = map[string]string{"lvm.vg_name": "vg", "lvm.use_thinpool": "false"}
+	d.logger = logger.NewMemoryLogger()
+
+	dir := t.TempDir()
+	mergeFails := filepath.Join(dir, "merge-fails")
+	createFails := filepath.Join(dir, "create-fails")
+
+	// Mock the LVM tools so that lvconvert and lvcreate fail when their marker files exist.
+	tools := map[string]string{
+		"lvchange":  "true",
+		"lvs":       `echo "  containers_c1-snap0"`,
+		"lvconvert": fmt.Sprintf("[ ! -e %s ]", mergeFails),
+		"lvcreate":  fmt.Sprintf("[ ! -e %s ]", createFails),
+	}
+
+	lvmToolPaths = map[string]string{}
+	defer func() { lvmToolPaths = map[string]string{} }()
+
+	for name, script := range tools {
+		path := filepath.Join(dir, name)
+		assert.NoError(t, os.WriteFile(path, []byte("#!/bin/sh\n"+script+"\n"), 0755))
+		lvmToolPaths[name] = path
+	}
+
+	oldLVMVersion := lvmVersion
+	lvmVersion = "2.03.11"
+	defer func() { lvmVersion = oldLVMVersion }()
+
+	vol := NewVolume(d, d.name, VolumeTypeContainer, ContentTypeFS, "c1", nil, d.config)
+	snapVol, err := vol.NewSnapshot("snap0")
+	assert.NoError(t, err)
+
+	merged, err := d.mergeLogicalVolumeSnapshot(vol, snapVol)
+	assert.NoError(t, err)
+	assert.True(t, merged)
+
+	// The volume has been restored but the snapshot consumed by the merge couldn't be re-created.
+	assert.NoError(t, os.WriteFile(createFails, nil, 0644))
+
+	merged, err = d.mergeLogicalVolumeSnapshot(vol, snapVol)
+	assert.True(t, merged)
+
+	var snapLostErr ErrSnapshotLost
+	assert.ErrorAs(t, err, &snapLostErr)
+
+	// The merge didn't start and the snapshot is still there, so the volume is left as is.
+	assert.NoError(t, os.WriteFile(mergeFails, nil, 0644))
+
+	merged, err = d.mergeLogicalVolumeSnapshot(vol, snapVol)
+	assert.NoError(t, err)
+	assert.False(t, merged)
+}
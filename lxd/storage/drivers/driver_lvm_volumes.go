@@ -2,11 +2,15 @@ package drivers
 
 import (
 	"bufio"
+	"errors"
 	"fmt"
 	"io"
+	"io/fs"
 	"math"
+	"net/http"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"strings"
 
 	"golang.org/x/sys/unix"
@@ -24,6 +28,13 @@ import (
 	"github.com/canonical/lxd/shared/validate"
 )
 
+// lvmMountPropagationFlags maps the lvm.mount_propagation values to the mount flags that set them.
+var lvmMountPropagationFlags = map[string]uintptr{
+	"private": unix.MS_PRIVATE,
+	"shared":  unix.MS_SHARED,
+	"slave":   unix.MS_SLAVE,
+}
+
 // CreateVolume creates an empty volume and can optionally fill it by executing the supplied filler function.
 func (d *lvm) CreateVolume(vol Volume, filler *VolumeFiller, op *operations.Operation) error {
 	revert := revert.New()
@@ -37,9 +48,17 @@ func (d *lvm) CreateVolume(vol Volume, filler *VolumeFiller, op *operations.Oper
 
 	revert.Add(func() { _ = os.RemoveAll(volPath) })
 
-	err = d.createLogicalVolume(d.config["lvm.vg_name"], d.thinpoolName(), vol, d.usesThinpool())
+	// Making the filesystem (and pre-allocating the volume if requested) can take a while on large volumes.
+	if vol.contentType == ContentTypeFS {
+		updateProgress(op, fmt.Sprintf("Formatting volume %q", vol.name))
+	} else {
+		updateProgress(op, fmt.Sprintf("Creating volume %q", vol.name))
+	}
+
+	err = d.createLogicalVolume(d.config["lvm.vg_name"], d.volumeThinpoolName(vol), vol, d.usesThinpool())
+	updateProgress(op, "")
 	if err != nil {
-		return fmt.Errorf("Error creating LVM logical volume: %w", err)
+		return err
 	}
 
 	revert.Add(func() { _ = d.DeleteVolume(vol, op) })
@@ -90,6 +109,15 @@ func (d *lvm) CreateVolume(vol Volume, filler *VolumeFiller, op *operations.Oper
 				return err
 			}
 
+			// Check the unpacked image is usable before it is kept as the origin of instance volumes.
+			// On failure the volume is unmounted and removed by the revert.
+			if vol.volType == VolumeTypeImage && vol.contentType == ContentTypeFS {
+				err = checkImageRootfs(mountPath)
+				if err != nil {
+					return fmt.Errorf("Failed validating unpacked image: %w", err)
+				}
+			}
+
 			// Move the GPT alt header to end of disk if needed.
 			if vol.IsVMBlock() {
 				err = d.moveGPTAltHeader(devPath)
@@ -114,6 +142,12 @@ func (d *lvm) CreateVolume(vol Volume, filler *VolumeFiller, op *operations.Oper
 		return err
 	}
 
+	// A failing hook removes the volume through the revert.
+	err = d.runVolumeHook("storage.lvm.post_create_hook", vol)
+	if err != nil {
+		return err
+	}
+
 	revert.Success()
 	return nil
 }
@@ -125,6 +159,28 @@ func (d *lvm) CreateVolumeFromBackup(vol VolumeCopy, srcBackup backup.Info, srcD
 
 // CreateVolumeFromCopy provides same-pool volume copying functionality.
 func (d *lvm) CreateVolumeFromCopy(vol VolumeCopy, srcVol VolumeCopy, allowInconsistent bool, op *operations.Operation) error {
+	err := d.createVolumeFromCopy(vol, srcVol, allowInconsistent, op)
+	if err != nil {
+		return err
+	}
+
+	err = d.runVolumeHook("storage.lvm.post_create_hook", vol.Volume)
+	if err != nil {
+		// Remove the copied volume and its snapshots.
+		for _, snapVol := range vol.Snapshots {
+			_ = d.DeleteVolumeSnapshot(snapVol, op)
+		}
+
+		_ = d.DeleteVolume(vol.Volume, op)
+
+		return err
+	}
+
+	return nil
+}
+
+// createVolumeFromCopy copies a volume and its snapshots within the pool.
+func (d *lvm) createVolumeFromCopy(vol VolumeCopy, srcVol VolumeCopy, allowInconsistent bool, op *operations.Operation) error {
 	var err error
 	var srcSnapshots []string
 
@@ -141,8 +197,20 @@ func (d *lvm) CreateVolumeFromCopy(vol VolumeCopy, srcVol VolumeCopy, allowIncon
 		}
 	}
 
+	// The btrfs snapshots stored inside a volume would be copied along with its logical volume, so such
+	// volumes are copied using the generic copy.
+	btrfsSnapshots, err := d.btrfsSnapshots(srcVol.Volume, op)
+	if err != nil {
+		return err
+	}
+
+	sameThinpool, err := d.sameThinpool(vol.Volume, srcVol.Volume)
+	if err != nil {
+		return err
+	}
+
 	// We can use optimised copying when the pool is backed by an LVM thinpool.
-	if d.usesThinpool() {
+	if d.usesThinpool() && len(btrfsSnapshots) == 0 && sameThinpool {
 		err = d.copyThinpoolVolume(vol.Volume, srcVol.Volume, srcSnapshots, false)
 		if err != nil {
 			return err
@@ -159,7 +227,7 @@ func (d *lvm) CreateVolumeFromCopy(vol VolumeCopy, srcVol VolumeCopy, allowIncon
 	}
 
 	// Otherwise run the generic copy.
-	_, err = genericVFSCopyVolume(d, nil, vol, srcVol, srcSnapshots, false, allowInconsistent, op)
+	_, err = genericVFSCopyVolume(d, nil, vol, srcVol, srcSnapshots, false, allowInconsistent, btrfsSnapshotsExcludePaths(btrfsSnapshots), op)
 	return err
 }
 
@@ -171,13 +239,23 @@ func (d *lvm) CreateVolumeFromMigration(vol VolumeCopy, conn io.ReadWriteCloser,
 
 // RefreshVolume provides same-pool volume and specific snapshots syncing functionality.
 func (d *lvm) RefreshVolume(vol VolumeCopy, srcVol VolumeCopy, refreshSnapshots []string, allowInconsistent bool, op *operations.Operation) error {
+	btrfsSnapshots, err := d.btrfsSnapshots(srcVol.Volume, op)
+	if err != nil {
+		return err
+	}
+
+	sameThinpool, err := d.sameThinpool(vol.Volume, srcVol.Volume)
+	if err != nil {
+		return err
+	}
+
 	// We can use optimised copying when the pool is backed by an LVM thinpool.
-	if d.usesThinpool() {
+	if d.usesThinpool() && len(btrfsSnapshots) == 0 && sameThinpool {
 		return d.copyThinpoolVolume(vol.Volume, srcVol.Volume, refreshSnapshots, true)
 	}
 
 	// Otherwise run the generic copy.
-	_, err := genericVFSCopyVolume(d, nil, vol, srcVol, refreshSnapshots, true, allowInconsistent, op)
+	_, err = genericVFSCopyVolume(d, nil, vol, srcVol, refreshSnapshots, true, allowInconsistent, btrfsSnapshotsExcludePaths(btrfsSnapshots), op)
 	return err
 }
 
@@ -199,7 +277,16 @@ func (d *lvm) DeleteVolume(vol Volume, op *operations.Operation) error {
 		return err
 	}
 
+	// Image volumes are only created on pools using a thin pool (as optimized images need thin snapshots), and
+	// the instance volumes created from them are thin snapshots that don't depend on their origin volume once
+	// created. So image volumes can be removed while instance volumes that were created from them exist.
 	if lvExists {
+		// Failing hooks are only logged so that they can't prevent the volume from being deleted.
+		err = d.runVolumeHook("storage.lvm.pre_delete_hook", vol)
+		if err != nil {
+			d.logger.Warn("Failed running volume hook", logger.Ctx{"volName": vol.name, "err": err})
+		}
+
 		if vol.contentType == ContentTypeFS {
 			_, err = d.UnmountVolume(vol, false, op)
 			if err != nil {
@@ -207,9 +294,22 @@ func (d *lvm) DeleteVolume(vol Volume, op *operations.Operation) error {
 			}
 		}
 
+		// Wipe the volume before removing it if requested. Snapshots are read-only so are never wiped.
+		if shared.IsTrue(d.config["lvm.wipe_on_delete"]) {
+			err = d.wipeLogicalVolume(vol)
+			if err != nil {
+				return err
+			}
+		}
+
 		err = d.removeLogicalVolume(d.lvmDevPath(d.config["lvm.vg_name"], vol.volType, vol.contentType, vol.name))
 		if err != nil {
-			return fmt.Errorf("Error removing LVM logical volume: %w", err)
+			return err
+		}
+
+		err = d.reclaimThinpool()
+		if err != nil {
+			return err
 		}
 	}
 
@@ -282,7 +382,8 @@ func (d *lvm) FillVolumeConfig(vol Volume) error {
 		}
 
 		// Default filesystem mount options if neither volume nor pool specify an override.
-		if vol.config["block.mount_options"] == "" {
+		// The ext2 driver doesn't support the discard mount option.
+		if vol.config["block.mount_options"] == "" && vol.config["block.filesystem"] != "ext2" {
 			// Unchangeable volume property: Set unconditionally.
 			vol.config["block.mount_options"] = "discard"
 		}
@@ -306,7 +407,15 @@ func (d *lvm) FillVolumeConfig(vol Volume) error {
 func (d *lvm) commonVolumeRules() map[string]func(value string) error {
 	return map[string]func(value string) error{
 		"block.mount_options": validate.IsAny,
-		"block.filesystem":    validate.Optional(validate.IsOneOf(blockBackedAllowedFilesystems...)),
+		// lxdmeta:generate(entities=storage-lvm; group=volume-conf; key=block.filesystem)
+		// Valid options are: `btrfs`, `ext2`, `ext3`, `ext4`, `xfs`
+		// If not set, `ext4` is assumed.
+		// ---
+		//  type: string
+		//  condition: block-based volume with content type `filesystem`
+		//  defaultdesc: same as `volume.block.filesystem`
+		//  shortdesc: File system of the storage volume
+		"block.filesystem": validate.Optional(validate.IsOneOf(lvmAllowedFilesystems...)),
 		// lxdmeta:generate(entities=storage-lvm; group=volume-conf; key=lvm.stripes)
 		//
 		// ---
@@ -321,6 +430,45 @@ func (d *lvm) commonVolumeRules() map[string]func(value string) error {
 		//  defaultdesc: same as `volume.lvm.stripes.size`
 		//  shortdesc: Size of stripes to use
 		"lvm.stripes.size": validate.Optional(validate.IsSize),
+		// lxdmeta:generate(entities=storage-lvm; group=volume-conf; key=lvm.preallocate)
+		// When enabled, all blocks of a new thin volume are allocated in the thin pool when the volume
+		// is created, by writing zeroes over the whole volume.
+		// This avoids the latency of allocating blocks on first write (for example, for databases), at the
+		// cost of using the full size of the volume in the thin pool straight away and a slower volume creation.
+		// Blocks that are discarded later (for example, when using the `discard` mount option) are returned to
+		// the thin pool, and space added when growing the volume is not pre-allocated.
+		// ---
+		//  type: bool
+		//  condition: thin pool
+		//  defaultdesc: same as `volume.lvm.preallocate` or `false`
+		//  shortdesc: Whether to pre-allocate the blocks of new thin volumes
+		"lvm.preallocate": validate.Optional(validate.IsBool),
+		// lxdmeta:generate(entities=storage-lvm; group=volume-conf; key=lvm.mount_readonly)
+		// When enabled, the filesystem of the volume is mounted read-only and without replaying its journal,
+		// so that a filesystem suspected to be corrupted can be inspected or its data recovered without
+		// modifying it. {config:option}`storage-lvm-pool-conf:lvm.fsck_on_mount` is ignored for the volume.
+		// An instance using the volume might fail to start if it needs to write to its root file system.
+		// The change takes effect the next time the volume is mounted.
+		// ---
+		//  type: bool
+		//  condition: block-based volume with content type `filesystem`
+		//  defaultdesc: same as `volume.lvm.mount_readonly` or `false`
+		//  shortdesc: Whether to mount the volume read-only for recovery
+		"lvm.mount_readonly": validate.Optional(validate.IsBool),
+		// lxdmeta:generate(entities=storage-lvm; group=volume-conf; key=lvm.mount_propagation)
+		// Valid options are: `private`, `shared`, `slave`
+		// When set, the propagation type of the mount of the volume is changed after it is mounted. Otherwise,
+		// the mount keeps the propagation type it gets from its parent mount.
+		// For a container root file system, use `shared` or `slave` if mounts made on the host below the volume's
+		// mount path must also appear in the container (for example, for nested containers or network namespaces
+		// bind mounted from the host), and `private` to stop mounts from propagating between the two.
+		// The change takes effect the next time the volume is mounted.
+		// ---
+		//  type: string
+		//  condition: block-based volume with content type `filesystem`
+		//  defaultdesc: same as `volume.lvm.mount_propagation`
+		//  shortdesc: Propagation type of the volume mount
+		"lvm.mount_propagation": validate.Optional(validate.IsOneOf("private", "shared", "slave")),
 	}
 }
 
@@ -350,6 +498,10 @@ func (d *lvm) ValidateVolume(vol Volume, removeUnknownKeys bool) error {
 		return fmt.Errorf("lvm.stripes.size cannot be used with thin pool volumes")
 	}
 
+	if !d.usesThinpool() && vol.config["lvm.preallocate"] != "" {
+		return fmt.Errorf("lvm.preallocate can only be used with thin pool volumes")
+	}
+
 	return nil
 }
 
@@ -373,6 +525,11 @@ func (d *lvm) UpdateVolume(vol Volume, changedConfig map[string]string) error {
 		return fmt.Errorf("lvm.stripes.size cannot be changed")
 	}
 
+	_, changed = changedConfig["lvm.preallocate"]
+	if changed {
+		return fmt.Errorf("lvm.preallocate cannot be changed")
+	}
+
 	return nil
 }
 
@@ -447,6 +604,14 @@ func (d *lvm) SetVolumeQuota(vol Volume, size string, allowUnsafeResize bool, op
 		return nil
 	}
 
+	// Growing a thin volume adds to the virtual size of its thin pool.
+	if sizeBytes > oldSizeBytes && d.usesThinpool() {
+		err = d.checkThinpoolOvercommit(d.config["lvm.vg_name"], d.volumeThinpoolName(vol), sizeBytes-oldSizeBytes)
+		if err != nil {
+			return err
+		}
+	}
+
 	l := d.logger.AddContext(logger.Ctx{"dev": volDevPath, "size": fmt.Sprintf("%db", sizeBytes)})
 
 	// Activate volume if needed.
@@ -548,11 +713,42 @@ func (d *lvm) GetVolumeDiskPath(vol Volume) (string, error) {
 	return "", ErrNotSupported
 }
 
+// GetVolumeDevice returns the resolved device path of the logical volume backing a volume and whether the
+// volume is currently mounted. If the logical volume is inactive its device path is returned unresolved.
+func (d *lvm) GetVolumeDevice(vol Volume) (string, bool, error) {
+	volDevPath := d.lvmDevPath(d.config["lvm.vg_name"], vol.volType, vol.contentType, vol.name)
+
+	devPath, err := filepath.EvalSymlinks(volDevPath)
+	if err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			return "", false, fmt.Errorf("Failed resolving logical volume device path %q: %w", volDevPath, err)
+		}
+
+		volExists, err := d.logicalVolumeExists(volDevPath)
+		if err != nil {
+			return "", false, err
+		}
+
+		if !volExists {
+			return "", false, api.StatusErrorf(http.StatusNotFound, "Logical volume %q not found", volDevPath)
+		}
+
+		devPath = volDevPath
+	}
+
+	mounted := filesystem.IsMountPoint(vol.MountPath())
+
+	return devPath, mounted, nil
+}
+
 // ListVolumes returns a list of LXD volumes in storage pool.
 func (d *lvm) ListVolumes() ([]Volume, error) {
 	vols := make(map[string]Volume)
 
-	cmd := exec.Command("lvs", "--noheadings", "-o", "lv_name", d.config["lvm.vg_name"])
+	args := []string{"--noheadings", "-o", "lv_name,lv_tags", d.config["lvm.vg_name"]}
+	start := d.lvmCommandStarted("lvs", args)
+	lvsPath, lvsArgs := lvmCommand("lvs", args...)
+	cmd := exec.Command(lvsPath, lvsArgs...)
 	stdout, err := cmd.StdoutPipe()
 	if err != nil {
 		return nil, err
@@ -570,7 +766,19 @@ func (d *lvm) ListVolumes() ([]Volume, error) {
 
 	scanner := bufio.NewScanner(stdout)
 	for scanner.Scan() {
-		rawName := strings.TrimSpace(scanner.Text())
+		rawName, tags := d.parseLogicalVolumeTags(scanner.Text())
+		if rawName == "" {
+			continue
+		}
+
+		// Only consider volumes that are marked as managed by LXD, so that unrelated volumes sharing the
+		// volume group are never picked up, even if they follow LXD's "<type>_<name>" naming scheme.
+		// Volumes created by older versions of LXD are tagged by the storage_lvm_tag_volumes patch.
+		if !shared.ValueInSlice(lvmVolumeMarker, tags) {
+			d.logger.Debug("Ignoring volume not managed by LXD", logger.Ctx{"name": rawName})
+			continue
+		}
+
 		var volType VolumeType
 		var volName string
 
@@ -587,10 +795,9 @@ func (d *lvm) ListVolumes() ([]Volume, error) {
 			continue // Ignore unrecognised volume.
 		}
 
-		lvSnapSepCount := strings.Count(volName, lvmSnapshotSeparator)
-		if lvSnapSepCount%2 != 0 {
-			// If snapshot separator count is odd, then this means we have a lone lvmSnapshotSeparator
-			// that is not part of the lvmEscapedHyphen pair, which means this volume is a snapshot.
+		// Unescape raw LVM name to LXD storage volume name.
+		volName = lvNameToVolName(volName)
+		if shared.IsSnapshot(volName) {
 			d.logger.Debug("Ignoring snapshot volume", logger.Ctx{"name": rawName})
 			continue // Ignore snapshot volumes.
 		}
@@ -601,10 +808,6 @@ func (d *lvm) ListVolumes() ([]Volume, error) {
 			continue // Ignore VM filesystem volumes as we will just return the VM's block volume.
 		}
 
-		// Unescape raw LVM name to LXD storage volume name. Safe to do now we know we are not dealing
-		// with snapshot volumes.
-		volName = strings.Replace(volName, lvmEscapedHyphen, "-", -1)
-
 		contentType := ContentTypeFS
 		if volType == VolumeTypeCustom && strings.HasSuffix(volName, lvmISOVolSuffix) {
 			contentType = ContentTypeISO
@@ -639,11 +842,12 @@ func (d *lvm) ListVolumes() ([]Volume, error) {
 	}
 
 	err = cmd.Wait()
+	d.lvmCommandFinished("lvs", start, err)
 	if err != nil {
 		return nil, fmt.Errorf("Failed getting volume list: %v: %w", strings.TrimSpace(string(errMsg)), err)
 	}
 
-	volList := make([]Volume, len(vols))
+	volList := make([]Volume, 0, len(vols))
 	for _, v := range vols {
 		volList = append(volList, v)
 	}
@@ -677,13 +881,23 @@ func (d *lvm) MountVolume(vol Volume, op *operations.Operation) error {
 		// Check if already mounted.
 		mountPath := vol.MountPath()
 		if !filesystem.IsMountPoint(mountPath) {
-			fsType := vol.ConfigBlockFilesystem()
 			volDevPath := d.lvmDevPath(d.config["lvm.vg_name"], vol.volType, vol.contentType, vol.name)
 
-			if vol.mountFilesystemProbe {
-				fsType, err = fsProbe(volDevPath)
+			fsType, err := d.volumeFilesystem(vol, volDevPath)
+			if err != nil {
+				return err
+			}
+
+			readOnly := shared.IsTrue(vol.ExpandedConfig("lvm.mount_readonly"))
+
+			// Check the filesystem before mounting it if requested. Snapshots are mounted read-only by
+			// MountVolumeSnapshot so are never checked, nor are volumes mounted read-only for recovery as
+			// the check could modify them.
+			if shared.IsTrue(d.config["lvm.fsck_on_mount"]) && !readOnly {
+				d.logger.Debug("Checking filesystem", logger.Ctx{"dev": volDevPath, "fs": fsType})
+				err = checkFileSystem(fsType, volDevPath, lvmFsckTimeout)
 				if err != nil {
-					return fmt.Errorf("Failed probing filesystem: %w", err)
+					return err
 				}
 			}
 
@@ -693,12 +907,27 @@ func (d *lvm) MountVolume(vol Volume, op *operations.Operation) error {
 			}
 
 			mountFlags, mountOptions := filesystem.ResolveMountOptions(strings.Split(vol.ConfigBlockMountOptions(), ","))
-			err = TryMount(volDevPath, mountPath, fsType, mountFlags, mountOptions)
+			if readOnly {
+				mountFlags |= unix.MS_RDONLY
+				mountOptions = noRecoveryMountOptions(fsType, mountOptions)
+			}
+
+			err = mountBlockFilesystem(d.logger, d.mountRetryPolicy().mount, volDevPath, mountPath, fsType, mountFlags, mountOptions)
 			if err != nil {
 				return fmt.Errorf("Failed to mount LVM logical volume: %w", err)
 			}
 
-			d.logger.Debug("Mounted logical volume", logger.Ctx{"volName": vol.name, "dev": volDevPath, "path": mountPath, "options": mountOptions})
+			revert.Add(func() { _, _ = forceUnmount(mountPath) })
+
+			propagation := vol.ExpandedConfig("lvm.mount_propagation")
+			if propagation != "" {
+				err = unix.Mount("", mountPath, "", lvmMountPropagationFlags[propagation], "")
+				if err != nil {
+					return fmt.Errorf("Failed setting %q propagation on mount %q: %w", propagation, mountPath, err)
+				}
+			}
+
+			d.logger.Debug("Mounted logical volume", logger.Ctx{"volName": vol.name, "dev": volDevPath, "path": mountPath, "options": mountOptions, "propagation": propagation})
 		}
 	} else if vol.contentType == ContentTypeBlock {
 		// For VMs, mount the filesystem volume.
@@ -738,16 +967,17 @@ func (d *lvm) UnmountVolume(vol Volume, keepBlockDev bool, op *operations.Operat
 			return false, ErrInUse
 		}
 
-		err = TryUnmount(mountPath, 0)
+		lazy, err := d.mountRetryPolicy().unmountOrDetach(mountPath)
 		if err != nil {
 			return false, fmt.Errorf("Failed to unmount LVM logical volume: %w", err)
 		}
 
-		d.logger.Debug("Unmounted logical volume", logger.Ctx{"volName": vol.name, "path": mountPath, "keepBlockDev": keepBlockDev})
+		d.logger.Debug("Unmounted logical volume", logger.Ctx{"volName": vol.name, "path": mountPath, "keepBlockDev": keepBlockDev, "lazy": lazy})
 
 		// We only deactivate filesystem volumes if an unmount was needed to better align with our
-		// unmount return value indicator.
-		if !keepBlockDev {
+		// unmount return value indicator. A lazily unmounted volume is still held open so its device
+		// cannot be deactivated yet.
+		if !keepBlockDev && !lazy {
 			_, err = d.deactivateVolume(vol)
 			if err != nil {
 				return false, err
@@ -811,8 +1041,13 @@ func (d *lvm) RenameVolume(vol Volume, newVolName string, op *operations.Operati
 			revert.Add(func() { _ = d.renameLogicalVolume(newSnapVolDevPath, snapVolDevPath) })
 		}
 
-		// Rename snapshots dir if present.
+		// Rename snapshots dir if present. An empty one left behind is removed rather than renamed.
 		if vol.contentType == ContentTypeFS {
+			err = deleteParentSnapshotDirIfEmpty(d.name, vol.volType, vol.name)
+			if err != nil {
+				return err
+			}
+
 			srcSnapshotDir := GetVolumeSnapshotDir(d.name, vol.volType, vol.name)
 			dstSnapshotDir := GetVolumeSnapshotDir(d.name, vol.volType, newVolName)
 			if shared.PathExists(srcSnapshotDir) {
@@ -844,6 +1079,13 @@ func (d *lvm) RenameVolume(vol Volume, newVolName string, op *operations.Operati
 			}
 
 			revert.Add(func() { _ = os.Rename(dstVolumePath, srcVolumePath) })
+
+			// Update the filesystem label to match the new name.
+			newVol := NewVolume(d, d.name, vol.volType, vol.contentType, newVolName, vol.config, vol.poolConfig)
+			err = d.setVolumeFilesystemLabel(newVol)
+			if err != nil {
+				d.logger.Warn("Failed setting filesystem label", logger.Ctx{"dev": newVolDevPath, "err": err})
+			}
 		}
 
 		// For VMs, also rename the filesystem volume.
@@ -862,13 +1104,23 @@ func (d *lvm) RenameVolume(vol Volume, newVolName string, op *operations.Operati
 
 // MigrateVolume sends a volume for migration.
 func (d *lvm) MigrateVolume(vol VolumeCopy, conn io.ReadWriteCloser, volSrcArgs *migration.VolumeSourceArgs, op *operations.Operation) error {
-	return genericVFSMigrateVolume(d, d.state, vol, conn, volSrcArgs, op)
+	btrfsSnapshots, err := d.btrfsSnapshots(vol.Volume, op)
+	if err != nil {
+		return err
+	}
+
+	return genericVFSMigrateVolume(d, d.state, vol, conn, volSrcArgs, btrfsSnapshotsExcludePaths(btrfsSnapshots), op)
 }
 
 // BackupVolume copies a volume (and optionally its snapshots) to a specified target path.
 // This driver does not support optimized backups.
 func (d *lvm) BackupVolume(vol VolumeCopy, tarWriter *instancewriter.InstanceTarWriter, _ bool, snapshots []string, op *operations.Operation) error {
-	return genericVFSBackupVolume(d, vol, tarWriter, snapshots, op)
+	btrfsSnapshots, err := d.btrfsSnapshots(vol.Volume, op)
+	if err != nil {
+		return err
+	}
+
+	return genericVFSBackupVolume(d, vol, tarWriter, snapshots, btrfsSnapshotsExcludePaths(btrfsSnapshots), op)
 }
 
 // CreateVolumeSnapshot creates a snapshot of a volume.
@@ -877,15 +1129,22 @@ func (d *lvm) CreateVolumeSnapshot(snapVol Volume, op *operations.Operation) err
 	parentVol := NewVolume(d, d.name, snapVol.volType, snapVol.contentType, parentName, snapVol.config, snapVol.poolConfig)
 	snapPath := snapVol.MountPath()
 
-	// Create the parent directory.
-	err := createParentSnapshotDirIfMissing(d.name, snapVol.volType, parentName)
+	err := d.checkMaxSnapshots(parentVol, op)
 	if err != nil {
 		return err
 	}
 
+	// Create the parent directory.
 	revert := revert.New()
 	defer revert.Fail()
 
+	err = createParentSnapshotDirIfMissing(d.name, snapVol.volType, parentName)
+	if err != nil {
+		return err
+	}
+
+	revert.Add(func() { _ = deleteParentSnapshotDirIfEmpty(d.name, snapVol.volType, parentName) })
+
 	// Create snapshot directory.
 	err = snapVol.EnsureMountPath()
 	if err != nil {
@@ -894,9 +1153,27 @@ func (d *lvm) CreateVolumeSnapshot(snapVol Volume, op *operations.Operation) err
 
 	revert.Add(func() { _ = os.RemoveAll(snapPath) })
 
-	_, err = d.createLogicalVolumeSnapshot(d.config["lvm.vg_name"], parentVol, snapVol, true, d.usesThinpool())
+	btrfsSnapshot, err := d.usesBtrfsSnapshot(snapVol, true)
+	if err != nil {
+		return err
+	}
+
+	if btrfsSnapshot {
+		err = d.createBtrfsSnapshot(snapVol, op)
+		if err != nil {
+			return err
+		}
+
+		revert.Success()
+		return nil
+	}
+
+	err = d.freezeDuring(parentVol, func() error {
+		_, err := d.createLogicalVolumeSnapshot(d.config["lvm.vg_name"], parentVol, snapVol, true, d.usesThinpool())
+		return err
+	})
 	if err != nil {
-		return fmt.Errorf("Error creating LVM logical volume snapshot: %w", err)
+		return err
 	}
 
 	volDevPath := d.lvmDevPath(d.config["lvm.vg_name"], snapVol.volType, snapVol.contentType, snapVol.name)
@@ -911,7 +1188,7 @@ func (d *lvm) CreateVolumeSnapshot(snapVol Volume, op *operations.Operation) err
 		fsVol := snapVol.NewVMBlockFilesystemVolume()
 		_, err = d.createLogicalVolumeSnapshot(d.config["lvm.vg_name"], parentFSVol, fsVol, true, d.usesThinpool())
 		if err != nil {
-			return fmt.Errorf("Error creating LVM logical volume snapshot: %w", err)
+			return err
 		}
 	}
 
@@ -937,7 +1214,19 @@ func (d *lvm) DeleteVolumeSnapshot(snapVol Volume, op *operations.Operation) err
 
 		err = d.removeLogicalVolume(d.lvmDevPath(d.config["lvm.vg_name"], snapVol.volType, snapVol.contentType, snapVol.name))
 		if err != nil {
-			return fmt.Errorf("Error removing LVM logical volume: %w", err)
+			return err
+		}
+	} else if canUseBtrfsSnapshots(snapVol) {
+		if filesystem.IsMountPoint(snapVol.MountPath()) {
+			err = d.unmountBtrfsSnapshot(snapVol, op)
+			if err != nil {
+				return err
+			}
+		}
+
+		err = d.deleteBtrfsSnapshot(snapVol, op)
+		if err != nil {
+			return err
 		}
 	}
 
@@ -981,8 +1270,18 @@ func (d *lvm) MountVolumeSnapshot(snapVol Volume, op *operations.Operation) erro
 
 	mountPath := snapVol.MountPath()
 
+	btrfsSnapshot, err := d.usesBtrfsSnapshot(snapVol, false)
+	if err != nil {
+		return err
+	}
+
 	// Check if already mounted.
-	if snapVol.contentType == ContentTypeFS && !filesystem.IsMountPoint(mountPath) {
+	if btrfsSnapshot && !filesystem.IsMountPoint(mountPath) {
+		err = d.mountBtrfsSnapshot(snapVol, op)
+		if err != nil {
+			return err
+		}
+	} else if snapVol.contentType == ContentTypeFS && !filesystem.IsMountPoint(mountPath) {
 		err = snapVol.EnsureMountPath()
 		if err != nil {
 			return err
@@ -1007,6 +1306,12 @@ func (d *lvm) MountVolumeSnapshot(snapVol Volume, op *operations.Operation) erro
 			tmpVolName := fmt.Sprintf("%s%s", snapVol.name, tmpVolSuffix)
 			tmpVol := NewVolume(d, d.name, snapVol.volType, snapVol.contentType, tmpVolName, snapVol.config, snapVol.poolConfig)
 
+			// Remove any temporary snapshot volume left behind by a previous lazy unmount.
+			err = d.removeTemporarySnapshotVolume(snapVol)
+			if err != nil {
+				return err
+			}
+
 			// Create writable snapshot from source snapshot named with a tmpVolSuffix suffix.
 			_, err = d.createLogicalVolumeSnapshot(d.config["lvm.vg_name"], snapVol, tmpVol, false, d.usesThinpool())
 			if err != nil {
@@ -1029,8 +1334,13 @@ func (d *lvm) MountVolumeSnapshot(snapVol Volume, op *operations.Operation) erro
 			return err
 		}
 
+		fsType, err := d.volumeFilesystem(mountVol, volDevPath)
+		if err != nil {
+			return err
+		}
+
 		if regenerateFSUUID {
-			tmpVolFsType := mountVol.ConfigBlockFilesystem()
+			tmpVolFsType := fsType
 
 			// When mounting XFS filesystems temporarily we can use the nouuid option rather than fully
 			// regenerating the filesystem UUID.
@@ -1041,7 +1351,7 @@ func (d *lvm) MountVolumeSnapshot(snapVol Volume, op *operations.Operation) erro
 				}
 			} else {
 				d.logger.Debug("Regenerating filesystem UUID", logger.Ctx{"dev": volDevPath, "fs": tmpVolFsType})
-				err = regenerateFilesystemUUID(mountVol.ConfigBlockFilesystem(), volDevPath)
+				err = regenerateFilesystemUUID(tmpVolFsType, volDevPath)
 				if err != nil {
 					return err
 				}
@@ -1049,7 +1359,7 @@ func (d *lvm) MountVolumeSnapshot(snapVol Volume, op *operations.Operation) erro
 		}
 
 		// Finally attempt to mount the volume that needs mounting.
-		err = TryMount(volDevPath, mountPath, mountVol.ConfigBlockFilesystem(), mountFlags|unix.MS_RDONLY, mountOptions)
+		err = mountBlockFilesystem(d.logger, d.mountRetryPolicy().mount, volDevPath, mountPath, fsType, mountFlags|unix.MS_RDONLY, mountOptions)
 		if err != nil {
 			return fmt.Errorf("Failed to mount LVM snapshot volume: %w", err)
 		}
@@ -1092,33 +1402,49 @@ func (d *lvm) UnmountVolumeSnapshot(snapVol Volume, op *operations.Operation) (b
 
 	refCount := snapVol.MountRefCountDecrement()
 
+	btrfsSnapshot, err := d.usesBtrfsSnapshot(snapVol, false)
+	if err != nil {
+		return false, err
+	}
+
 	// Check if already mounted.
-	if snapVol.contentType == ContentTypeFS && filesystem.IsMountPoint(mountPath) {
+	if btrfsSnapshot && filesystem.IsMountPoint(mountPath) {
 		if refCount > 0 {
 			d.logger.Debug("Skipping unmount as in use", logger.Ctx{"volName": snapVol.name, "refCount": refCount})
 			return false, ErrInUse
 		}
 
-		err = TryUnmount(mountPath, 0)
+		err = d.unmountBtrfsSnapshot(snapVol, op)
 		if err != nil {
-			return false, fmt.Errorf("Failed to unmount LVM snapshot volume: %w", err)
+			return false, err
 		}
 
-		d.logger.Debug("Unmounted logical volume snapshot", logger.Ctx{"path": mountPath})
+		ourUnmount = true
+	} else if snapVol.contentType == ContentTypeFS && filesystem.IsMountPoint(mountPath) {
+		if refCount > 0 {
+			d.logger.Debug("Skipping unmount as in use", logger.Ctx{"volName": snapVol.name, "refCount": refCount})
+			return false, ErrInUse
+		}
 
-		// Check if a temporary snapshot exists, and if so remove it.
-		tmpVolName := fmt.Sprintf("%s%s", snapVol.name, tmpVolSuffix)
-		tmpVolDevPath := d.lvmDevPath(d.config["lvm.vg_name"], snapVol.volType, snapVol.contentType, tmpVolName)
-		exists, err := d.logicalVolumeExists(tmpVolDevPath)
+		lazy, err := d.mountRetryPolicy().unmountOrDetach(mountPath)
 		if err != nil {
-			return true, fmt.Errorf("Failed to check existence of temporary LVM snapshot volume %q: %w", tmpVolDevPath, err)
+			return false, fmt.Errorf("Failed to unmount LVM snapshot volume: %w", err)
 		}
 
-		if exists {
-			err = d.removeLogicalVolume(tmpVolDevPath)
-			if err != nil {
-				return true, fmt.Errorf("Failed to remove temporary LVM snapshot volume %q: %w", tmpVolDevPath, err)
-			}
+		d.logger.Debug("Unmounted logical volume snapshot", logger.Ctx{"path": mountPath, "lazy": lazy})
+
+		// A lazily unmounted snapshot is still held open, so neither the temporary snapshot volume can be
+		// removed nor the snapshot volume deactivated. Any leftover temporary snapshot volume is removed
+		// the next time the snapshot is mounted.
+		if lazy {
+			d.logger.Warn("Skipping removal of temporary LVM snapshot volume as device is still in use", logger.Ctx{"volName": snapVol.name, "path": mountPath})
+			return true, nil
+		}
+
+		// Check if a temporary snapshot exists, and if so remove it.
+		err = d.removeTemporarySnapshotVolume(snapVol)
+		if err != nil {
+			return true, err
 		}
 
 		// We only deactivate filesystem volumes if an unmount was needed to better align with our
@@ -1129,6 +1455,13 @@ func (d *lvm) UnmountVolumeSnapshot(snapVol Volume, op *operations.Operation) (b
 		}
 
 		ourUnmount = true
+	} else if snapVol.contentType == ContentTypeFS && refCount <= 0 && renegerateFilesystemUUIDNeeded(snapVol.ConfigBlockFilesystem()) {
+		// The snapshot isn't mounted (for example because mounting it never completed), so make sure the
+		// temporary writable snapshot volume isn't left behind.
+		err = d.removeTemporarySnapshotVolume(snapVol)
+		if err != nil {
+			d.logger.Warn("Failed to remove temporary LVM snapshot volume", logger.Ctx{"volName": snapVol.name, "err": err})
+		}
 	} else if snapVol.contentType == ContentTypeBlock {
 		// For VMs, unmount the filesystem volume.
 		if snapVol.IsVMBlock() {
@@ -1162,9 +1495,12 @@ func (d *lvm) UnmountVolumeSnapshot(snapVol Volume, op *operations.Operation) (b
 func (d *lvm) VolumeSnapshots(vol Volume, op *operations.Operation) ([]string, error) {
 	// We use the volume list rather than inspecting the logical volumes themselves because the origin
 	// property of an LVM snapshot can be removed/changed when restoring snapshots, such that they are no
-	// marked as origin of the parent volume. Instead we use prefix matching on the volume names to find the
-	// snapshot volumes.
-	cmd := exec.Command("lvs", "--noheadings", "-o", "lv_name", d.config["lvm.vg_name"])
+	// marked as origin of the parent volume. Instead each logical volume name is decoded back to a volume name
+	// (using lvNameToVolName in parseLogicalVolumeSnapshot) to find the snapshots of the parent volume.
+	args := []string{"--noheadings", "-o", "lv_name", d.config["lvm.vg_name"]}
+	start := d.lvmCommandStarted("lvs", args)
+	lvsPath, lvsArgs := lvmCommand("lvs", args...)
+	cmd := exec.Command(lvsPath, lvsArgs...)
 	stdout, err := cmd.StdoutPipe()
 	if err != nil {
 		return nil, err
@@ -1197,15 +1533,102 @@ func (d *lvm) VolumeSnapshots(vol Volume, op *operations.Operation) ([]string, e
 	}
 
 	err = cmd.Wait()
+	d.lvmCommandFinished("lvs", start, err)
 	if err != nil {
 		return nil, fmt.Errorf("Failed to get snapshot list for volume %q: %v: %w", vol.name, strings.TrimSpace(string(errMsg)), err)
 	}
 
+	btrfsSnapshots, err := d.btrfsSnapshots(vol, op)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, snapName := range btrfsSnapshots {
+		if !shared.ValueInSlice(snapName, snapshots) {
+			snapshots = append(snapshots, snapName)
+		}
+	}
+
 	return snapshots, nil
 }
 
+// restoreVolumeByMerge restores a volume (and its filesystem volume for VMs) by merging its snapshot back onto it.
+// The volume is unmounted for the merge and mounted again afterwards if it was mounted beforehand.
+// Returns false if merging isn't possible, in which case the volume has not been modified.
+// Returns an ErrSnapshotLost error if the volumes were restored but the snapshot could not be re-created.
+func (d *lvm) restoreVolumeByMerge(vol Volume, snapshotName string, op *operations.Operation) (bool, error) {
+	vols := []Volume{vol}
+	if vol.IsVMBlock() {
+		vols = append(vols, vol.NewVMBlockFilesystemVolume())
+	}
+
+	snapVols := make([]Volume, 0, len(vols))
+	for _, v := range vols {
+		snapVol, err := v.NewSnapshot(snapshotName)
+		if err != nil {
+			return false, err
+		}
+
+		canMerge, err := d.canMergeLogicalVolumeSnapshot(v, snapVol)
+		if err != nil || !canMerge {
+			return false, err
+		}
+
+		snapVols = append(snapVols, snapVol)
+	}
+
+	var snapLostErr error
+	for i, v := range vols {
+		ourUnmount, err := d.UnmountVolume(v, false, op)
+		if err != nil {
+			return false, fmt.Errorf("Error unmounting LVM logical volume: %w", err)
+		}
+
+		// If a VM's filesystem volume can't be merged the caller falls back to restoring both volumes, which
+		// is fine as the already merged block volume's snapshot has been re-created. If it couldn't be
+		// re-created there is nothing left to restore the block volume from, so fail instead.
+		merged, err := d.mergeLogicalVolumeSnapshot(v, snapVols[i])
+		if errors.As(err, &ErrSnapshotLost{}) {
+			snapLostErr = err
+		} else if err != nil || !merged {
+			if snapLostErr != nil {
+				return true, fmt.Errorf("Failed merging LVM logical volume snapshot %q after its block volume snapshot was lost: %v", snapVols[i].name, snapLostErr)
+			}
+
+			return merged, err
+		}
+
+		if ourUnmount {
+			err = d.MountVolume(v, op)
+			if err != nil {
+				return true, err
+			}
+		}
+	}
+
+	if snapLostErr != nil {
+		return true, snapLostErr
+	}
+
+	return true, nil
+}
+
 // RestoreVolume restores a volume from a snapshot.
 func (d *lvm) RestoreVolume(vol Volume, snapVol Volume, op *operations.Operation) error {
+	btrfsSnapshot, err := d.usesBtrfsSnapshot(snapVol, false)
+	if err != nil {
+		return err
+	}
+
+	if btrfsSnapshot {
+		return d.restoreBtrfsSnapshot(vol, snapVol, op)
+	}
+
+	err = d.checkRestoreSnapshot(vol, snapVol, d.logicalVolumeExists)
+	if err != nil {
+		return err
+	}
+
 	_, snapshotName, _ := api.GetParentAndSnapshotName(snapVol.name)
 
 	restoreThinPoolVolume := func(restoreVol Volume) (revert.Hook, error) {
@@ -1275,6 +1698,18 @@ func (d *lvm) RestoreVolume(vol Volume, snapVol Volume, op *operations.Operation
 		return cleanup, nil
 	}
 
+	// Where possible restore the snapshot by merging it back onto the volume using "lvconvert --merge". This keeps
+	// the volume's logical volume (and so its relationship with the other snapshots) and avoids copying its data.
+	// Otherwise fall back to the approaches below.
+	merged, err := d.restoreVolumeByMerge(vol, snapshotName, op)
+	if err != nil {
+		return err
+	}
+
+	if merged {
+		return nil
+	}
+
 	reverter := revert.New()
 	defer reverter.Fail()
 
@@ -1306,7 +1741,7 @@ func (d *lvm) RestoreVolume(vol Volume, snapVol Volume, op *operations.Operation
 	}
 
 	// Instantiate snapshot volume from snapshot name.
-	snapVol, err := vol.NewSnapshot(snapshotName)
+	snapVol, err = vol.NewSnapshot(snapshotName)
 	if err != nil {
 		return err
 	}
@@ -1335,7 +1770,7 @@ func (d *lvm) RestoreVolume(vol Volume, snapVol Volume, op *operations.Operation
 	// as newer snapshots are taken at using the "100%ORIGIN" size). Confusing isn't it.
 	if snapVol.IsVMBlock() || snapVol.contentType == ContentTypeFS {
 		snapLVPath := d.lvmDevPath(d.config["lvm.vg_name"], snapVol.volType, ContentTypeFS, snapVol.name)
-		_, err = shared.TryRunCommand("lvresize", "-l", "+100%ORIGIN", "-f", snapLVPath)
+		_, err = d.tryRunVolumeGroupCommand(d.config["lvm.vg_name"], "lvresize", "-l", "+100%ORIGIN", "-f", snapLVPath)
 		if err != nil {
 			return err
 		}
@@ -1343,7 +1778,7 @@ func (d *lvm) RestoreVolume(vol Volume, snapVol Volume, op *operations.Operation
 
 	if snapVol.IsVMBlock() || (snapVol.contentType == ContentTypeBlock && snapVol.volType == VolumeTypeCustom) {
 		snapLVPath := d.lvmDevPath(d.config["lvm.vg_name"], snapVol.volType, ContentTypeBlock, snapVol.name)
-		_, err = shared.TryRunCommand("lvresize", "-l", "+100%ORIGIN", "-f", snapLVPath)
+		_, err = d.tryRunVolumeGroupCommand(d.config["lvm.vg_name"], "lvresize", "-l", "+100%ORIGIN", "-f", snapLVPath)
 		if err != nil {
 			return err
 		}
@@ -1410,9 +1845,21 @@ func (d *lvm) RenameVolumeSnapshot(snapVol Volume, newSnapshotName string, op *o
 	parentName, _, _ := api.GetParentAndSnapshotName(snapVol.name)
 	newSnapVolName := GetSnapshotVolumeName(parentName, newSnapshotName)
 	newVolDevPath := d.lvmDevPath(d.config["lvm.vg_name"], snapVol.volType, snapVol.contentType, newSnapVolName)
-	err := d.renameLogicalVolume(volDevPath, newVolDevPath)
+
+	btrfsSnapshot, err := d.usesBtrfsSnapshot(snapVol, false)
+	if err != nil {
+		return err
+	}
+
+	if btrfsSnapshot {
+		newSnapVol := NewVolume(d, d.name, snapVol.volType, snapVol.contentType, newSnapVolName, snapVol.config, snapVol.poolConfig)
+		err = d.renameBtrfsSnapshot(snapVol, newSnapVol, op)
+	} else {
+		err = d.renameLogicalVolume(volDevPath, newVolDevPath)
+	}
+
 	if err != nil {
-		return fmt.Errorf("Error renaming LVM logical volume: %w", err)
+		return fmt.Errorf("Error renaming snapshot: %w", err)
 	}
 
 	oldPath := snapVol.MountPath()
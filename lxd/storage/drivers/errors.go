@@ -16,6 +16,15 @@ var ErrCannotBeShrunk = fmt.Errorf("Cannot be shrunk")
 // ErrInUse indicates operation cannot proceed as resource is in use.
 var ErrInUse = fmt.Errorf("In use")
 
+// ErrVolumeGroupNotFound indicates operation cannot proceed as the LVM volume group doesn't exist.
+var ErrVolumeGroupNotFound = fmt.Errorf("Volume group not found")
+
+// ErrThinPoolFull indicates operation cannot proceed as the thin pool has run out of data space.
+var ErrThinPoolFull = fmt.Errorf("Thin pool is full")
+
+// ErrThinPoolMetadataFull indicates operation cannot proceed as the thin pool has run out of metadata space.
+var ErrThinPoolMetadataFull = fmt.Errorf("Thin pool metadata is full")
+
 // ErrSnapshotDoesNotMatchIncrementalSource in the "Snapshot does not match incremental source" error.
 var ErrSnapshotDoesNotMatchIncrementalSource = fmt.Errorf("Snapshot does not match incremental source")
 
@@ -27,3 +36,17 @@ type ErrDeleteSnapshots struct {
 func (e ErrDeleteSnapshots) Error() string {
 	return fmt.Sprintf("More recent snapshots must be deleted: %+v", e.Snapshots)
 }
+
+// ErrSnapshotLost is a special error used to tell the backend that a volume was restored from a snapshot but the
+// snapshot itself could not be kept, so it must be deleted.
+type ErrSnapshotLost struct {
+	Err error
+}
+
+func (e ErrSnapshotLost) Error() string {
+	return fmt.Sprintf("Snapshot could not be kept after restoring from it: %v", e.Err)
+}
+
+func (e ErrSnapshotLost) Unwrap() error {
+	return e.Err
+}
@@ -147,7 +147,8 @@ func genericVFSRenameVolumeSnapshot(d Driver, snapVol Volume, newSnapshotName st
 }
 
 // genericVFSMigrateVolume is a generic MigrateVolume implementation for VFS-only drivers.
-func genericVFSMigrateVolume(d Driver, s *state.State, vol VolumeCopy, conn io.ReadWriteCloser, volSrcArgs *migration.VolumeSourceArgs, op *operations.Operation) error {
+// The excludePaths are paths relative to the root of filesystem volumes that aren't transferred.
+func genericVFSMigrateVolume(d Driver, s *state.State, vol VolumeCopy, conn io.ReadWriteCloser, volSrcArgs *migration.VolumeSourceArgs, excludePaths []string, op *operations.Operation) error {
 	bwlimit := d.Config()["rsync.bwlimit"]
 	var rsyncArgs []string
 
@@ -167,6 +168,10 @@ func genericVFSMigrateVolume(d Driver, s *state.State, vol VolumeCopy, conn io.R
 		if !shared.ValueInSlice(volSrcArgs.MigrationType.FSType, []migration.MigrationFSType{migration.MigrationFSType_RSYNC, migration.MigrationFSType_RBD_AND_RSYNC}) {
 			return ErrNotSupported
 		}
+
+		for _, excludePath := range excludePaths {
+			rsyncArgs = append(rsyncArgs, "--exclude", "/"+excludePath)
+		}
 	}
 
 	// Define function to send a filesystem volume.
@@ -220,7 +225,19 @@ func genericVFSMigrateVolume(d Driver, s *state.State, vol VolumeCopy, conn io.R
 			}
 		}
 
-		d.Logger().Debug("Sending block volume", logger.Ctx{"volName": vol.name, "path": path})
+		// Apply the same bandwidth limit as for the rsync transfers.
+		if bwlimit != "" {
+			limit, err := parseBwlimit(bwlimit)
+			if err != nil {
+				return err
+			}
+
+			if limit > 0 {
+				fromPipe = newBwlimitReader(fromPipe, limit)
+			}
+		}
+
+		d.Logger().Debug("Sending block volume", logger.Ctx{"volName": vol.name, "path": path, "bwlimit": bwlimit})
 		_, err = io.Copy(conn, fromPipe)
 		if err != nil {
 			return fmt.Errorf("Error copying %q to migration connection: %w", path, err)
@@ -331,6 +348,11 @@ func genericVFSCreateVolumeFromMigration(d Driver, initVolume func(vol Volume) (
 		return rsync.Recv(path, conn, wrapper, volTargetArgs.MigrationType.Features)
 	}
 
+	// Zero regions of the first block volume received can be skipped rather than written if the newly
+	// created volume already reads as zeroes, which keeps thin volumes from being fully allocated.
+	// Later writes (such as the main volume after its snapshots) happen over existing data so must be full.
+	blockZeroed := !volTargetArgs.Refresh && d.Info().ZeroedVolumes && (preFiller == nil || preFiller.Fill == nil)
+
 	recvBlockVol := func(volName string, conn io.ReadWriteCloser, path string) error {
 		var wrapper *ioprogress.ProgressTracker
 		if volTargetArgs.TrackProgress {
@@ -356,7 +378,19 @@ func genericVFSCreateVolumeFromMigration(d Driver, initVolume func(vol Volume) (
 		d.Logger().Debug("Receiving block volume started", logger.Ctx{"volName": volName, "path": path})
 		defer d.Logger().Debug("Receiving block volume stopped", logger.Ctx{"volName": volName, "path": path})
 
-		_, err = io.Copy(to, fromPipe)
+		fi, err := to.Stat()
+		if err != nil {
+			return fmt.Errorf("Error getting file info %q: %w", path, err)
+		}
+
+		// Regular files have just been truncated so always read as zeroes.
+		if blockZeroed || fi.Mode().IsRegular() {
+			_, err = sparseCopy(to, fromPipe)
+		} else {
+			_, err = io.Copy(to, fromPipe)
+		}
+
+		blockZeroed = false
 		if err != nil {
 			return fmt.Errorf("Error copying from migration connection to %q: %w", path, err)
 		}
@@ -500,7 +534,8 @@ func genericVFSGetVolumeDiskPath(vol Volume) (string, error) {
 }
 
 // genericVFSBackupVolume is a generic BackupVolume implementation for VFS-only drivers.
-func genericVFSBackupVolume(d Driver, vol VolumeCopy, tarWriter *instancewriter.InstanceTarWriter, snapshots []string, op *operations.Operation) error {
+// The excludePaths are paths relative to the root of filesystem volumes that aren't added to the backup.
+func genericVFSBackupVolume(d Driver, vol VolumeCopy, tarWriter *instancewriter.InstanceTarWriter, snapshots []string, excludePaths []string, op *operations.Operation) error {
 	if len(snapshots) > 0 {
 		// Check requested snapshot match those in storage.
 		err := d.CheckVolumeSnapshots(vol.Volume, vol.Snapshots, op)
@@ -544,6 +579,16 @@ func genericVFSBackupVolume(d Driver, vol VolumeCopy, tarWriter *instancewriter.
 						return fmt.Errorf("Error walking file during export: %q: %w", srcPath, err)
 					}
 
+					for _, excludePath := range excludePaths {
+						if srcPath == filepath.Join(mountPath, excludePath) {
+							if fi.IsDir() {
+								return filepath.SkipDir
+							}
+
+							return nil
+						}
+					}
+
 					name := filepath.Join(prefix, strings.TrimPrefix(srcPath, mountPath))
 
 					// Write the file to the tarball with ignoreGrowth enabled so that if the
@@ -978,7 +1023,8 @@ func genericVFSBackupUnpack(d Driver, sysOS *sys.OS, vol VolumeCopy, snapshots [
 
 // genericVFSCopyVolume copies a volume and its snapshots using a non-optimized method.
 // initVolume is run against the main volume (not the snapshots) and is often used for quota initialization.
-func genericVFSCopyVolume(d Driver, initVolume func(vol Volume) (revert.Hook, error), vol VolumeCopy, srcVol VolumeCopy, refreshSnapshots []string, refresh bool, allowInconsistent bool, op *operations.Operation) (revert.Hook, error) {
+// The excludePaths are paths relative to the root of filesystem volumes that aren't copied.
+func genericVFSCopyVolume(d Driver, initVolume func(vol Volume) (revert.Hook, error), vol VolumeCopy, srcVol VolumeCopy, refreshSnapshots []string, refresh bool, allowInconsistent bool, excludePaths []string, op *operations.Operation) (revert.Hook, error) {
 	if vol.contentType != srcVol.contentType {
 		return nil, fmt.Errorf("Content type of source and target must be the same")
 	}
@@ -991,6 +1037,16 @@ func genericVFSCopyVolume(d Driver, initVolume func(vol Volume) (revert.Hook, er
 		rsyncArgs = append(rsyncArgs, "--exclude", genericVolumeDiskFile)
 	}
 
+	if srcVol.contentType == ContentTypeFS {
+		for _, excludePath := range excludePaths {
+			rsyncArgs = append(rsyncArgs, "--exclude", "/"+excludePath)
+		}
+	}
+
+	if srcVol.volType == VolumeTypeContainer && srcVol.contentType == ContentTypeFS {
+		rsyncArgs = append(rsyncArgs, rsyncExcludeArgs(d.Config()["rsync.exclude"])...)
+	}
+
 	revert := revert.New()
 	defer revert.Fail()
 
@@ -1014,7 +1070,21 @@ func genericVFSCopyVolume(d Driver, initVolume func(vol Volume) (revert.Hook, er
 			return nil
 		}
 
-		return err
+		if err != nil {
+			return err
+		}
+
+		// Check the copy matches its source if requested. This is skipped for inconsistent copies as the
+		// source may have changed since.
+		if shared.IsTrue(d.Config()["rsync.verify"]) && !allowInconsistent {
+			d.Logger().Debug("Verifying filesystem volume copy", logger.Ctx{"sourcePath": srcPath, "targetPath": targetPath})
+			err = rsync.LocalVerify(srcPath, targetPath, true, rsyncArgs...)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
 	}
 
 	// Define function to send a block volume.
@@ -0,0 +1,28 @@
+package drivers
+
+import (
+	"time"
+)
+
+// CommandObserver is notified of the time taken by the external commands run by the storage drivers.
+type CommandObserver interface {
+	ObserveCommand(command string, duration time.Duration)
+}
+
+// commandObserver is the observer the command durations are reported to. It is nil when metrics aren't collected.
+var commandObserver CommandObserver
+
+// SetCommandObserver sets the observer that the duration of storage commands is reported to.
+// It must be called before any storage pool is loaded, passing nil disables the reporting.
+func SetCommandObserver(observer CommandObserver) {
+	commandObserver = observer
+}
+
+// observeCommand reports the time elapsed since start for the named command, if an observer is set.
+func observeCommand(command string, start time.Time) {
+	if commandObserver == nil {
+		return
+	}
+
+	commandObserver.ObserveCommand(command, time.Since(start))
+}
@@ -1,6 +1,9 @@
 package drivers
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"io/fs"
@@ -8,6 +11,7 @@ import (
 	"os/exec"
 	"path/filepath"
 	"sort"
+	"strconv"
 	"strings"
 	"time"
 
@@ -19,6 +23,7 @@ import (
 	"github.com/canonical/lxd/shared"
 	"github.com/canonical/lxd/shared/api"
 	"github.com/canonical/lxd/shared/logger"
+	"github.com/canonical/lxd/shared/units"
 )
 
 // MinBlockBoundary minimum block boundary size to use.
@@ -176,6 +181,84 @@ func TryMount(src string, dst string, fs string, flags uintptr, options string)
 	return nil
 }
 
+// tryMountBlockFilesystem mounts the filesystem of a block volume using TryMount. If the mount fails because of
+// the mount options, the options rejected by the filesystem are identified and reported in the error.
+func tryMountBlockFilesystem(l logger.Logger, devPath string, mountPath string, fsType string, flags uintptr, options string) error {
+	return mountBlockFilesystem(l, TryMount, devPath, mountPath, fsType, flags, options)
+}
+
+// mountBlockFilesystem mounts the filesystem of a block volume using the supplied mount function, reporting the
+// mount options rejected by the filesystem if the mount fails because of them.
+func mountBlockFilesystem(l logger.Logger, mount func(src string, dst string, fs string, flags uintptr, options string) error, devPath string, mountPath string, fsType string, flags uintptr, options string) error {
+	l.Debug("Mounting filesystem", logger.Ctx{"dev": devPath, "path": mountPath, "fs": fsType, "flags": flags, "options": options})
+
+	start := time.Now()
+	err := mount(devPath, mountPath, fsType, flags, options)
+	observeCommand("mount", start)
+	if err == nil || options == "" || !errors.Is(err, unix.EINVAL) {
+		return err
+	}
+
+	rejected := rejectedMountOptions(devPath, mountPath, fsType, flags, options)
+	if len(rejected) == 0 {
+		return err
+	}
+
+	return fmt.Errorf("Mount option(s) %q rejected by %q filesystem of %q (check block.mount_options): %w", strings.Join(rejected, ","), fsType, devPath, err)
+}
+
+// noRecoveryMountOptions returns the mount options with those needed to stop the filesystem from replaying its
+// journal when mounted read-only added, so that mounting it doesn't write to the device.
+func noRecoveryMountOptions(fsType string, options string) string {
+	var option string
+
+	switch fsType {
+	case "ext3", "ext4":
+		option = "noload"
+	case "xfs":
+		option = "norecovery"
+	case "btrfs":
+		option = "nologreplay"
+	default:
+		return options
+	}
+
+	if options == "" {
+		return option
+	}
+
+	return options + "," + option
+}
+
+// rejectedMountOptions returns the mount options that the filesystem refuses to be mounted with.
+// Each option is tried on its own, and none are returned if the filesystem can't be mounted without any options
+// either, as the options aren't the problem then.
+func rejectedMountOptions(devPath string, mountPath string, fsType string, flags uintptr, options string) []string {
+	tryMount := func(options string) error {
+		err := unix.Mount(devPath, mountPath, fsType, flags, options)
+		if err != nil {
+			return err
+		}
+
+		_ = unix.Unmount(mountPath, 0)
+		return nil
+	}
+
+	if tryMount("") != nil {
+		return nil
+	}
+
+	var rejected []string
+	for _, option := range strings.Split(options, ",") {
+		err := tryMount(option)
+		if errors.Is(err, unix.EINVAL) {
+			rejected = append(rejected, option)
+		}
+	}
+
+	return rejected
+}
+
 // TryUnmount tries unmounting a filesystem multiple times. This is useful for unreliable backends.
 func TryUnmount(path string, flags int) error {
 	var err error
@@ -197,6 +280,90 @@ func TryUnmount(path string, flags int) error {
 	return nil
 }
 
+// tryUnmountOrDetach tries unmounting a filesystem multiple times, and if the mount is still busy falls back to
+// a lazy unmount. Returns true if a lazy unmount was performed, in which case the mount has been detached from the
+// path but the underlying device remains in use until all remaining references to it are released.
+func tryUnmountOrDetach(path string) (bool, error) {
+	err := TryUnmount(path, 0)
+	if err == nil {
+		return false, nil
+	}
+
+	if !errors.Is(err, unix.EBUSY) {
+		return false, err
+	}
+
+	logger.Warn("Mount still busy, falling back to lazy unmount", logger.Ctx{"path": path, "err": err})
+
+	err = unix.Unmount(path, unix.MNT_DETACH)
+	if err != nil {
+		return false, fmt.Errorf("Failed to lazily unmount %q: %w", path, err)
+	}
+
+	return true, nil
+}
+
+// mountRetryPolicy bounds the retries of mount and unmount calls.
+type mountRetryPolicy struct {
+	attempts int           // Maximum number of attempts.
+	timeout  time.Duration // Maximum time spent retrying.
+}
+
+// retry calls fn until it succeeds, the attempts are exhausted or the next attempt would exceed the timeout.
+// The delay between attempts starts at 50ms and doubles after each attempt, up to 1s.
+func (p mountRetryPolicy) retry(action string, path string, fn func() error) error {
+	start := time.Now()
+	delay := 50 * time.Millisecond
+
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil || attempt >= p.attempts || time.Since(start)+delay > p.timeout {
+			return err
+		}
+
+		logger.Debug("Retrying "+action, logger.Ctx{"path": path, "attempt": attempt, "delay": delay, "err": err})
+		time.Sleep(delay)
+
+		delay = min(delay*2, time.Second)
+	}
+}
+
+// mount mounts a filesystem, retrying according to the policy.
+func (p mountRetryPolicy) mount(src string, dst string, fs string, flags uintptr, options string) error {
+	err := p.retry("mount", dst, func() error {
+		return unix.Mount(src, dst, fs, flags, options)
+	})
+	if err != nil {
+		return fmt.Errorf("Failed to mount %q on %q using %q: %w", src, dst, fs, err)
+	}
+
+	return nil
+}
+
+// unmountOrDetach unmounts a filesystem, retrying according to the policy, and falls back to a lazy unmount if
+// the mount is still busy. Returns true if a lazy unmount was performed.
+func (p mountRetryPolicy) unmountOrDetach(path string) (bool, error) {
+	err := p.retry("unmount", path, func() error {
+		return unix.Unmount(path, 0)
+	})
+	if err == nil {
+		return false, nil
+	}
+
+	if !errors.Is(err, unix.EBUSY) {
+		return false, fmt.Errorf("Failed to unmount %q: %w", path, err)
+	}
+
+	logger.Warn("Mount still busy, falling back to lazy unmount", logger.Ctx{"path": path, "err": err})
+
+	err = unix.Unmount(path, unix.MNT_DETACH)
+	if err != nil {
+		return false, fmt.Errorf("Failed to lazily unmount %q: %w", path, err)
+	}
+
+	return true, nil
+}
+
 // tryExists waits up to 10s for a file to exist.
 func tryExists(path string) bool {
 	// Attempt 20 checks over 10s
@@ -282,27 +449,67 @@ func createParentSnapshotDirIfMissing(poolName string, volType VolumeType, volNa
 	return nil
 }
 
+// removeEmptyDirs removes the directory at path and then its parent directories, stopping at the first directory
+// that isn't empty. The root directory and anything above it are never removed, and paths that aren't below root
+// are left untouched. Missing directories are skipped.
+func removeEmptyDirs(path string, root string) error {
+	root = filepath.Clean(root)
+	path = filepath.Clean(path)
+
+	for {
+		rel, err := filepath.Rel(root, path)
+		if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, "../") {
+			return nil
+		}
+
+		// Rmdir only ever removes empty directories.
+		err = unix.Rmdir(path)
+		if err != nil && !errors.Is(err, unix.ENOENT) {
+			if errors.Is(err, unix.ENOTEMPTY) || errors.Is(err, unix.EEXIST) {
+				return nil
+			}
+
+			return fmt.Errorf("Failed to remove %q: %w", path, err)
+		}
+
+		path = filepath.Dir(path)
+	}
+}
+
 // deleteParentSnapshotDirIfEmpty removes the parent snapshot directory if it is empty.
 // It accepts the pool name, volume type and parent volume name.
 func deleteParentSnapshotDirIfEmpty(poolName string, volType VolumeType, volName string) error {
-	snapshotsPath := GetVolumeSnapshotDir(poolName, volType, volName)
+	snapshotsRoot := shared.VarPath("storage-pools", poolName, fmt.Sprintf("%s-snapshots", string(volType)))
+	return removeEmptyDirs(GetVolumeSnapshotDir(poolName, volType, volName), snapshotsRoot)
+}
 
-	// If it exists, try to delete it.
-	if shared.PathExists(snapshotsPath) {
-		isEmpty, err := shared.PathIsEmpty(snapshotsPath)
-		if err != nil {
-			return err
-		}
+// updateProgress reports the stage of a long running storage operation in the storage_progress metadata of the
+// operation, for clients to show. An empty stage removes the progress once the stage is done. Nothing is reported
+// when there is no operation.
+func updateProgress(op *operations.Operation, stage string) {
+	if op == nil {
+		return
+	}
 
-		if isEmpty {
-			err := os.Remove(snapshotsPath)
-			if err != nil && !os.IsNotExist(err) {
-				return fmt.Errorf("Failed to remove '%s': %w", snapshotsPath, err)
-			}
+	meta := op.Metadata()
+	if meta == nil {
+		meta = make(map[string]any)
+	}
+
+	current, found := meta["storage_progress"]
+	if stage == "" {
+		if !found {
+			return
 		}
+
+		delete(meta, "storage_progress")
+	} else if current != stage {
+		meta["storage_progress"] = stage
+	} else {
+		return
 	}
 
-	return nil
+	_ = op.UpdateMetadata(meta)
 }
 
 // ensureSparseFile creates a sparse empty file at specified location with specified size.
@@ -388,7 +595,8 @@ func ensureVolumeBlockFile(vol Volume, path string, sizeBytes int64, allowUnsafe
 
 // mkfsOptions represents options for filesystem creation.
 type mkfsOptions struct {
-	Label string
+	Label     string
+	NoDiscard bool
 }
 
 // makeFSType creates the provided filesystem.
@@ -406,14 +614,24 @@ func makeFSType(path string, fsType string, options *mkfsOptions) (string, error
 		cmd = append(cmd, "-L", fsOptions.Label)
 	}
 
-	if fsType == "ext4" {
+	switch fsType {
+	case "ext4", "ext3":
 		cmd = append(cmd, "-E", "nodiscard,lazy_itable_init=0,lazy_journal_init=0")
+	case "ext2":
+		// ext2 has no journal.
+		cmd = append(cmd, "-E", "nodiscard,lazy_itable_init=0")
+	case "xfs", "btrfs":
+		if fsOptions.NoDiscard {
+			cmd = append(cmd, "-K")
+		}
 	}
 
 	// Always add the path to the device as the last argument for wider compatibility with versions of mkfs.
 	cmd = append(cmd, path)
 
+	start := time.Now()
 	msg, err = shared.TryRunCommand(cmd[0], cmd[1:]...)
+	observeCommand(cmd[0], start)
 	if err != nil {
 		return msg, err
 	}
@@ -427,7 +645,7 @@ func filesystemTypeCanBeShrunk(fsType string) bool {
 		fsType = DefaultFilesystem
 	}
 
-	if shared.ValueInSlice(fsType, []string{"ext4", "btrfs"}) {
+	if shared.ValueInSlice(fsType, []string{"ext2", "ext3", "ext4", "btrfs"}) {
 		return true
 	}
 
@@ -435,7 +653,7 @@ func filesystemTypeCanBeShrunk(fsType string) bool {
 }
 
 // shrinkFileSystem shrinks a filesystem if it is supported.
-// EXT4 volumes will be unmounted temporarily if needed.
+// EXT2, EXT3 and EXT4 volumes will be unmounted temporarily if needed, as they can't be shrunk online.
 // BTRFS volumes will be mounted temporarily if needed.
 // Accepts a force argument that indicates whether to skip some safety checks when resizing the volume.
 // This should only be used if the volume will be deleted on resize error.
@@ -452,7 +670,7 @@ func shrinkFileSystem(fsType string, devPath string, vol Volume, byteSize int64,
 	strSize := fmt.Sprintf("%dK", byteSize/1024)
 
 	switch fsType {
-	case "ext4":
+	case "ext2", "ext3", "ext4":
 		return vol.UnmountTask(func(op *operations.Operation) error {
 			output, err := shared.RunCommand("e2fsck", "-f", "-y", devPath)
 			if err != nil {
@@ -507,16 +725,28 @@ func shrinkFileSystem(fsType string, devPath string, vol Volume, byteSize int64,
 }
 
 // growFileSystem grows a filesystem if it is supported. The volume will be mounted temporarily if needed.
+// EXT2 volumes will be unmounted temporarily instead, as they can't be grown online.
 func growFileSystem(fsType string, devPath string, vol Volume) error {
 	if fsType == "" {
 		fsType = DefaultFilesystem
 	}
 
+	if fsType == "ext2" {
+		return vol.UnmountTask(func(op *operations.Operation) error {
+			msg, err := shared.TryRunCommand("resize2fs", devPath)
+			if err != nil {
+				return fmt.Errorf("Could not grow underlying %q filesystem for %q: %s", fsType, devPath, msg)
+			}
+
+			return nil
+		}, true, nil)
+	}
+
 	return vol.MountTask(func(mountPath string, op *operations.Operation) error {
 		var msg string
 		var err error
 		switch fsType {
-		case "ext4":
+		case "ext3", "ext4":
 			msg, err = shared.TryRunCommand("resize2fs", devPath)
 		case "xfs":
 			msg, err = shared.TryRunCommand("xfs_growfs", mountPath)
@@ -534,6 +764,47 @@ func growFileSystem(fsType string, devPath string, vol Volume) error {
 	}, nil)
 }
 
+// checkFileSystem runs a non-interactive check and repair of the filesystem on an unmounted device.
+// Filesystems that don't have a suitable check tool are skipped. An error is returned if the filesystem has errors
+// that could not be corrected, or if the check didn't finish within the timeout.
+func checkFileSystem(fsType string, devPath string, timeout time.Duration) error {
+	if fsType == "" {
+		fsType = DefaultFilesystem
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	var err error
+	var okExitCodes []int
+	switch fsType {
+	case "ext2", "ext3", "ext4":
+		_, err = shared.RunCommandContext(ctx, "e2fsck", "-p", devPath)
+		okExitCodes = []int{1, 2} // Errors were corrected (and a reboot is recommended).
+	case "xfs":
+		_, err = shared.RunCommandContext(ctx, "xfs_repair", devPath)
+		okExitCodes = []int{2} // The log needs replaying, which happens when the filesystem is mounted.
+	default:
+		return nil
+	}
+
+	if err == nil {
+		return nil
+	}
+
+	if ctx.Err() != nil {
+		return fmt.Errorf("Timed out checking %q filesystem on %q after %v", fsType, devPath, timeout)
+	}
+
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && shared.ValueInSlice(exitErr.ExitCode(), okExitCodes) {
+		logger.Warn("Corrected filesystem errors", logger.Ctx{"fs": fsType, "dev": devPath})
+		return nil
+	}
+
+	return fmt.Errorf("Filesystem %q on %q has errors that could not be corrected: %w", fsType, devPath, err)
+}
+
 // renegerateFilesystemUUIDNeeded returns true if fsType requires UUID regeneration, false if not.
 func renegerateFilesystemUUIDNeeded(fsType string) bool {
 	switch fsType {
@@ -601,6 +872,52 @@ func regenerateFilesystemXFSUUID(devPath string) error {
 	return nil
 }
 
+// filesystemLabel returns a filesystem label for the named volume. Characters other than letters, digits, "-",
+// "_" and "." are replaced with "-" and the label is truncated to the maximum length supported by fsType.
+func filesystemLabel(fsType string, volName string) string {
+	maxLength := 255
+	switch fsType {
+	case "ext2", "ext3", "ext4":
+		maxLength = 16
+	case "xfs":
+		maxLength = 12
+	}
+
+	label := []byte(volName)
+	for i, c := range label {
+		if (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9') || c == '-' || c == '_' || c == '.' {
+			continue
+		}
+
+		label[i] = '-'
+	}
+
+	if len(label) > maxLength {
+		label = label[:maxLength]
+	}
+
+	return string(label)
+}
+
+// setFilesystemLabel changes the label of the fsType filesystem on devPath.
+// The filesystem must not be mounted.
+func setFilesystemLabel(fsType string, devPath string, label string) error {
+	var err error
+
+	switch fsType {
+	case "ext2", "ext3", "ext4":
+		_, err = shared.RunCommand("e2label", devPath, label)
+	case "xfs":
+		_, err = shared.RunCommand("xfs_admin", "-L", label, devPath)
+	case "btrfs":
+		_, err = shared.RunCommand("btrfs", "filesystem", "label", devPath, label)
+	default:
+		return fmt.Errorf("Filesystem %q not supported", fsType)
+	}
+
+	return err
+}
+
 // copyDevice copies one device path to another using dd running at low priority.
 // It expects outputPath to exist already, so will not create it.
 func copyDevice(inputPath string, outputPath string) error {
@@ -881,6 +1198,146 @@ func wipeBlockHeaders(path string) error {
 	return nil
 }
 
+// zeroBlockDevice writes zeroes over the first size bytes of a block device and syncs it.
+func zeroBlockDevice(path string, size int64) error {
+	fdZero, err := os.Open("/dev/zero")
+	if err != nil {
+		return err
+	}
+
+	defer fdZero.Close()
+
+	fdDisk, err := os.OpenFile(path, os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+
+	defer fdDisk.Close()
+
+	_, err = io.CopyN(fdDisk, fdZero, size)
+	if err != nil {
+		return err
+	}
+
+	return fdDisk.Sync()
+}
+
+// checkImageRootfs checks that an unpacked container image volume mounted at mountPath contains a rootfs.
+func checkImageRootfs(mountPath string) error {
+	rootfsPath := filepath.Join(mountPath, "rootfs")
+
+	entries, err := os.ReadDir(rootfsPath)
+	if err != nil {
+		return fmt.Errorf("Failed reading image rootfs %q: %w", rootfsPath, err)
+	}
+
+	if len(entries) == 0 {
+		return fmt.Errorf("Image rootfs %q is empty", rootfsPath)
+	}
+
+	return nil
+}
+
+// parseBwlimit parses a bandwidth limit using the same syntax as rsync's --bwlimit and returns it in bytes per
+// second. As with rsync, a value without a unit is in KiB per second.
+func parseBwlimit(bwlimit string) (int64, error) {
+	_, err := strconv.ParseUint(bwlimit, 10, 64)
+	if err == nil {
+		bwlimit += "KiB"
+	}
+
+	limit, err := units.ParseByteSizeString(bwlimit)
+	if err != nil {
+		return -1, fmt.Errorf("Invalid bandwidth limit %q: %w", bwlimit, err)
+	}
+
+	return limit, nil
+}
+
+// bwlimitReader limits the average rate at which data can be read from the wrapped reader.
+type bwlimitReader struct {
+	io.ReadCloser
+
+	limit int64 // Bytes per second.
+	start time.Time
+	read  int64
+}
+
+// newBwlimitReader returns a reader that reads from r at no more than limit bytes per second on average.
+func newBwlimitReader(r io.ReadCloser, limit int64) *bwlimitReader {
+	return &bwlimitReader{ReadCloser: r, limit: limit, start: time.Now()}
+}
+
+// Read reads from the wrapped reader, sleeping as needed to keep within the limit.
+func (r *bwlimitReader) Read(p []byte) (int, error) {
+	// Avoid bursts of more than a second worth of data.
+	if int64(len(p)) > r.limit {
+		p = p[:r.limit]
+	}
+
+	n, err := r.ReadCloser.Read(p)
+	r.read += int64(n)
+
+	wait := time.Duration(float64(r.read)/float64(r.limit)*float64(time.Second)) - time.Since(r.start)
+	if wait > 0 {
+		time.Sleep(wait)
+	}
+
+	return n, err
+}
+
+// sparseCopyChunkSize is the size of the blocks compared against zeroes by sparseCopy.
+const sparseCopyChunkSize = 64 * 1024
+
+// sparseCopy copies src into dst, seeking over blocks of zeroes rather than writing them.
+// It must only be used when dst already reads as zeroes (such as a truncated file or a newly created thin volume)
+// as the skipped regions are left untouched. Returns the number of bytes copied, including the skipped ones.
+func sparseCopy(dst *os.File, src io.Reader) (int64, error) {
+	buf := make([]byte, sparseCopyChunkSize)
+	zero := make([]byte, sparseCopyChunkSize)
+
+	var copied int64
+	for {
+		n, err := io.ReadFull(src, buf)
+		if n > 0 {
+			if bytes.Equal(buf[:n], zero[:n]) {
+				_, seekErr := dst.Seek(int64(n), io.SeekCurrent)
+				if seekErr != nil {
+					return copied, seekErr
+				}
+			} else {
+				_, writeErr := dst.Write(buf[:n])
+				if writeErr != nil {
+					return copied, writeErr
+				}
+			}
+
+			copied += int64(n)
+		}
+
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			break
+		} else if err != nil {
+			return copied, err
+		}
+	}
+
+	// Seeking doesn't extend regular files, so make sure trailing zeroes are accounted for in the file size.
+	fi, err := dst.Stat()
+	if err != nil {
+		return copied, err
+	}
+
+	if fi.Mode().IsRegular() && fi.Size() < copied {
+		err = dst.Truncate(copied)
+		if err != nil {
+			return copied, err
+		}
+	}
+
+	return copied, nil
+}
+
 // IsContentBlock returns true if the content type is either block or iso.
 func IsContentBlock(contentType ContentType) bool {
 	return contentType == ContentTypeBlock || contentType == ContentTypeISO
@@ -901,3 +1358,15 @@ func roundAbove(above, val int64) int64 {
 
 	return rounded
 }
+
+// rsyncExcludeArgs returns the rsync arguments excluding the comma-separated list of paths inside a container's
+// root filesystem from a copy of its volume.
+func rsyncExcludeArgs(excludes string) []string {
+	var args []string
+	for _, path := range shared.SplitNTrimSpace(excludes, ",", -1, true) {
+		// Anchor the pattern to the root of the transfer, which is the volume's mount path.
+		args = append(args, "--exclude", filepath.Join("/rootfs", path))
+	}
+
+	return args
+}
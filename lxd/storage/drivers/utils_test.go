@@ -1,9 +1,18 @@
 package drivers
 
 import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/sys/unix"
+
+	"github.com/canonical/lxd/lxd/storage/filesystem"
 )
 
 // Test GetVolumeMountPath.
@@ -35,3 +44,216 @@ func TestGetVolumeMountPath(t *testing.T) {
 	expected = GetPoolMountPath(poolName) + "/virtual-machines/testvol"
 	assert.Equal(t, expected, path)
 }
+
+// Test tryUnmountOrDetach falls back to a lazy unmount when the mount is held open.
+func TestTryUnmountOrDetach(t *testing.T) {
+	if os.Geteuid() != 0 {
+		t.Skip("Mounting requires root")
+	}
+
+	mountPath := t.TempDir()
+	err := unix.Mount("tmpfs", mountPath, "tmpfs", 0, "")
+	if err != nil {
+		t.Skipf("Failed to mount tmpfs: %v", err)
+	}
+
+	// Hold the mount open.
+	f, err := os.Create(filepath.Join(mountPath, "held"))
+	require.NoError(t, err)
+
+	lazy, err := tryUnmountOrDetach(mountPath)
+	_ = f.Close()
+	require.NoError(t, err)
+	assert.True(t, lazy)
+	assert.False(t, filesystem.IsMountPoint(mountPath))
+
+	// A mount that isn't held is unmounted normally.
+	err = unix.Mount("tmpfs", mountPath, "tmpfs", 0, "")
+	require.NoError(t, err)
+
+	lazy, err = tryUnmountOrDetach(mountPath)
+	require.NoError(t, err)
+	assert.False(t, lazy)
+	assert.False(t, filesystem.IsMountPoint(mountPath))
+}
+
+// Test sparseCopy keeps the received file about as allocated as the source rather than its full size.
+func TestSparseCopy(t *testing.T) {
+	dir := t.TempDir()
+
+	// Create a 64MiB source file with only 1MiB of data (and a trailing hole).
+	srcPath := filepath.Join(dir, "src")
+	src, err := os.Create(srcPath)
+	require.NoError(t, err)
+	defer func() { _ = src.Close() }()
+
+	data := bytes.Repeat([]byte{0xaa}, 1024*1024)
+	_, err = src.WriteAt(data, 16*1024*1024)
+	require.NoError(t, err)
+	require.NoError(t, src.Truncate(64*1024*1024))
+
+	// Hide the source behind a reader so it is streamed the same way as from a migration connection.
+	dstPath := filepath.Join(dir, "dst")
+	dst, err := os.Create(dstPath)
+	require.NoError(t, err)
+	defer func() { _ = dst.Close() }()
+
+	n, err := sparseCopy(dst, io.MultiReader(src))
+	require.NoError(t, err)
+	assert.Equal(t, int64(64*1024*1024), n)
+
+	var srcStat, dstStat unix.Stat_t
+	require.NoError(t, unix.Stat(srcPath, &srcStat))
+	require.NoError(t, unix.Stat(dstPath, &dstStat))
+
+	assert.Equal(t, srcStat.Size, dstStat.Size)
+	assert.InDelta(t, srcStat.Blocks*512, dstStat.Blocks*512, float64(sparseCopyChunkSize))
+	assert.Less(t, dstStat.Blocks*512, int64(2*1024*1024))
+
+	// The content is preserved.
+	_, err = src.Seek(0, io.SeekStart)
+	require.NoError(t, err)
+	_, err = dst.Seek(0, io.SeekStart)
+	require.NoError(t, err)
+
+	srcContent, err := io.ReadAll(src)
+	require.NoError(t, err)
+	dstContent, err := io.ReadAll(dst)
+	require.NoError(t, err)
+	assert.True(t, bytes.Equal(srcContent, dstContent))
+}
+
+// Test checkImageRootfs rejects unpacked images without a usable rootfs.
+func TestCheckImageRootfs(t *testing.T) {
+	mountPath := t.TempDir()
+
+	// Missing rootfs.
+	assert.Error(t, checkImageRootfs(mountPath))
+
+	// Empty rootfs.
+	require.NoError(t, os.Mkdir(filepath.Join(mountPath, "rootfs"), 0755))
+	assert.Error(t, checkImageRootfs(mountPath))
+
+	// Populated rootfs.
+	require.NoError(t, os.Mkdir(filepath.Join(mountPath, "rootfs", "etc"), 0755))
+	assert.NoError(t, checkImageRootfs(mountPath))
+}
+
+// Test parseBwlimit follows rsync's --bwlimit syntax.
+func TestParseBwlimit(t *testing.T) {
+	limit, err := parseBwlimit("100")
+	require.NoError(t, err)
+	assert.Equal(t, int64(100*1024), limit)
+
+	limit, err = parseBwlimit("10MiB")
+	require.NoError(t, err)
+	assert.Equal(t, int64(10*1024*1024), limit)
+
+	_, err = parseBwlimit("fast")
+	assert.Error(t, err)
+}
+
+// Test bwlimitReader keeps reads within the limit.
+func TestBwlimitReader(t *testing.T) {
+	limit := int64(256 * 1024)
+	r := newBwlimitReader(io.NopCloser(bytes.NewReader(make([]byte, limit/2))), limit)
+
+	start := time.Now()
+	n, err := io.Copy(io.Discard, r)
+	require.NoError(t, err)
+	assert.Equal(t, limit/2, n)
+	assert.GreaterOrEqual(t, time.Since(start), 450*time.Millisecond)
+}
+
+// Test rsyncExcludeArgs anchors the paths to the container root filesystem.
+func TestRsyncExcludeArgs(t *testing.T) {
+	assert.Empty(t, rsyncExcludeArgs(""))
+	assert.Equal(t, []string{"--exclude", "/rootfs/var/cache", "--exclude", "/rootfs/tmp"}, rsyncExcludeArgs("/var/cache/, /tmp"))
+}
+
+// Test zeroBlockDevice overwrites the requested range only.
+func TestZeroBlockDevice(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "disk")
+	require.NoError(t, os.WriteFile(path, bytes.Repeat([]byte{0xff}, 8192), 0600))
+
+	require.NoError(t, zeroBlockDevice(path, 4096))
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, make([]byte, 4096), data[:4096])
+	assert.Equal(t, bytes.Repeat([]byte{0xff}, 4096), data[4096:])
+}
+
+// Test noRecoveryMountOptions adds the option stopping journal replay for the filesystems that have one.
+func TestNoRecoveryMountOptions(t *testing.T) {
+	assert.Equal(t, "discard,noload", noRecoveryMountOptions("ext4", "discard"))
+	assert.Equal(t, "norecovery", noRecoveryMountOptions("xfs", ""))
+	assert.Equal(t, "nologreplay", noRecoveryMountOptions("btrfs", ""))
+	assert.Equal(t, "discard", noRecoveryMountOptions("ext2", "discard"))
+}
+
+// Test filesystemLabel replaces unsupported characters and truncates to the filesystem's label length.
+func TestFilesystemLabel(t *testing.T) {
+	assert.Equal(t, "default_c1", filesystemLabel("ext4", "default_c1"))
+	assert.Equal(t, "my-project_web", filesystemLabel("ext4", "my-project_web"))
+	assert.Equal(t, "project_a-ve", filesystemLabel("xfs", "project_a-very-long-name"))
+	assert.Equal(t, "project_a-very-l", filesystemLabel("ext4", "project_a-very-long-name"))
+	assert.Equal(t, "project_a-very-long-name", filesystemLabel("btrfs", "project_a-very-long-name"))
+	assert.Equal(t, "c1-snap0", filesystemLabel("ext4", "c1/snap0"))
+}
+
+// Test mountRetryPolicy stops retrying once the attempts or the timeout are exhausted.
+func TestMountRetryPolicy(t *testing.T) {
+	calls := 0
+	failing := func() error {
+		calls++
+		return unix.EBUSY
+	}
+
+	err := mountRetryPolicy{attempts: 3, timeout: 10 * time.Second}.retry("unmount", "/mnt", failing)
+	assert.ErrorIs(t, err, unix.EBUSY)
+	assert.Equal(t, 3, calls)
+
+	calls = 0
+	err = mountRetryPolicy{attempts: 20, timeout: 0}.retry("unmount", "/mnt", failing)
+	assert.ErrorIs(t, err, unix.EBUSY)
+	assert.Equal(t, 1, calls)
+
+	calls = 0
+	err = mountRetryPolicy{attempts: 20, timeout: 10 * time.Second}.retry("mount", "/mnt", func() error {
+		calls++
+		if calls < 2 {
+			return unix.EAGAIN
+		}
+
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, calls)
+}
+
+// Test removeEmptyDirs removes nested empty directories up to, but not including, the root.
+func TestRemoveEmptyDirs(t *testing.T) {
+	root := filepath.Join(t.TempDir(), "containers-snapshots")
+	nested := filepath.Join(root, "c1", "nested", "snap0")
+	require.NoError(t, os.MkdirAll(nested, 0700))
+
+	// A non-empty directory stops the cleanup.
+	require.NoError(t, os.WriteFile(filepath.Join(root, "c1", "keep"), nil, 0600))
+	assert.NoError(t, removeEmptyDirs(nested, root))
+	assert.NoDirExists(t, filepath.Join(root, "c1", "nested"))
+	assert.DirExists(t, filepath.Join(root, "c1"))
+
+	// Once empty, the whole structure is removed but the root is kept.
+	require.NoError(t, os.Remove(filepath.Join(root, "c1", "keep")))
+	assert.NoError(t, removeEmptyDirs(nested, root))
+	assert.NoDirExists(t, filepath.Join(root, "c1"))
+	assert.DirExists(t, root)
+
+	// Paths outside of the root are never removed.
+	outside := filepath.Join(filepath.Dir(root), "other")
+	require.NoError(t, os.Mkdir(outside, 0700))
+	assert.NoError(t, removeEmptyDirs(outside, root))
+	assert.NoError(t, removeEmptyDirs(filepath.Join(root, "..", "other"), root))
+	assert.DirExists(t, outside)
+}
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"strings"
 
 	"github.com/canonical/lxd/lxd/locking"
 	"github.com/canonical/lxd/lxd/operations"
@@ -18,6 +19,17 @@ import (
 // tmpVolSuffix Suffix to use for any temporary volumes created by LXD.
 const tmpVolSuffix = ".lxdtmp"
 
+// IsTemporaryVolumeName returns whether the volume name uses the suffix reserved for temporary volumes created
+// by LXD. Such names cannot be used for user volumes or snapshots as they would collide with the temporary ones.
+func IsTemporaryVolumeName(volName string) bool {
+	return strings.HasSuffix(volName, tmpVolSuffix)
+}
+
+// nestedSnapshotsDir is the directory at the root of a filesystem volume holding the snapshots that are stored
+// inside the volume's own filesystem. It is left out when the volume's content is copied or backed up, as the
+// snapshots are handled separately.
+const nestedSnapshotsDir = ".lxd-snapshots"
+
 // isoVolSuffix suffix used for iso content type volumes.
 const isoVolSuffix = ".iso"
 
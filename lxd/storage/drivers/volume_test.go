@@ -110,3 +110,15 @@ func Test_Volume_ConfigSizeFromSource(t *testing.T) {
 		assert.Equal(t, test.err, err)
 	}
 }
+
+// Test IsTemporaryVolumeName reserves the names of the temporary volumes, so that a user snapshot cannot collide
+// with the writable snapshot used to mount another snapshot.
+func Test_IsTemporaryVolumeName(t *testing.T) {
+	snapVolName := GetSnapshotVolumeName("c1", "snap0")
+	tmpVolName := fmt.Sprintf("%s%s", snapVolName, tmpVolSuffix)
+
+	assert.True(t, IsTemporaryVolumeName(tmpVolName))
+	assert.True(t, IsTemporaryVolumeName(GetSnapshotVolumeName("c1", "snap0"+tmpVolSuffix)))
+	assert.False(t, IsTemporaryVolumeName(snapVolName))
+	assert.False(t, IsTemporaryVolumeName(GetSnapshotVolumeName("c1", "rw")))
+}
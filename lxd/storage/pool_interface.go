@@ -30,6 +30,12 @@ type MountInfo struct {
 	PostHooks []func(inst instance.Instance) error // Hooks to be called following a mount.
 }
 
+// VolumeDevice represents the block device backing a volume.
+type VolumeDevice struct {
+	Path    string // The resolved path of the block device.
+	Mounted bool   // Whether the volume is currently mounted.
+}
+
 // Type represents a LXD storage pool type.
 type Type interface {
 	ValidateName(name string) error
@@ -67,7 +73,7 @@ type Pool interface {
 	CreateInstanceFromBackup(srcBackup backup.Info, srcData io.ReadSeeker, op *operations.Operation) (func(instance.Instance) error, revert.Hook, error)
 	CreateInstanceFromCopy(inst instance.Instance, src instance.Instance, snapshots bool, allowInconsistent bool, op *operations.Operation) error
 	CreateInstanceFromImage(inst instance.Instance, fingerprint string, op *operations.Operation) error
-	CreateInstanceFromMigration(inst instance.Instance, conn io.ReadWriteCloser, args migration.VolumeTargetArgs, op *operations.Operation) error
+	CreateInstanceFromMigration(inst instance.Instance, conn io.ReadWriteCloser, args migration.VolumeTargetArgs, op *operations.Operation) (*migration.VolumeStats, error)
 	RenameInstance(inst instance.Instance, newName string, op *operations.Operation) error
 	DeleteInstance(inst instance.Instance, op *operations.Operation) error
 	UpdateInstance(inst instance.Instance, newDesc string, newConfig map[string]string, op *operations.Operation) error
@@ -77,11 +83,12 @@ type Pool interface {
 	ImportInstance(inst instance.Instance, poolVol *backupConfig.Config, op *operations.Operation) (revert.Hook, error)
 	CleanupInstancePaths(inst instance.Instance, op *operations.Operation) error
 
-	MigrateInstance(inst instance.Instance, conn io.ReadWriteCloser, args *migration.VolumeSourceArgs, op *operations.Operation) error
+	MigrateInstance(inst instance.Instance, conn io.ReadWriteCloser, args *migration.VolumeSourceArgs, op *operations.Operation) (*migration.VolumeStats, error)
 	RefreshInstance(inst instance.Instance, src instance.Instance, srcSnapshots []instance.Instance, allowInconsistent bool, op *operations.Operation) error
 	BackupInstance(inst instance.Instance, tarWriter *instancewriter.InstanceTarWriter, optimized bool, snapshots bool, op *operations.Operation) error
 
 	GetInstanceUsage(inst instance.Instance) (*VolumeUsage, error)
+	GetInstanceDevice(inst instance.Instance) (*VolumeDevice, error)
 	SetInstanceQuota(inst instance.Instance, size string, vmStateSize string, op *operations.Operation) error
 
 	MountInstance(inst instance.Instance, op *operations.Operation) (*MountInfo, error)
@@ -136,8 +143,8 @@ type Pool interface {
 
 	// Custom volume migration.
 	MigrationTypes(contentType drivers.ContentType, refresh bool, copySnapshots bool) []migration.Type
-	CreateCustomVolumeFromMigration(projectName string, conn io.ReadWriteCloser, args migration.VolumeTargetArgs, op *operations.Operation) error
-	MigrateCustomVolume(projectName string, conn io.ReadWriteCloser, args *migration.VolumeSourceArgs, op *operations.Operation) error
+	CreateCustomVolumeFromMigration(projectName string, conn io.ReadWriteCloser, args migration.VolumeTargetArgs, op *operations.Operation) (*migration.VolumeStats, error)
+	MigrateCustomVolume(projectName string, conn io.ReadWriteCloser, args *migration.VolumeSourceArgs, op *operations.Operation) (*migration.VolumeStats, error)
 
 	// Custom volume backups.
 	BackupCustomVolume(projectName string, volName string, tarWriter *instancewriter.InstanceTarWriter, optimized bool, snapshots bool, op *operations.Operation) error
@@ -145,4 +152,5 @@ type Pool interface {
 
 	// Storage volume recovery.
 	ListUnknownVolumes(op *operations.Operation) (map[string][]*backupConfig.Config, error)
+	CheckVolumeConsistency() (*VolumeConsistencyReport, error)
 }
@@ -201,12 +201,20 @@ func LoadByName(s *state.State, name string) (Pool, error) {
 }
 
 // LoadByInstance retrieves the pool from the database using the instance's pool.
+// If the instance's pool can't be determined from its root disk device then the pool its volume is on is used.
 // If the pool's driver is not recognised then drivers.ErrUnknownDriver is returned. If the pool's
 // driver does not support the instance's type then drivers.ErrNotSupported is returned.
 func LoadByInstance(s *state.State, inst instance.Instance) (Pool, error) {
 	poolName, err := inst.StoragePool()
 	if err != nil {
-		return nil, fmt.Errorf("Failed getting instance storage pool name: %w", err)
+		// Fall back to the pool the instance's volume is on in case its root disk device is missing or invalid.
+		var symlinkErr error
+		poolName, symlinkErr = InstancePoolFromSymlink(inst.Type(), inst.Project().Name, inst.Name())
+		if symlinkErr != nil {
+			return nil, fmt.Errorf("Failed getting instance storage pool name: %w (from instance symlink: %w)", err, symlinkErr)
+		}
+
+		logger.Warn("Using storage pool of instance symlink as instance storage pool name couldn't be determined", logger.Ctx{"project": inst.Project().Name, "instance": inst.Name(), "pool": poolName, "err": err})
 	}
 
 	pool, err := LoadByName(s, poolName)
@@ -4,7 +4,9 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"path/filepath"
 	"sort"
+	"strings"
 
 	"github.com/canonical/lxd/lxd/db"
 	"github.com/canonical/lxd/lxd/db/cluster"
@@ -49,6 +51,29 @@ func InstanceImportingFilePath(instanceType instancetype.Type, poolName, project
 	return shared.VarPath("storage-pools", poolName, typeDir, fullName, ".importing")
 }
 
+// InstancePoolFromSymlink returns the name of the storage pool an instance's volume is on, based on the target
+// of the instance's symlink rather than on the database. This allows finding out which pool (and so which storage
+// driver) backs an instance even if its database records are missing or inconsistent. For snapshots the symlink
+// of the parent instance is used.
+func InstancePoolFromSymlink(instanceType instancetype.Type, projectName, instanceName string) (string, error) {
+	parentName, _, _ := api.GetParentAndSnapshotName(instanceName)
+	symlinkPath := InstancePath(instanceType, projectName, parentName, false)
+
+	target, err := os.Readlink(symlinkPath)
+	if err != nil {
+		return "", fmt.Errorf("Failed reading instance symlink %q: %w", symlinkPath, err)
+	}
+
+	relPath, err := filepath.Rel(shared.VarPath("storage-pools"), target)
+	if err != nil || relPath == "." || strings.HasPrefix(relPath, "..") {
+		return "", fmt.Errorf("Instance symlink %q target %q is not a storage pool volume", symlinkPath, target)
+	}
+
+	poolName, _, _ := strings.Cut(relPath, string(filepath.Separator))
+
+	return poolName, nil
+}
+
 // GetStoragePoolMountPoint returns the mountpoint of the given pool.
 // {LXD_DIR}/storage-pools/<pool>
 // Deprecated, use GetPoolMountPath in storage/drivers package.
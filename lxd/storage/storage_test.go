@@ -0,0 +1,50 @@
+package storage
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/canonical/lxd/lxd/instance/instancetype"
+)
+
+// Test that InstancePoolFromSymlink returns the pool the instance symlink points into.
+func TestInstancePoolFromSymlink(t *testing.T) {
+	lxdDir := t.TempDir()
+	t.Setenv("LXD_DIR", lxdDir)
+
+	for _, dir := range []string{"containers", "virtual-machines"} {
+		require.NoError(t, os.Mkdir(filepath.Join(lxdDir, dir), 0711))
+	}
+
+	require.NoError(t, os.Symlink(filepath.Join(lxdDir, "storage-pools", "default", "containers", "c1"), InstancePath(instancetype.Container, "default", "c1", false)))
+	require.NoError(t, os.Symlink(filepath.Join(lxdDir, "storage-pools", "fast", "virtual-machines", "foo_v1"), InstancePath(instancetype.VM, "foo", "v1", false)))
+	require.NoError(t, os.Symlink("/srv/containers/c2", InstancePath(instancetype.Container, "default", "c2", false)))
+	require.NoError(t, os.Symlink(filepath.Join(lxdDir, "storage-pools"), InstancePath(instancetype.Container, "default", "c3", false)))
+
+	poolName, err := InstancePoolFromSymlink(instancetype.Container, "default", "c1")
+	require.NoError(t, err)
+	assert.Equal(t, "default", poolName)
+
+	// Snapshots use the symlink of their parent instance.
+	poolName, err = InstancePoolFromSymlink(instancetype.Container, "default", "c1/snap0")
+	require.NoError(t, err)
+	assert.Equal(t, "default", poolName)
+
+	poolName, err = InstancePoolFromSymlink(instancetype.VM, "foo", "v1")
+	require.NoError(t, err)
+	assert.Equal(t, "fast", poolName)
+
+	// Symlinks pointing outside of the storage pools are rejected.
+	_, err = InstancePoolFromSymlink(instancetype.Container, "default", "c2")
+	assert.ErrorContains(t, err, "is not a storage pool volume")
+
+	_, err = InstancePoolFromSymlink(instancetype.Container, "default", "c3")
+	assert.ErrorContains(t, err, "is not a storage pool volume")
+
+	_, err = InstancePoolFromSymlink(instancetype.Container, "default", "missing")
+	assert.ErrorContains(t, err, "Failed reading instance symlink")
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 
@@ -30,6 +31,7 @@ import (
 	"github.com/canonical/lxd/shared/api"
 	"github.com/canonical/lxd/shared/ioprogress"
 	"github.com/canonical/lxd/shared/logger"
+	"github.com/canonical/lxd/shared/units"
 	"github.com/canonical/lxd/shared/validate"
 )
 
@@ -600,7 +602,7 @@ func validatePoolCommonRules() map[string]func(string) error {
 		"volatile.initial_source": validate.IsAny,
 		// lxdmeta:generate(entities=storage-dir,storage-lvm,storage-powerflex; group=pool-conf; key=rsync.bwlimit)
 		// When `rsync` must be used to transfer storage entities, this option specifies the upper limit
-		// to be placed on the socket I/O.
+		// to be placed on the socket I/O. The limit also applies to block volumes sent during migration.
 		// ---
 		//  type: string
 		//  defaultdesc: `0` (no limit)
@@ -613,6 +615,26 @@ func validatePoolCommonRules() map[string]func(string) error {
 		//  defaultdesc: `true`
 		//  shortdesc: Whether to use compression while migrating storage pools
 		"rsync.compression": validate.Optional(validate.IsBool),
+		// lxdmeta:generate(entities=storage-dir,storage-lvm,storage-powerflex; group=pool-conf; key=rsync.exclude)
+		// When `rsync` is used to copy containers within the pool, this option specifies a comma-separated list
+		// of absolute paths inside the container root filesystem that are not copied, for example `/var/cache`.
+		// It doesn't apply to copies done by other means, such as snapshots of the source volume.
+		// ---
+		//  type: string
+		//  defaultdesc: empty (everything is copied)
+		//  shortdesc: Paths of the container root filesystem not copied by `rsync`
+		"rsync.exclude": validate.Optional(validate.IsListOf(validate.IsAbsFilePath)),
+		// lxdmeta:generate(entities=storage-dir,storage-lvm,storage-powerflex; group=pool-conf; key=rsync.verify)
+		// When `rsync` is used to copy volumes within the pool, this option enables comparing the checksums of
+		// the files of the copy with those of the source once the copy is done. The copy fails if they differ,
+		// for example when files were truncated because the pool ran out of space.
+		// This reads all of the data of both volumes again, so it makes copies noticeably slower.
+		// Copies of running instances that are allowed to be inconsistent aren't verified.
+		// ---
+		//  type: bool
+		//  defaultdesc: `false`
+		//  shortdesc: Whether to verify the content of volumes copied by `rsync`
+		"rsync.verify": validate.Optional(validate.IsBool),
 	}
 
 	// Add to pool config rules (prefixed with volume.*) which are common for pool and volume.
@@ -677,8 +699,14 @@ func ImageUnpack(imageFile string, vol drivers.Volume, destBlockFile string, sys
 	if destBlockFile == "" {
 		rootfsPath := filepath.Join(destPath, "rootfs")
 
+		// Make sure a block backed volume is large enough for the image before unpacking it.
+		unpackSize, err := ensureImageUnpackSize(vol, imageFile, imageRootfsFile, sysOS, allowUnsafeResize)
+		if err != nil {
+			return -1, err
+		}
+
 		// Unpack the main image file.
-		err := archive.Unpack(imageFile, destPath, vol.IsBlockBacked(), sysOS, tracker)
+		err = archive.Unpack(imageFile, destPath, vol.IsBlockBacked(), sysOS, tracker)
 		if err != nil {
 			return -1, err
 		}
@@ -702,7 +730,7 @@ func ImageUnpack(imageFile string, vol drivers.Volume, destBlockFile string, sys
 		}
 
 		// Done with this.
-		return 0, nil
+		return unpackSize, nil
 	}
 
 	// If a rootBlockPath is supplied then this is a VM image unpack.
@@ -869,6 +897,98 @@ func ImageUnpack(imageFile string, vol drivers.Volume, destBlockFile string, sys
 	return imgSize, nil
 }
 
+// imageUnpackHeadroomPercent is the extra space, as a percentage of the estimated unpacked size of a container
+// image, that the volume it is unpacked to must have to account for the filesystem overhead.
+const imageUnpackHeadroomPercent = 20
+
+// ensureImageUnpackSize grows a block backed filesystem volume if it is too small for the container image to be
+// unpacked into it. The volume must be mounted. It returns the size of the image content, which is recorded as the
+// minimum size of volumes created from the image, or 0 if the volume isn't block backed or the size couldn't be
+// determined, in which case the image is unpacked into the volume as is.
+// The space needed in the volume, which is only used to decide how much to grow it, adds some headroom to the
+// content size, as each file takes whole filesystem blocks and the filesystem has its own overhead.
+// The content size recorded in the volume's volatile.rootfs.size when the image was previously unpacked is used
+// if set, as working it out means reading through the whole image.
+func ensureImageUnpackSize(vol drivers.Volume, imageFile string, imageRootfsFile string, sysOS *sys.OS, allowUnsafeResize bool) (int64, error) {
+	if !vol.IsBlockBacked() {
+		return 0, nil
+	}
+
+	l := logger.AddContext(logger.Ctx{"imageFile": imageFile, "vol": vol.Name()})
+
+	var contentSize, diskSize int64
+	if vol.Config()["volatile.rootfs.size"] != "" {
+		var err error
+		contentSize, err = strconv.ParseInt(vol.Config()["volatile.rootfs.size"], 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("Invalid recorded image size %q: %w", vol.Config()["volatile.rootfs.size"], err)
+		}
+
+		// The space taken by the files isn't recorded, so only the headroom allows for it.
+		diskSize = contentSize
+	} else {
+		for _, file := range []string{imageFile, imageRootfsFile} {
+			if !shared.PathExists(file) {
+				continue
+			}
+
+			fileContentSize, fileDiskSize, err := archive.UnpackedSize(context.TODO(), file, sysOS, vol.MountPath())
+			if err != nil {
+				l.Warn("Failed estimating image unpack size, using the volume size", logger.Ctx{"file": file, "err": err})
+				return 0, nil
+			}
+
+			contentSize += fileContentSize
+			diskSize += fileDiskSize
+		}
+	}
+
+	neededSize := diskSize + diskSize*imageUnpackHeadroomPercent/100
+
+	// Check whether the image is allowed to be unpacked into the volume, and what size the volume should be.
+	imgVolConfig := map[string]string{
+		"volatile.rootfs.size": fmt.Sprintf("%d", contentSize),
+	}
+
+	imgVol := drivers.NewVolume(nil, "", drivers.VolumeTypeImage, drivers.ContentTypeFS, "", imgVolConfig, nil)
+
+	newVolSize, err := vol.ConfigSizeFromSource(imgVol)
+	if err != nil {
+		return 0, err
+	}
+
+	var stat unix.Statfs_t
+	err = unix.Statfs(vol.MountPath(), &stat)
+	if err != nil {
+		return 0, fmt.Errorf("Failed getting filesystem size of %q: %w", vol.MountPath(), err)
+	}
+
+	fsSizeBytes := int64(stat.Blocks) * stat.Bsize
+	if fsSizeBytes < neededSize {
+		// Grow the volume to the larger of its size and the space needed.
+		var newVolSizeBytes int64
+		if newVolSize != "" {
+			newVolSizeBytes, err = units.ParseByteSizeString(newVolSize)
+			if err != nil {
+				return 0, err
+			}
+		}
+
+		if newVolSizeBytes < neededSize {
+			newVolSize = fmt.Sprintf("%d", neededSize)
+		}
+
+		l.Debug("Increasing volume size for image unpack", logger.Ctx{"fsSize": fsSizeBytes, "contentSize": contentSize, "neededSize": neededSize, "newSize": newVolSize})
+
+		err = vol.SetQuota(newVolSize, allowUnsafeResize, nil)
+		if err != nil {
+			return 0, fmt.Errorf("Error increasing volume size: %w", err)
+		}
+	}
+
+	return contentSize, nil
+}
+
 // InstanceContentType returns the instance's content type.
 func InstanceContentType(inst instance.Instance) drivers.ContentType {
 	contentType := drivers.ContentTypeFS
@@ -1260,5 +1380,9 @@ func ValidVolumeName(volumeName string) error {
 		return fmt.Errorf("Invalid volume name %q: Cannot contain slashes", volumeName)
 	}
 
+	if drivers.IsTemporaryVolumeName(volumeName) {
+		return fmt.Errorf("Invalid volume name %q: Reserved for temporary volumes", volumeName)
+	}
+
 	return nil
 }
@@ -0,0 +1,67 @@
+package storage
+
+import (
+	"archive/tar"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/canonical/lxd/lxd/state"
+	"github.com/canonical/lxd/lxd/storage/drivers"
+	"github.com/canonical/lxd/lxd/sys"
+)
+
+// Test that ensureImageUnpackSize returns the image content size and checks it against the volume size.
+func TestEnsureImageUnpackSize(t *testing.T) {
+	t.Setenv("LXD_DIR", t.TempDir())
+
+	imageFile := filepath.Join(t.TempDir(), "image.tar")
+	f, err := os.Create(imageFile)
+	require.NoError(t, err)
+
+	tw := tar.NewWriter(f)
+	require.NoError(t, tw.WriteHeader(&tar.Header{Name: "rootfs/", Typeflag: tar.TypeDir, Mode: 0755}))
+	require.NoError(t, tw.WriteHeader(&tar.Header{Name: "rootfs/file", Typeflag: tar.TypeReg, Mode: 0644, Size: 10}))
+	_, err = tw.Write([]byte("0123456789"))
+	require.NoError(t, err)
+	require.NoError(t, tw.Close())
+	require.NoError(t, f.Close())
+
+	driver, err := drivers.Load(&state.State{OS: &sys.OS{MockMode: true}}, "mock", "pool", nil, nil, nil, nil)
+	require.NoError(t, err)
+
+	newVol := func(size string) drivers.Volume {
+		vol := drivers.NewVolume(driver, "pool", drivers.VolumeTypeContainer, drivers.ContentTypeFS, "c1", map[string]string{"size": size}, nil)
+		require.NoError(t, os.MkdirAll(vol.MountPath(), 0711))
+		return vol
+	}
+
+	// Volumes that aren't block backed are left as is.
+	size, err := ensureImageUnpackSize(newVol(""), imageFile, "", nil, false)
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), size)
+
+	// The content size is returned rather than the estimated space needed to unpack the image.
+	vol := newVol("")
+	vol.SetMountFilesystemProbe(true)
+	size, err = ensureImageUnpackSize(vol, imageFile, "", nil, false)
+	require.NoError(t, err)
+	assert.Equal(t, int64(10), size)
+
+	// The content size recorded by a previous unpack is used without reading the image.
+	vol = drivers.NewVolume(driver, "pool", drivers.VolumeTypeImage, drivers.ContentTypeFS, "img", map[string]string{"volatile.rootfs.size": "1000"}, nil)
+	require.NoError(t, os.MkdirAll(vol.MountPath(), 0711))
+	vol.SetMountFilesystemProbe(true)
+	size, err = ensureImageUnpackSize(vol, filepath.Join(t.TempDir(), "missing"), "", nil, false)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1000), size)
+
+	// A specified volume size smaller than the image content is rejected.
+	vol = newVol("5B")
+	vol.SetMountFilesystemProbe(true)
+	_, err = ensureImageUnpackSize(vol, imageFile, "", nil, false)
+	assert.ErrorContains(t, err, "exceeds specified volume size")
+}
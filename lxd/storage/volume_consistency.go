@@ -0,0 +1,74 @@
+package storage
+
+import (
+	"sort"
+
+	"github.com/canonical/lxd/lxd/storage/drivers"
+)
+
+// VolumeReference identifies a storage volume in a pool.
+type VolumeReference struct {
+	Project string
+	Type    drivers.VolumeType
+	Name    string
+}
+
+// VolumeConsistencyReport lists the differences between the volumes recorded in the database and the volumes
+// that exist on a storage pool.
+type VolumeConsistencyReport struct {
+	// MissingOnPool are the volumes recorded in the database that don't exist on the storage pool.
+	MissingOnPool []VolumeReference
+
+	// MissingInDatabase are the volumes that exist on the storage pool but aren't recorded in the database.
+	MissingInDatabase []VolumeReference
+}
+
+// Consistent returns true if no differences were found.
+func (r *VolumeConsistencyReport) Consistent() bool {
+	return len(r.MissingOnPool) == 0 && len(r.MissingInDatabase) == 0
+}
+
+// compareVolumes returns the volumes that are only in one of the database and pool volume lists.
+func compareVolumes(dbVols []VolumeReference, poolVols []VolumeReference) *VolumeConsistencyReport {
+	report := &VolumeConsistencyReport{}
+
+	onPool := make(map[VolumeReference]bool, len(poolVols))
+	for _, vol := range poolVols {
+		onPool[vol] = true
+	}
+
+	inDatabase := make(map[VolumeReference]bool, len(dbVols))
+	for _, vol := range dbVols {
+		inDatabase[vol] = true
+
+		if !onPool[vol] {
+			report.MissingOnPool = append(report.MissingOnPool, vol)
+		}
+	}
+
+	for _, vol := range poolVols {
+		if !inDatabase[vol] {
+			report.MissingInDatabase = append(report.MissingInDatabase, vol)
+		}
+	}
+
+	sortVolumeReferences(report.MissingOnPool)
+	sortVolumeReferences(report.MissingInDatabase)
+
+	return report
+}
+
+// sortVolumeReferences sorts the volumes by project, type and name.
+func sortVolumeReferences(vols []VolumeReference) {
+	sort.Slice(vols, func(i, j int) bool {
+		if vols[i].Project != vols[j].Project {
+			return vols[i].Project < vols[j].Project
+		}
+
+		if vols[i].Type != vols[j].Type {
+			return vols[i].Type < vols[j].Type
+		}
+
+		return vols[i].Name < vols[j].Name
+	})
+}
@@ -0,0 +1,28 @@
+package storage
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/canonical/lxd/lxd/storage/drivers"
+)
+
+// Test that compareVolumes reports the volumes only found in the database or on the pool, sorted.
+func TestCompareVolumes(t *testing.T) {
+	c1 := VolumeReference{Project: "default", Type: drivers.VolumeTypeContainer, Name: "c1"}
+	c2 := VolumeReference{Project: "default", Type: drivers.VolumeTypeContainer, Name: "c2"}
+	vm1 := VolumeReference{Project: "default", Type: drivers.VolumeTypeVM, Name: "vm1"}
+	data := VolumeReference{Project: "foo", Type: drivers.VolumeTypeCustom, Name: "data"}
+	c1Foo := VolumeReference{Project: "foo", Type: drivers.VolumeTypeContainer, Name: "c1"}
+
+	report := compareVolumes([]VolumeReference{vm1, c2, c1, data}, []VolumeReference{c1, data, c1Foo})
+	assert.False(t, report.Consistent())
+	assert.Equal(t, []VolumeReference{c2, vm1}, report.MissingOnPool)
+	assert.Equal(t, []VolumeReference{c1Foo}, report.MissingInDatabase)
+
+	report = compareVolumes([]VolumeReference{c1, data}, []VolumeReference{data, c1})
+	assert.True(t, report.Consistent())
+	assert.Empty(t, report.MissingOnPool)
+	assert.Empty(t, report.MissingInDatabase)
+}
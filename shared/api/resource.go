@@ -783,6 +783,38 @@ type ResourcesStoragePool struct {
 
 	// DIsk inode usage
 	Inodes ResourcesStoragePoolInodes `json:"inodes,omitempty" yaml:"inodes,omitempty"`
+
+	// LVM volume group and thin pool usage (only set for LVM pools)
+	//
+	// API extension: resources_storage_lvm
+	LVM *ResourcesStoragePoolLVM `json:"lvm,omitempty" yaml:"lvm,omitempty"`
+}
+
+// ResourcesStoragePoolLVM represents the usage of the volume group and thin pool of an LVM storage pool
+//
+// swagger:model
+//
+// API extension: resources_storage_lvm.
+type ResourcesStoragePoolLVM struct {
+	// Total size of the volume group (bytes)
+	// Example: 420100937728
+	VolumeGroupTotal uint64 `json:"volume_group_total" yaml:"volume_group_total"`
+
+	// Free space in the volume group (bytes)
+	// Example: 76563517952
+	VolumeGroupFree uint64 `json:"volume_group_free" yaml:"volume_group_free"`
+
+	// Number of logical volumes in the volume group
+	// Example: 12
+	LogicalVolumes uint64 `json:"logical_volumes" yaml:"logical_volumes"`
+
+	// Used thin pool data space (percentage, only set when the pool uses a thin pool)
+	// Example: 42.5
+	ThinPoolDataUsage float64 `json:"thin_pool_data_usage,omitempty" yaml:"thin_pool_data_usage,omitempty"`
+
+	// Used thin pool metadata space (percentage, only set when the pool uses a thin pool)
+	// Example: 12.8
+	ThinPoolMetadataUsage float64 `json:"thin_pool_metadata_usage,omitempty" yaml:"thin_pool_metadata_usage,omitempty"`
 }
 
 // ResourcesStoragePoolSpace represents the space available to a given storage pool
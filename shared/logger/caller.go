@@ -0,0 +1,57 @@
+package logger
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// loggerPackage is the package path of this package, used to skip its frames when looking for the caller.
+var loggerPackage = callerPackage()
+
+// callerPackage returns the package path of the function calling it.
+func callerPackage() string {
+	pc, _, _, _ := runtime.Caller(1)
+
+	return packageName(runtime.FuncForPC(pc).Name())
+}
+
+// packageName returns the package path of a fully qualified function name.
+func packageName(function string) string {
+	// Strip the function and receiver names, which come after the last slash of the package path.
+	lastSlash := strings.LastIndex(function, "/")
+	firstPeriod := strings.Index(function[lastSlash+1:], ".")
+	if firstPeriod < 0 {
+		return function
+	}
+
+	return function[:lastSlash+1+firstPeriod]
+}
+
+// caller returns the file and line of the first frame outside of this package.
+// Frames from the package tests are not skipped so that the callers they log from are reported.
+func caller() string {
+	pcs := make([]uintptr, 16)
+	n := runtime.Callers(2, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+
+	for {
+		frame, more := frames.Next()
+
+		if packageName(frame.Function) != loggerPackage || strings.HasSuffix(frame.File, "_test.go") {
+			return fmt.Sprintf("%s:%d", frame.File, frame.Line)
+		}
+
+		if !more {
+			return ""
+		}
+	}
+}
+
+// callerFields returns the context to add to an entry so that it records its caller.
+// The "caller" key is used rather than the logrus "file" key as the latter is commonly used in the context.
+func callerFields() logrus.Fields {
+	return logrus.Fields{"caller": caller()}
+}
@@ -0,0 +1,40 @@
+package logger
+
+import (
+	"context"
+)
+
+// ContextKey is the type used for the context.Context values added to the log entries by WithContext.
+type ContextKey string
+
+// Context keys, the values of which are added to the entries under the key name.
+const (
+	// ContextKeyRequestID is the ID of the API request the work is done for.
+	ContextKeyRequestID ContextKey = "requestID"
+
+	// ContextKeyOperation is the ID of the operation the work is done for.
+	// It correlates the entries of an operation spanning several subsystems, such as storage and migration.
+	ContextKeyOperation ContextKey = "operation"
+)
+
+// contextKeys are the keys extracted from a context.Context by WithContext.
+var contextKeys = []ContextKey{ContextKeyRequestID, ContextKeyOperation}
+
+// contextFields returns the values of the known keys set in the context.
+func contextFields(ctx context.Context) Ctx {
+	fields := Ctx{}
+	for _, key := range contextKeys {
+		value := ctx.Value(key)
+		if value != nil {
+			fields[string(key)] = value
+		}
+	}
+
+	return fields
+}
+
+// FromContext returns the global logger with the values of the known keys set in the context added to the
+// context of the entries.
+func FromContext(ctx context.Context) Logger {
+	return Log.WithContext(ctx)
+}
@@ -0,0 +1,75 @@
+package logger
+
+import (
+	"slices"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+// hookSet is a logrus hook dispatching the entries to the hooks registered at runtime.
+// It is safe to add and remove hooks while entries are logged. The hooks are fired without holding the lock, so
+// they may themselves add or remove hooks, and a hook removed while an entry is being logged may still receive it.
+type hookSet struct {
+	mu    sync.RWMutex
+	hooks []logrus.Hook
+}
+
+// Levels returns all levels as the entries are filtered by each hook in Fire.
+func (s *hookSet) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+// Fire passes the entry to each registered hook handling its level.
+func (s *hookSet) Fire(entry *logrus.Entry) error {
+	s.mu.RLock()
+	hooks := s.hooks
+	s.mu.RUnlock()
+
+	var firstErr error
+	for _, hook := range hooks {
+		if !slices.Contains(hook.Levels(), entry.Level) {
+			continue
+		}
+
+		err := hook.Fire(entry)
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	return firstErr
+}
+
+// add registers the hook.
+func (s *hookSet) add(hook logrus.Hook) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Copy the slice so that entries being fired keep the previous one.
+	s.hooks = append(slices.Clone(s.hooks), hook)
+}
+
+// remove unregisters the hook, if registered.
+func (s *hookSet) remove(hook logrus.Hook) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.hooks = slices.DeleteFunc(slices.Clone(s.hooks), func(h logrus.Hook) bool {
+		return h == hook
+	})
+}
+
+// Flush flushes the registered hooks implementing Flusher.
+func (s *hookSet) Flush() {
+	s.mu.RLock()
+	hooks := s.hooks
+	s.mu.RUnlock()
+
+	for _, hook := range hooks {
+		flusher, ok := hook.(Flusher)
+		if ok {
+			flusher.Flush()
+		}
+	}
+}
@@ -1,11 +1,14 @@
 package logger
 
 import (
+	"fmt"
 	"io"
 	"os"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/sirupsen/logrus"
-	lWriter "github.com/sirupsen/logrus/hooks/writer"
 
 	"github.com/canonical/lxd/shared/termios"
 )
@@ -18,25 +21,90 @@ func init() {
 	Log = newWrapper(logger)
 }
 
+// FormatText is the human readable log format.
+const FormatText = "text"
+
+// FormatJSON is the log format with one JSON object per entry, with the context as top-level keys.
+const FormatJSON = "json"
+
+// Option configures the logger set up by InitLogger.
+type Option func(opts *options)
+
+// options are the settings of the logger set up by InitLogger.
+type options struct {
+	format          string
+	reportCaller    bool
+	timestampFormat string
+	utc             bool
+}
+
+// WithFormat sets the format of the entries, one of FormatText or FormatJSON.
+// The default is FormatText.
+func WithFormat(format string) Option {
+	return func(opts *options) {
+		opts.format = format
+	}
+}
+
+// WithReportCaller adds the file and line of the call site to each entry, which has a small cost on every
+// logged entry.
+func WithReportCaller() Option {
+	return func(opts *options) {
+		opts.reportCaller = true
+	}
+}
+
+// WithTimestampFormat sets the layout of the timestamps, as accepted by time.Format.
+// The default is time.RFC3339.
+func WithTimestampFormat(layout string) Option {
+	return func(opts *options) {
+		opts.timestampFormat = layout
+	}
+}
+
+// WithUTC writes the timestamps in UTC rather than in the local time zone.
+func WithUTC() Option {
+	return func(opts *options) {
+		opts.utc = true
+	}
+}
+
 // InitLogger intializes a full logging instance.
-func InitLogger(filepath string, syslogName string, verbose bool, debug bool, hook logrus.Hook) error {
+func InitLogger(filepath string, syslogName string, verbose bool, debug bool, hook logrus.Hook, opts ...Option) error {
+	logOptions := options{format: FormatText, timestampFormat: time.RFC3339}
+	for _, opt := range opts {
+		opt(&logOptions)
+	}
+
 	logger := logrus.New()
 	logger.Level = logrus.DebugLevel
 	logger.SetOutput(io.Discard)
 
 	// Setup the formatter.
-	logger.Formatter = &logrus.TextFormatter{PadLevelText: true, FullTimestamp: true, ForceColors: termios.IsTerminal(int(os.Stderr.Fd()))}
+	switch logOptions.format {
+	case FormatText:
+		logger.Formatter = &logrus.TextFormatter{PadLevelText: true, FullTimestamp: true, TimestampFormat: logOptions.timestampFormat, ForceColors: termios.IsTerminal(int(os.Stderr.Fd()))}
+	case FormatJSON:
+		logger.Formatter = &logrus.JSONFormatter{TimestampFormat: logOptions.timestampFormat}
+	default:
+		return fmt.Errorf("Unknown log format %q", logOptions.format)
+	}
+
+	if logOptions.utc {
+		logger.Formatter = &utcFormatter{Formatter: logger.Formatter}
+	}
 
 	// Setup log level.
-	levels := []logrus.Level{logrus.PanicLevel, logrus.FatalLevel, logrus.ErrorLevel, logrus.WarnLevel}
+	level := logrus.WarnLevel
 	if debug {
-		levels = append(levels, logrus.InfoLevel, logrus.DebugLevel)
+		level = logrus.DebugLevel
 	} else if verbose {
-		levels = append(levels, logrus.InfoLevel)
+		level = logrus.InfoLevel
 	}
 
 	// Setup writers.
 	writers := []io.Writer{os.Stderr}
+	var files []*os.File
 
 	if filepath != "" {
 		f, err := os.OpenFile(filepath, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
@@ -45,12 +113,12 @@ func InitLogger(filepath string, syslogName string, verbose bool, debug bool, ho
 		}
 
 		writers = append(writers, f)
+		files = append(files, f)
 	}
 
-	logger.AddHook(&lWriter.Hook{
-		Writer:    io.MultiWriter(writers...),
-		LogLevels: levels,
-	})
+	writer := &writerHook{writer: io.MultiWriter(writers...), files: files}
+	writer.SetLevel(level)
+	logger.AddHook(writer)
 
 	// Setup syslog.
 	if syslogName != "" {
@@ -66,7 +134,107 @@ func InitLogger(filepath string, syslogName string, verbose bool, debug bool, ho
 	}
 
 	// Set the logger.
-	Log = newWrapper(logger)
+	var options []wrapperOption
+	if logOptions.reportCaller {
+		options = append(options, withReportCaller())
+	}
+
+	Log = newWrapper(logger, options...)
 
 	return nil
 }
+
+// utcFormatter formats the entries with their time in UTC.
+type utcFormatter struct {
+	logrus.Formatter
+}
+
+// Format formats a copy of the entry with its time in UTC, as the entry is shared with the other hooks.
+func (f *utcFormatter) Format(entry *logrus.Entry) ([]byte, error) {
+	utcEntry := *entry
+	utcEntry.Time = entry.Time.UTC()
+
+	return f.Formatter.Format(&utcEntry)
+}
+
+// hookLevels holds the level up to which a hook handles entries, globally and for each module. The levels can
+// be changed at runtime, concurrently with the entries being fired.
+type hookLevels struct {
+	level   atomic.Uint32
+	modules sync.Map // Module name to logrus.Level.
+}
+
+// enabled returns whether the entry is within the level, or the level of the module it was logged by.
+func (h *hookLevels) enabled(entry *logrus.Entry) bool {
+	level := h.GetLevel()
+
+	module, ok := entry.Data["module"].(string)
+	if ok {
+		moduleLevel, ok := h.ModuleLevel(module)
+		if ok {
+			level = moduleLevel
+		}
+	}
+
+	return entry.Level <= level
+}
+
+// GetLevel returns the level up to which entries are handled.
+func (h *hookLevels) GetLevel() logrus.Level {
+	return logrus.Level(h.level.Load())
+}
+
+// SetLevel sets the level up to which entries are handled.
+func (h *hookLevels) SetLevel(level logrus.Level) {
+	h.level.Store(uint32(level))
+}
+
+// ModuleLevel returns the level up to which entries of the module are handled, if one was set.
+func (h *hookLevels) ModuleLevel(module string) (logrus.Level, bool) {
+	level, ok := h.modules.Load(module)
+	if !ok {
+		return 0, false
+	}
+
+	return level.(logrus.Level), true
+}
+
+// SetModuleLevel sets the level up to which entries of the module are handled, regardless of the global level.
+func (h *hookLevels) SetModuleLevel(module string, level logrus.Level) {
+	h.modules.Store(module, level)
+}
+
+// writerHook writes log entries up to a level that can be changed at runtime, globally or for each module.
+type writerHook struct {
+	hookLevels
+
+	writer io.Writer
+	files  []*os.File // Files written to, synced by Flush.
+}
+
+// Levels returns all levels as the entries are filtered by Fire.
+func (h *writerHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+// Fire writes the entry if it is within the hook's level, or the level of the module it was logged by.
+func (h *writerHook) Fire(entry *logrus.Entry) error {
+	if !h.enabled(entry) {
+		return nil
+	}
+
+	line, err := entry.Bytes()
+	if err != nil {
+		return err
+	}
+
+	_, err = h.writer.Write(line)
+	return err
+}
+
+// Flush syncs the files written to.
+func (h *writerHook) Flush() {
+	for _, f := range h.files {
+		_ = f.Sync()
+	}
+}
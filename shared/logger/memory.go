@@ -0,0 +1,101 @@
+package logger
+
+import (
+	"io"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+// MemoryEntry is a log entry recorded by a MemoryLogger.
+type MemoryEntry struct {
+	Level   logrus.Level
+	Message string
+	Ctx     Ctx
+}
+
+// MemoryLogger is a Logger recording the entries in memory, for tests to inspect what was logged.
+// The loggers derived from it, with AddContext, WithError or Named, record their entries into it too.
+type MemoryLogger struct {
+	Logger
+
+	recorder *memoryRecorder
+}
+
+// NewMemoryLogger returns a MemoryLogger recording entries at all levels. Fatal entries are recorded without
+// exiting, while Panic entries are recorded and then panic as usual.
+func NewMemoryLogger() *MemoryLogger {
+	target := logrus.New()
+	target.SetOutput(io.Discard)
+	target.SetLevel(logrus.TraceLevel)
+	target.ExitFunc = func(int) {}
+
+	recorder := &memoryRecorder{}
+	recorder.SetLevel(logrus.TraceLevel)
+	target.AddHook(recorder)
+
+	return &MemoryLogger{Logger: newWrapper(target), recorder: recorder}
+}
+
+// Entries returns the entries recorded so far, in the order they were logged.
+func (l *MemoryLogger) Entries() []MemoryEntry {
+	l.recorder.mu.Lock()
+	defer l.recorder.mu.Unlock()
+
+	entries := make([]MemoryEntry, len(l.recorder.entries))
+	copy(entries, l.recorder.entries)
+
+	return entries
+}
+
+// EntriesAtLevel returns the entries recorded so far at the given level.
+func (l *MemoryLogger) EntriesAtLevel(level logrus.Level) []MemoryEntry {
+	var entries []MemoryEntry
+	for _, entry := range l.Entries() {
+		if entry.Level == level {
+			entries = append(entries, entry)
+		}
+	}
+
+	return entries
+}
+
+// Reset discards the entries recorded so far.
+func (l *MemoryLogger) Reset() {
+	l.recorder.mu.Lock()
+	defer l.recorder.mu.Unlock()
+
+	l.recorder.entries = nil
+}
+
+// memoryRecorder records the log entries up to a level that can be changed at runtime.
+type memoryRecorder struct {
+	hookLevels
+
+	mu      sync.Mutex
+	entries []MemoryEntry
+}
+
+// Levels returns all levels as the entries are filtered by Fire.
+func (r *memoryRecorder) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+// Fire records the entry if it is within the recorder's level, or the level of the module it was logged by.
+func (r *memoryRecorder) Fire(entry *logrus.Entry) error {
+	if !r.enabled(entry) {
+		return nil
+	}
+
+	ctx := make(Ctx, len(entry.Data))
+	for k, v := range entry.Data {
+		ctx[k] = v
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.entries = append(r.entries, MemoryEntry{Level: entry.Level, Message: entry.Message, Ctx: ctx})
+
+	return nil
+}
@@ -0,0 +1,115 @@
+package logger
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Test MemoryLogger records the entries of the loggers derived from it.
+func TestMemoryLogger(t *testing.T) {
+	l := NewMemoryLogger()
+
+	l.AddContext(Ctx{"cPath": "/var/lib/lxd/containers/c1"}).Error("Failed deleting", Ctx{"err": "busy"})
+	l.Debugf("Deleted %d snapshots", 2)
+	l.Named("storage_lvm").Fatal("Failed")
+
+	entries := l.Entries()
+	require.Len(t, entries, 3)
+	assert.Equal(t, MemoryEntry{Level: logrus.ErrorLevel, Message: "Failed deleting", Ctx: Ctx{"cPath": "/var/lib/lxd/containers/c1", "err": "busy"}}, entries[0])
+	assert.Equal(t, MemoryEntry{Level: logrus.DebugLevel, Message: "Deleted 2 snapshots", Ctx: Ctx{}}, entries[1])
+	assert.Equal(t, MemoryEntry{Level: logrus.FatalLevel, Message: "Failed", Ctx: Ctx{"module": "storage_lvm"}}, entries[2])
+
+	errEntries := l.EntriesAtLevel(logrus.ErrorLevel)
+	require.Len(t, errEntries, 1)
+	assert.Equal(t, "Failed deleting", errEntries[0].Message)
+	assert.Empty(t, l.EntriesAtLevel(logrus.WarnLevel))
+
+	l.Reset()
+	assert.Empty(t, l.Entries())
+
+	// The level applies to what is recorded.
+	l.SetLevel(logrus.InfoLevel)
+	l.Debug("Hidden")
+	l.Info("Shown")
+	require.Len(t, l.Entries(), 1)
+	assert.Equal(t, "Shown", l.Entries()[0].Message)
+
+	assert.Panics(t, func() { l.Panic("Panic") })
+	assert.Equal(t, logrus.PanicLevel, l.Entries()[1].Level)
+}
+
+// Test the known context.Context values are added to the entries.
+func TestLogWrapperWithContext(t *testing.T) {
+	l := NewMemoryLogger()
+
+	ctx := context.WithValue(context.Background(), ContextKeyOperation, "6916c8a6")
+	ctx = context.WithValue(ctx, ContextKey("other"), "ignored")
+
+	l.Named("storage_lvm").WithContext(ctx).Info("Creating volume", Ctx{"volName": "c1"})
+	l.WithContext(context.Background()).Info("Nothing added")
+
+	entries := l.Entries()
+	require.Len(t, entries, 2)
+	assert.Equal(t, Ctx{"module": "storage_lvm", "operation": "6916c8a6", "volName": "c1"}, entries[0].Ctx)
+	assert.Equal(t, Ctx{}, entries[1].Ctx)
+}
+
+// Test FromContext uses the global logger.
+func TestFromContext(t *testing.T) {
+	oldLog := Log
+	defer func() { Log = oldLog }()
+
+	l := NewMemoryLogger()
+	Log = l
+
+	ctx := context.WithValue(context.Background(), ContextKeyRequestID, "42")
+	FromContext(ctx).Warn("Slow request")
+
+	require.Len(t, l.Entries(), 1)
+	assert.Equal(t, Ctx{"requestID": "42"}, l.Entries()[0].Ctx)
+}
+
+// Test the identical entries logged within the window are collapsed into one with their count.
+func TestLogWrapperWithRateLimit(t *testing.T) {
+	l := NewMemoryLogger()
+	limited := l.WithRateLimit(200 * time.Millisecond).AddContext(Ctx{"dev": "/dev/dm-3"})
+
+	for i := 0; i < 5; i++ {
+		limited.Error("Failed to suspend device")
+		limited.Errorf("Failed %d times", 1)
+	}
+
+	limited.Error("Failed to suspend device", Ctx{"attempt": 2})
+	limited.Warn("Failed to suspend device")
+
+	entries := l.Entries()
+	require.Len(t, entries, 4)
+	assert.Equal(t, "Failed to suspend device", entries[0].Message)
+	assert.Equal(t, "Failed 1 times", entries[1].Message)
+	assert.Equal(t, Ctx{"dev": "/dev/dm-3", "attempt": 2}, entries[2].Ctx)
+	assert.Equal(t, logrus.WarnLevel, entries[3].Level)
+
+	// The suppressed occurrences are reported at the end of the window.
+	require.Eventually(t, func() bool { return len(l.Entries()) == 6 }, 2*time.Second, 10*time.Millisecond)
+
+	repeated := l.Entries()[4:]
+	assert.ElementsMatch(t, []MemoryEntry{
+		{Level: logrus.ErrorLevel, Message: "Failed to suspend device", Ctx: Ctx{"dev": "/dev/dm-3", "repeated": 4}},
+		{Level: logrus.ErrorLevel, Message: "Failed 1 times", Ctx: Ctx{"dev": "/dev/dm-3", "repeated": 4}},
+	}, repeated)
+
+	// The entry is logged again once the window is over.
+	limited.Error("Failed to suspend device")
+	assert.Len(t, l.Entries(), 7)
+
+	// The rate limit is disabled by default.
+	l.Reset()
+	l.Error("Failed")
+	l.Error("Failed")
+	assert.Len(t, l.Entries(), 2)
+}
@@ -0,0 +1,70 @@
+package logger
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// rateLimiter collapses the identical entries logged within a window.
+type rateLimiter struct {
+	window time.Duration
+
+	mu   sync.Mutex
+	seen map[string]*rateLimitedEntry
+}
+
+// rateLimitedEntry counts the occurrences of an entry suppressed during its window.
+type rateLimitedEntry struct {
+	count int
+}
+
+// newRateLimiter returns a rateLimiter with the given window.
+func newRateLimiter(window time.Duration) *rateLimiter {
+	return &rateLimiter{window: window, seen: map[string]*rateLimitedEntry{}}
+}
+
+// allow returns whether the entry with the given key should be logged. The first occurrence is allowed and
+// starts a window during which the other occurrences are suppressed. If any were, flush is called with their count
+// at the end of the window.
+func (r *rateLimiter) allow(key string, flush func(count int)) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	entry, ok := r.seen[key]
+	if ok {
+		entry.count++
+		return false
+	}
+
+	entry = &rateLimitedEntry{}
+	r.seen[key] = entry
+
+	time.AfterFunc(r.window, func() {
+		r.mu.Lock()
+		count := entry.count
+		delete(r.seen, key)
+		r.mu.Unlock()
+
+		if count > 0 {
+			flush(count)
+		}
+	})
+
+	return true
+}
+
+// rateLimitKey returns the key identifying an entry by its level, message and context.
+func rateLimitKey(level logrus.Level, msg string, logger targetLogger) string {
+	var fields logrus.Fields
+
+	entry, ok := logger.(*logrus.Entry)
+	if ok {
+		fields = entry.Data
+	}
+
+	// Maps are printed sorted by key.
+	return fmt.Sprintf("%d\x00%s\x00%v", level, msg, fields)
+}
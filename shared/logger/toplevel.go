@@ -1,7 +1,7 @@
 package logger
 
 import (
-	"fmt"
+	"github.com/sirupsen/logrus"
 )
 
 // Trace logs a message (with optional context) at the TRACE log level.
@@ -36,35 +36,55 @@ func Panic(msg string, ctx ...Ctx) {
 
 // Tracef logs at the TRACE log level using a standard printf format string.
 func Tracef(format string, args ...any) {
-	Log.Trace(fmt.Sprintf(format, args...))
+	Log.Tracef(format, args...)
 }
 
 // Debugf logs at the DEBUG log level using a standard printf format string.
 func Debugf(format string, args ...any) {
-	Log.Debug(fmt.Sprintf(format, args...))
+	Log.Debugf(format, args...)
 }
 
 // Infof logs at the INFO log level using a standard printf format string.
 func Infof(format string, args ...any) {
-	Log.Info(fmt.Sprintf(format, args...))
+	Log.Infof(format, args...)
 }
 
 // Warnf logs at the WARNING log level using a standard printf format string.
 func Warnf(format string, args ...any) {
-	Log.Warn(fmt.Sprintf(format, args...))
+	Log.Warnf(format, args...)
 }
 
 // Errorf logs at the ERROR log level using a standard printf format string.
 func Errorf(format string, args ...any) {
-	Log.Error(fmt.Sprintf(format, args...))
+	Log.Errorf(format, args...)
 }
 
 // Panicf logs at the PANIC log level using a standard printf format string.
 func Panicf(format string, args ...any) {
-	Log.Panic(fmt.Sprintf(format, args...))
+	Log.Panicf(format, args...)
 }
 
 // AddContext returns a new logger with the context added.
 func AddContext(ctx Ctx) Logger {
 	return Log.AddContext(ctx)
 }
+
+// WithError returns a new logger with the error added to the context.
+func WithError(err error) Logger {
+	return Log.WithError(err)
+}
+
+// AddHook registers a hook on the current logger, receiving the entries logged at the levels it handles.
+func AddHook(hook logrus.Hook) {
+	Log.AddHook(hook)
+}
+
+// RemoveHook unregisters a hook registered with AddHook.
+func RemoveHook(hook logrus.Hook) {
+	Log.RemoveHook(hook)
+}
+
+// Flush waits for the pending entries to be written.
+func Flush() {
+	Log.Flush()
+}
@@ -1,6 +1,9 @@
 package logger
 
 import (
+	"context"
+	"time"
+
 	"github.com/sirupsen/logrus"
 )
 
@@ -8,6 +11,7 @@ import (
 type Ctx logrus.Fields
 
 // Log contains the logger used by all the logging functions.
+// It discards all entries until replaced by InitLogger, so that logging before the setup is safe.
 var Log Logger
 
 // Logger is the main logging interface.
@@ -19,7 +23,38 @@ type Logger interface {
 	Info(msg string, args ...Ctx)
 	Debug(msg string, args ...Ctx)
 	Trace(msg string, args ...Ctx)
+	Panicf(format string, args ...any)
+	Fatalf(format string, args ...any)
+	Errorf(format string, args ...any)
+	Warnf(format string, args ...any)
+	Infof(format string, args ...any)
+	Debugf(format string, args ...any)
+	Tracef(format string, args ...any)
 	AddContext(Ctx) Logger
+	WithError(err error) Logger
+	WithContext(ctx context.Context) Logger
+	WithRateLimit(window time.Duration) Logger
+	Named(module string) Logger
+	GetLevel() logrus.Level
+	SetLevel(level logrus.Level)
+	AddHook(hook logrus.Hook)
+	RemoveHook(hook logrus.Hook)
+	Flush()
+}
+
+// Flusher is implemented by the hooks buffering entries or delivering them asynchronously, so that Flush can wait
+// for the pending entries to be written.
+type Flusher interface {
+	// Flush returns once the entries fired so far are written.
+	Flush()
+}
+
+// levelHook represents a hook whose level, and the level of each module, can be changed at runtime.
+type levelHook interface {
+	GetLevel() logrus.Level
+	SetLevel(level logrus.Level)
+	ModuleLevel(module string) (logrus.Level, bool)
+	SetModuleLevel(module string, level logrus.Level)
 }
 
 // targetLogger represents the subset of logrus.Logger and logrus.Entry that we care about.
@@ -31,5 +66,14 @@ type targetLogger interface {
 	Info(args ...interface{})
 	Debug(args ...interface{})
 	Trace(args ...interface{})
+	Panicf(format string, args ...interface{})
+	Fatalf(format string, args ...interface{})
+	Errorf(format string, args ...interface{})
+	Warnf(format string, args ...interface{})
+	Infof(format string, args ...interface{})
+	Debugf(format string, args ...interface{})
+	Tracef(format string, args ...interface{})
+	Log(level logrus.Level, args ...interface{})
+	Logf(level logrus.Level, format string, args ...interface{})
 	WithFields(fields logrus.Fields) *logrus.Entry
 }
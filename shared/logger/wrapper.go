@@ -1,6 +1,12 @@
 package logger
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
 	"github.com/sirupsen/logrus"
 )
 
@@ -14,42 +20,380 @@ func (lw *logWrapper) ctxLogger(ctx ...Ctx) targetLogger {
 	return logger
 }
 
-func newWrapper(target targetLogger) Logger {
-	return &logWrapper{target}
+// entryLogger returns the logger target to emit an entry through, with all provided ctx applied and the caller
+// recorded if enabled.
+func (lw *logWrapper) entryLogger(ctx ...Ctx) targetLogger {
+	logger := lw.ctxLogger(ctx...)
+	if lw.reportCaller {
+		logger = logger.WithFields(callerFields())
+	}
+
+	return logger
+}
+
+// wrapperOption configures a logWrapper.
+type wrapperOption func(lw *logWrapper)
+
+// withReportCaller records the file and line of the call site in each entry. The frames of this package are
+// skipped so that the reported location is the one calling the logger, at the cost of walking the stack on each
+// entry.
+func withReportCaller() wrapperOption {
+	return func(lw *logWrapper) {
+		lw.reportCaller = true
+	}
 }
 
+func newWrapper(target targetLogger, options ...wrapperOption) Logger {
+	lw := &logWrapper{target: target, hooks: &hookSet{}}
+	for _, option := range options {
+		option(lw)
+	}
+
+	// Dispatch the entries to the hooks registered through AddHook.
+	root := lw.root()
+	if root != nil {
+		root.AddHook(lw.hooks)
+	}
+
+	return lw
+}
+
+// flushTimeout is the time Flush waits for the hooks to write the pending entries.
+const flushTimeout = 5 * time.Second
+
 type logWrapper struct {
-	target targetLogger
+	target       targetLogger
+	module       string
+	reportCaller bool
+	hooks        *hookSet
+	limiter      *rateLimiter
 }
 
+// withTarget returns a copy of the logger emitting its entries through the target.
+func (lw *logWrapper) withTarget(target targetLogger) *logWrapper {
+	clone := *lw
+	clone.target = target
+
+	return &clone
+}
+
+// Panic and fatal entries are flushed before panicking or exiting.
 func (lw *logWrapper) Panic(msg string, ctx ...Ctx) {
-	lw.ctxLogger(ctx...).Panic(msg)
+	defer lw.Flush()
+
+	lw.entryLogger(ctx...).Panic(msg)
 }
 
 func (lw *logWrapper) Fatal(msg string, ctx ...Ctx) {
-	lw.ctxLogger(ctx...).Fatal(msg)
+	lw.entryLogger(ctx...).Log(logrus.FatalLevel, msg)
+	lw.Flush()
+	lw.exit()
 }
 
 func (lw *logWrapper) Error(msg string, ctx ...Ctx) {
-	lw.ctxLogger(ctx...).Error(msg)
+	if lw.limited(logrus.ErrorLevel, msg, ctx...) {
+		return
+	}
+
+	lw.entryLogger(ctx...).Error(msg)
 }
 
 func (lw *logWrapper) Warn(msg string, ctx ...Ctx) {
-	lw.ctxLogger(ctx...).Warn(msg)
+	if lw.limited(logrus.WarnLevel, msg, ctx...) {
+		return
+	}
+
+	lw.entryLogger(ctx...).Warn(msg)
 }
 
 func (lw *logWrapper) Info(msg string, ctx ...Ctx) {
-	lw.ctxLogger(ctx...).Info(msg)
+	if lw.limited(logrus.InfoLevel, msg, ctx...) {
+		return
+	}
+
+	lw.entryLogger(ctx...).Info(msg)
 }
 
 func (lw *logWrapper) Debug(msg string, ctx ...Ctx) {
-	lw.ctxLogger(ctx...).Debug(msg)
+	if lw.limited(logrus.DebugLevel, msg, ctx...) {
+		return
+	}
+
+	lw.entryLogger(ctx...).Debug(msg)
 }
 
 func (lw *logWrapper) Trace(msg string, ctx ...Ctx) {
-	lw.ctxLogger(ctx...).Trace(msg)
+	if lw.limited(logrus.TraceLevel, msg, ctx...) {
+		return
+	}
+
+	lw.entryLogger(ctx...).Trace(msg)
+}
+
+// The formatted variants only format the message if the level is enabled.
+func (lw *logWrapper) Panicf(format string, args ...any) {
+	defer lw.Flush()
+
+	lw.entryLogger().Panicf(format, args...)
+}
+
+func (lw *logWrapper) Fatalf(format string, args ...any) {
+	lw.entryLogger().Logf(logrus.FatalLevel, format, args...)
+	lw.Flush()
+	lw.exit()
+}
+
+func (lw *logWrapper) Errorf(format string, args ...any) {
+	if lw.limitedf(logrus.ErrorLevel, format, args...) {
+		return
+	}
+
+	lw.entryLogger().Errorf(format, args...)
+}
+
+func (lw *logWrapper) Warnf(format string, args ...any) {
+	if lw.limitedf(logrus.WarnLevel, format, args...) {
+		return
+	}
+
+	lw.entryLogger().Warnf(format, args...)
+}
+
+func (lw *logWrapper) Infof(format string, args ...any) {
+	if lw.limitedf(logrus.InfoLevel, format, args...) {
+		return
+	}
+
+	lw.entryLogger().Infof(format, args...)
+}
+
+func (lw *logWrapper) Debugf(format string, args ...any) {
+	if lw.limitedf(logrus.DebugLevel, format, args...) {
+		return
+	}
+
+	lw.entryLogger().Debugf(format, args...)
 }
 
+func (lw *logWrapper) Tracef(format string, args ...any) {
+	if lw.limitedf(logrus.TraceLevel, format, args...) {
+		return
+	}
+
+	lw.entryLogger().Tracef(format, args...)
+}
+
+// AddContext returns a new logger with the context added to the one of this logger, which is left unchanged.
+// Values of the added context replace those already set for the same keys.
 func (lw *logWrapper) AddContext(ctx Ctx) Logger {
-	return &logWrapper{lw.ctxLogger(ctx)}
+	return lw.withTarget(lw.ctxLogger(ctx))
+}
+
+// WithContext returns a logger with the values of the known keys (see ContextKey) set in the context added to the
+// context of the entries.
+func (lw *logWrapper) WithContext(ctx context.Context) Logger {
+	return lw.AddContext(contextFields(ctx))
+}
+
+// WithRateLimit returns a logger collapsing the identical entries, with the same level, message and context,
+// logged within the window. The first occurrence is logged immediately and, if more occurred within the window,
+// the entry is logged again at its end with their count under the "repeated" key. Panic and fatal entries are
+// never collapsed. The loggers derived from it share the same window. A window of 0 disables the rate limit.
+func (lw *logWrapper) WithRateLimit(window time.Duration) Logger {
+	limited := lw.withTarget(lw.target)
+	limited.limiter = nil
+	if window > 0 {
+		limited.limiter = newRateLimiter(window)
+	}
+
+	return limited
+}
+
+// limited returns whether the entry is suppressed by the rate limit.
+func (lw *logWrapper) limited(level logrus.Level, msg string, ctx ...Ctx) bool {
+	if lw.limiter == nil {
+		return false
+	}
+
+	logger := lw.ctxLogger(ctx...)
+
+	return !lw.limiter.allow(rateLimitKey(level, msg, logger), func(count int) {
+		logger.WithFields(logrus.Fields{"repeated": count}).Log(level, msg)
+	})
+}
+
+// limitedf returns whether the formatted entry is suppressed by the rate limit. The message is only formatted if
+// a rate limit is set and the level is enabled.
+func (lw *logWrapper) limitedf(level logrus.Level, format string, args ...any) bool {
+	if lw.limiter == nil {
+		return false
+	}
+
+	root := lw.root()
+	if root != nil && !root.IsLevelEnabled(level) {
+		return false
+	}
+
+	return lw.limited(level, fmt.Sprintf(format, args...))
+}
+
+// Named returns a logger for the module, with the module name added to the context under the "module" key.
+// The level of a named logger can be changed independently of the global level using its SetLevel.
+func (lw *logWrapper) Named(module string) Logger {
+	named := lw.withTarget(lw.ctxLogger(Ctx{"module": module}))
+	named.module = module
+
+	return named
+}
+
+// WithError returns a logger with the error added to the context under the "err" key. If the error wraps other
+// errors, the innermost one is also added under the "cause" key.
+func (lw *logWrapper) WithError(err error) Logger {
+	ctx := Ctx{"err": err}
+
+	cause := err
+	for cause != nil {
+		next := errors.Unwrap(cause)
+		if next == nil {
+			break
+		}
+
+		cause = next
+	}
+
+	if cause != err {
+		ctx["cause"] = cause
+	}
+
+	return lw.AddContext(ctx)
+}
+
+// AddHook registers a hook receiving the entries logged through this logger and any logger derived from the same
+// root, at the levels returned by its Levels method. The entries include the full context. Entries are only
+// received if the logger level lets them through, regardless of the level set with SetLevel.
+// The hook must be comparable (e.g. a pointer) to be removed with RemoveHook. Hooks are fired synchronously, in
+// the logging goroutine, so they must be safe for concurrent use and should not block.
+func (lw *logWrapper) AddHook(hook logrus.Hook) {
+	lw.hooks.add(hook)
+}
+
+// RemoveHook unregisters a hook registered with AddHook.
+func (lw *logWrapper) RemoveHook(hook logrus.Hook) {
+	lw.hooks.remove(hook)
+}
+
+// Flush waits for the hooks implementing Flusher, such as the one writing to the log file, to write the pending
+// entries. It is best-effort: if the hooks haven't returned within flushTimeout, it returns without waiting for
+// them.
+func (lw *logWrapper) Flush() {
+	root := lw.root()
+	if root == nil {
+		return
+	}
+
+	// The hooks of the root are only set up when it is created, so are safe to iterate. Those set up by InitLogger
+	// and newWrapper handle all levels, so are found once among the panic level hooks.
+	var flushers []Flusher
+	for _, hook := range root.Hooks[logrus.PanicLevel] {
+		flusher, ok := hook.(Flusher)
+		if ok {
+			flushers = append(flushers, flusher)
+		}
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+
+		for _, flusher := range flushers {
+			flusher.Flush()
+		}
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(flushTimeout):
+	}
+}
+
+// exit exits through the root logger, so that its exit handlers are run.
+func (lw *logWrapper) exit() {
+	root := lw.root()
+	if root == nil {
+		os.Exit(1)
+	}
+
+	root.Exit(1)
+}
+
+// root returns the logrus logger the entries are emitted through.
+func (lw *logWrapper) root() *logrus.Logger {
+	switch target := lw.target.(type) {
+	case *logrus.Logger:
+		return target
+	case *logrus.Entry:
+		return target.Logger
+	}
+
+	return nil
+}
+
+// GetLevel returns the level up to which log entries are written to the log outputs. For a named logger, this is
+// the level of its module if one was set.
+func (lw *logWrapper) GetLevel() logrus.Level {
+	root := lw.root()
+	if root == nil {
+		return logrus.PanicLevel
+	}
+
+	for _, hooks := range root.Hooks {
+		for _, hook := range hooks {
+			levelHook, ok := hook.(levelHook)
+			if !ok {
+				continue
+			}
+
+			if lw.module != "" {
+				level, ok := levelHook.ModuleLevel(lw.module)
+				if ok {
+					return level
+				}
+			}
+
+			return levelHook.GetLevel()
+		}
+	}
+
+	return root.GetLevel()
+}
+
+// SetLevel changes the level up to which log entries are written to the log outputs (the log file, stderr and
+// syslog are still limited to their own levels). Hooks that receive every log entry, such as the one passed to
+// InitLogger, are not affected. For a named logger, only the level of its module is changed.
+// It is safe to call concurrently with logging.
+func (lw *logWrapper) SetLevel(level logrus.Level) {
+	root := lw.root()
+	if root == nil {
+		return
+	}
+
+	// The logger level must let the entries through to the hooks.
+	if level > root.GetLevel() {
+		root.SetLevel(level)
+	}
+
+	for _, hooks := range root.Hooks {
+		for _, hook := range hooks {
+			levelHook, ok := hook.(levelHook)
+			if !ok {
+				continue
+			}
+
+			if lw.module != "" {
+				levelHook.SetModuleLevel(lw.module, level)
+			} else {
+				levelHook.SetLevel(level)
+			}
+		}
+	}
 }
@@ -0,0 +1,485 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Test the context passed to the logging functions and through AddContext reaches the emitted record.
+func TestLogWrapperContext(t *testing.T) {
+	target, hook := test.NewNullLogger()
+	target.SetLevel(logrus.TraceLevel)
+
+	l := newWrapper(target)
+
+	l.Error("error", Ctx{"a": 1}, Ctx{"b": "two"})
+	entry := hook.LastEntry()
+	require.NotNil(t, entry)
+	assert.Equal(t, logrus.ErrorLevel, entry.Level)
+	assert.Equal(t, "error", entry.Message)
+	assert.Equal(t, logrus.Fields{"a": 1, "b": "two"}, entry.Data)
+
+	sub := l.AddContext(Ctx{"instance": "c1"})
+	sub.Warn("warn", Ctx{"err": "failed"})
+	entry = hook.LastEntry()
+	require.NotNil(t, entry)
+	assert.Equal(t, logrus.WarnLevel, entry.Level)
+	assert.Equal(t, logrus.Fields{"instance": "c1", "err": "failed"}, entry.Data)
+
+	// Context added to a derived logger doesn't leak into its parent.
+	l.Info("info")
+	entry = hook.LastEntry()
+	require.NotNil(t, entry)
+	assert.Empty(t, entry.Data)
+}
+
+// Test AddContext can be chained, each call returning a new logger without changing the one it derives from.
+func TestLogWrapperAddContextChain(t *testing.T) {
+	target, hook := test.NewNullLogger()
+
+	pool := newWrapper(target).AddContext(Ctx{"pool": "p1"})
+	vol := pool.AddContext(Ctx{"volName": "c1"})
+	other := pool.AddContext(Ctx{"volName": "c2", "pool": "p2"})
+
+	vol.Error("Failed")
+	assert.Equal(t, logrus.Fields{"pool": "p1", "volName": "c1"}, hook.LastEntry().Data)
+
+	other.Error("Failed")
+	assert.Equal(t, logrus.Fields{"pool": "p2", "volName": "c2"}, hook.LastEntry().Data)
+
+	pool.Error("Failed")
+	assert.Equal(t, logrus.Fields{"pool": "p1"}, hook.LastEntry().Data)
+
+	vol.Error("Failed")
+	assert.Equal(t, logrus.Fields{"pool": "p1", "volName": "c1"}, hook.LastEntry().Data)
+}
+
+// Test each context field is rendered exactly once.
+func TestLogWrapperContextOnce(t *testing.T) {
+	var buf bytes.Buffer
+
+	target := logrus.New()
+	target.SetOutput(&buf)
+	target.Formatter = &logrus.TextFormatter{DisableTimestamp: true, DisableColors: true}
+
+	l := newWrapper(target)
+
+	l.Info("single", Ctx{"a": 1})
+	assert.Equal(t, 1, strings.Count(buf.String(), "a=1"))
+
+	buf.Reset()
+	l.AddContext(Ctx{"a": 1}).Info("multiple", Ctx{"b": 2}, Ctx{"c": 3})
+	for _, field := range []string{"a=1", "b=2", "c=3"} {
+		assert.Equal(t, 1, strings.Count(buf.String(), field), field)
+	}
+}
+
+// Test SetLevel changes which entries are written without affecting hooks receiving every entry.
+func TestLogWrapperSetLevel(t *testing.T) {
+	var buf bytes.Buffer
+
+	target := logrus.New()
+	target.SetOutput(&bytes.Buffer{})
+	target.Level = logrus.DebugLevel
+
+	writer := &writerHook{writer: &buf}
+	writer.SetLevel(logrus.WarnLevel)
+	target.AddHook(writer)
+
+	all := test.NewLocal(target)
+
+	l := newWrapper(target).AddContext(Ctx{"a": 1})
+
+	l.Debug("hidden")
+	assert.Empty(t, buf.String())
+	require.NotNil(t, all.LastEntry())
+	assert.Equal(t, "hidden", all.LastEntry().Message)
+
+	l.SetLevel(logrus.DebugLevel)
+	l.Debug("shown")
+	assert.Contains(t, buf.String(), "shown")
+
+	buf.Reset()
+	l.SetLevel(logrus.TraceLevel)
+	l.Trace("traced")
+	assert.Contains(t, buf.String(), "traced")
+
+	buf.Reset()
+	l.SetLevel(logrus.ErrorLevel)
+	l.Warn("hidden")
+	assert.Empty(t, buf.String())
+}
+
+// Test the level of a named logger is changed independently of the global level.
+func TestLogWrapperNamedSetLevel(t *testing.T) {
+	var buf bytes.Buffer
+
+	target := logrus.New()
+	target.SetOutput(&bytes.Buffer{})
+	target.Level = logrus.InfoLevel
+
+	writer := &writerHook{writer: &buf}
+	writer.SetLevel(logrus.InfoLevel)
+	target.AddHook(writer)
+
+	l := newWrapper(target)
+	lvm := l.Named("storage_lvm").AddContext(Ctx{"pool": "p1"})
+	zfs := l.Named("storage_zfs")
+
+	lvm.SetLevel(logrus.DebugLevel)
+	assert.Equal(t, logrus.DebugLevel, lvm.GetLevel())
+	assert.Equal(t, logrus.InfoLevel, zfs.GetLevel())
+	assert.Equal(t, logrus.InfoLevel, l.GetLevel())
+
+	lvm.Debug("lvm debug")
+	zfs.Debug("zfs debug")
+	l.Debug("global debug")
+	assert.Contains(t, buf.String(), "lvm debug")
+	assert.Contains(t, buf.String(), "module=storage_lvm")
+	assert.NotContains(t, buf.String(), "zfs debug")
+	assert.NotContains(t, buf.String(), "global debug")
+
+	// The module level overrides the global level in both directions.
+	buf.Reset()
+	l.SetLevel(logrus.TraceLevel)
+	lvm.Trace("lvm trace")
+	zfs.Trace("zfs trace")
+	assert.NotContains(t, buf.String(), "lvm trace")
+	assert.Contains(t, buf.String(), "zfs trace")
+	assert.Equal(t, logrus.DebugLevel, lvm.GetLevel())
+	assert.Equal(t, logrus.TraceLevel, l.GetLevel())
+}
+
+// Test SetLevel can be called while logging.
+func TestLogWrapperSetLevelConcurrent(t *testing.T) {
+	target := logrus.New()
+	target.SetOutput(&bytes.Buffer{})
+	target.AddHook(&writerHook{writer: io.Discard})
+
+	l := newWrapper(target)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+
+		go func() {
+			defer wg.Done()
+			l.Info("message", Ctx{"a": 1})
+		}()
+
+		go func(i int) {
+			defer wg.Done()
+			l.SetLevel(logrus.AllLevels[i%len(logrus.AllLevels)])
+		}(i)
+	}
+
+	wg.Wait()
+}
+
+// countingStringer counts how many times it is formatted.
+type countingStringer struct {
+	calls int
+}
+
+func (s *countingStringer) String() string {
+	s.calls++
+	return "value"
+}
+
+// Test the formatted variants format the message only when the level is enabled.
+func TestLogWrapperFormatted(t *testing.T) {
+	target, hook := test.NewNullLogger()
+	target.SetLevel(logrus.InfoLevel)
+
+	l := newWrapper(target).AddContext(Ctx{"a": 1})
+	arg := &countingStringer{}
+
+	l.Infof("info %s %d", arg, 2)
+	entry := hook.LastEntry()
+	require.NotNil(t, entry)
+	assert.Equal(t, logrus.InfoLevel, entry.Level)
+	assert.Equal(t, "info value 2", entry.Message)
+	assert.Equal(t, logrus.Fields{"a": 1}, entry.Data)
+	assert.Equal(t, 1, arg.calls)
+
+	l.Debugf("debug %s", arg)
+	assert.Len(t, hook.AllEntries(), 1)
+	assert.Equal(t, 1, arg.calls)
+}
+
+// Test WithError adds the error and its cause to the context.
+func TestLogWrapperWithError(t *testing.T) {
+	target, hook := test.NewNullLogger()
+
+	l := newWrapper(target)
+
+	cause := errors.New("No space left on device")
+	err := fmt.Errorf("Failed creating volume: %w", fmt.Errorf("Failed writing: %w", cause))
+
+	l.WithError(err).Error("Failed", Ctx{"volName": "c1"})
+	entry := hook.LastEntry()
+	require.NotNil(t, entry)
+	assert.Equal(t, logrus.Fields{"err": err, "cause": cause, "volName": "c1"}, entry.Data)
+
+	l.WithError(cause).Error("Failed")
+	entry = hook.LastEntry()
+	require.NotNil(t, entry)
+	assert.Equal(t, logrus.Fields{"err": cause}, entry.Data)
+}
+
+// Test the JSON format writes the context as top-level keys.
+func TestInitLoggerJSON(t *testing.T) {
+	oldLog := Log
+	defer func() { Log = oldLog }()
+
+	logFile := filepath.Join(t.TempDir(), "lxd.log")
+
+	err := InitLogger(logFile, "", true, false, nil, WithFormat(FormatJSON))
+	require.NoError(t, err)
+
+	AddContext(Ctx{"project": "default"}).Info("Started", Ctx{"pool": "p1"})
+
+	content, err := os.ReadFile(logFile)
+	require.NoError(t, err)
+
+	var record map[string]any
+	err = json.Unmarshal(bytes.TrimSpace(content), &record)
+	require.NoError(t, err)
+
+	assert.Equal(t, "Started", record["msg"])
+	assert.Equal(t, "info", record["level"])
+	assert.Equal(t, "default", record["project"])
+	assert.Equal(t, "p1", record["pool"])
+
+	err = InitLogger("", "", false, false, nil, WithFormat("xml"))
+	assert.Error(t, err)
+}
+
+// Test the reported caller is the call site rather than the logging package.
+func TestLogWrapperReportCaller(t *testing.T) {
+	target, hook := test.NewNullLogger()
+
+	l := newWrapper(target, withReportCaller()).Named("storage_lvm").AddContext(Ctx{"pool": "p1"})
+
+	_, file, line, _ := runtime.Caller(0)
+	l.Info("Mounted")
+	l.Warnf("Failed %d times", 2)
+
+	entries := hook.AllEntries()
+	require.Len(t, entries, 2)
+	assert.Equal(t, fmt.Sprintf("%s:%d", file, line+1), entries[0].Data["caller"])
+	assert.Equal(t, fmt.Sprintf("%s:%d", file, line+2), entries[1].Data["caller"])
+	assert.Equal(t, "p1", entries[0].Data["pool"])
+
+	// The caller isn't recorded by default.
+	newWrapper(target).Info("Mounted")
+	assert.NotContains(t, hook.LastEntry().Data, "caller")
+}
+
+// Test hooks registered at runtime receive the entries at their levels, with the full context.
+func TestLogWrapperAddHook(t *testing.T) {
+	target, _ := test.NewNullLogger()
+	target.SetLevel(logrus.DebugLevel)
+
+	l := newWrapper(target)
+	pool := l.Named("storage_lvm").AddContext(Ctx{"pool": "p1"})
+
+	errHook := &levelsHook{Hook: &test.Hook{}, levels: logrus.AllLevels[:logrus.ErrorLevel+1]}
+	all := test.NewLocal(target)
+
+	// Hooks registered on a derived logger receive the entries of all the loggers of the same root.
+	pool.AddHook(errHook)
+
+	pool.Warn("Slow")
+	pool.Error("Failed", Ctx{"volName": "c1"})
+	l.Error("Failed globally")
+
+	require.Len(t, errHook.AllEntries(), 2)
+	assert.Equal(t, logrus.Fields{"module": "storage_lvm", "pool": "p1", "volName": "c1"}, errHook.AllEntries()[0].Data)
+	assert.Equal(t, "Failed globally", errHook.AllEntries()[1].Message)
+	assert.Len(t, all.AllEntries(), 3)
+
+	l.RemoveHook(errHook)
+	l.Error("Not received")
+	assert.Len(t, errHook.AllEntries(), 2)
+}
+
+// Test hooks can be added and removed while logging.
+func TestLogWrapperAddHookConcurrent(t *testing.T) {
+	target, _ := test.NewNullLogger()
+
+	l := newWrapper(target)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+
+		go func() {
+			defer wg.Done()
+
+			for j := 0; j < 100; j++ {
+				l.Error("Failed", Ctx{"j": j})
+			}
+		}()
+
+		go func() {
+			defer wg.Done()
+
+			for j := 0; j < 100; j++ {
+				hook := &test.Hook{}
+				l.AddHook(hook)
+				l.RemoveHook(hook)
+			}
+		}()
+	}
+
+	wg.Wait()
+}
+
+// levelsHook restricts a hook to the given levels.
+type levelsHook struct {
+	*test.Hook
+
+	levels []logrus.Level
+}
+
+// Levels returns the levels of the hook.
+func (h *levelsHook) Levels() []logrus.Level {
+	return h.levels
+}
+
+// Test the default logger can be used before InitLogger is called.
+func TestDefaultLogger(t *testing.T) {
+	require.NotNil(t, Log)
+
+	assert.NotPanics(t, func() {
+		Trace("trace", Ctx{"a": 1})
+		Debug("debug", Ctx{"a": 1})
+		Info("info", Ctx{"a": 1})
+		Warn("warn", Ctx{"a": 1})
+		Error("error", Ctx{"a": 1})
+		Tracef("trace %d", 1)
+		Debugf("debug %d", 1)
+		Infof("info %d", 1)
+		Warnf("warn %d", 1)
+		Errorf("error %d", 1)
+		AddContext(Ctx{"a": 1}).Info("info")
+		WithError(errors.New("failed")).Error("error")
+		Log.Named("module").Debug("debug")
+	})
+
+	// Panic still panics, as it does once initialized.
+	assert.Panics(t, func() { Panic("panic") })
+}
+
+// flushHook records the entries it was fired with once flushed.
+type flushHook struct {
+	mu      sync.Mutex
+	pending []string
+	flushed []string
+}
+
+func (h *flushHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (h *flushHook) Fire(entry *logrus.Entry) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.pending = append(h.pending, entry.Message)
+
+	return nil
+}
+
+func (h *flushHook) Flush() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.flushed = append(h.flushed, h.pending...)
+	h.pending = nil
+}
+
+// Test Flush flushes the hooks, including before exiting on fatal entries.
+func TestLogWrapperFlush(t *testing.T) {
+	target, _ := test.NewNullLogger()
+
+	exitCode := -1
+	target.ExitFunc = func(code int) { exitCode = code }
+
+	hook := &flushHook{}
+	l := newWrapper(target)
+	l.AddHook(hook)
+
+	l.Info("info")
+	assert.Empty(t, hook.flushed)
+
+	l.Flush()
+	assert.Equal(t, []string{"info"}, hook.flushed)
+
+	l.AddContext(Ctx{"a": 1}).Fatal("fatal")
+	assert.Equal(t, []string{"info", "fatal"}, hook.flushed)
+	assert.Equal(t, 1, exitCode)
+
+	assert.Panics(t, func() { l.Panicf("panic %d", 1) })
+	assert.Equal(t, []string{"info", "fatal", "panic 1"}, hook.flushed)
+}
+
+// Test the timestamps can be written with a custom layout and in UTC.
+func TestInitLoggerTimestamp(t *testing.T) {
+	oldLog := Log
+	defer func() { Log = oldLog }()
+
+	oldLocal := time.Local
+	defer func() { time.Local = oldLocal }()
+	time.Local = time.FixedZone("UTC+2", 2*60*60)
+
+	timestamp := regexp.MustCompile(`\d{4}-\d\d-\d\dT[\d:.]+(Z|[+-]\d\d:\d\d)`)
+
+	tests := []struct {
+		opts   []Option
+		format string
+		zone   string
+	}{
+		{format: FormatText, zone: "+02:00"},
+		{format: FormatJSON, zone: "+02:00"},
+		{format: FormatText, opts: []Option{WithUTC()}, zone: "Z"},
+		{format: FormatJSON, opts: []Option{WithUTC(), WithTimestampFormat(time.RFC3339Nano)}, zone: "Z"},
+	}
+
+	for i, tt := range tests {
+		logFile := filepath.Join(t.TempDir(), "lxd.log")
+
+		err := InitLogger(logFile, "", false, false, nil, append(tt.opts, WithFormat(tt.format))...)
+		require.NoError(t, err)
+
+		Warn("Started")
+
+		content, err := os.ReadFile(logFile)
+		require.NoError(t, err)
+
+		match := timestamp.FindStringSubmatch(string(content))
+		require.NotNil(t, match, "test %d: %s", i, content)
+		assert.Equal(t, tt.zone, match[1], "test %d: %s", i, content)
+
+		logged, err := time.Parse(time.RFC3339Nano, match[0])
+		require.NoError(t, err)
+		assert.WithinDuration(t, time.Now(), logged, time.Minute)
+	}
+}
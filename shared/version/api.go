@@ -408,6 +408,29 @@ var APIExtensions = []string{
 	"device_usb_serial",
 	"network_allocate_external_ips",
 	"explicit_trust_token",
+	"storage_lvm_thinpool_reclaim",
+	"storage_lvm_thinpool_chunk_size",
+	"storage_lvm_thinpool_metadata_autoextend",
+	"storage_lvm_fsck_on_mount",
+	"storage_lvm_ext2_ext3",
+	"storage_lvm_max_snapshots",
+	"storage_lvm_wipe_on_delete",
+	"storage_lvm_log_warnings",
+	"storage_rsync_exclude",
+	"storage_lvm_preallocate",
+	"storage_rsync_verify",
+	"storage_lvm_mount_readonly",
+	"metrics_storage_commands",
+	"storage_lvm_mount_retry",
+	"storage_lvm_volume_hooks",
+	"storage_lvm_btrfs_snapshots",
+	"resources_storage_lvm",
+	"storage_lvm_freeze_on_snapshot",
+	"storage_lvm_command_log_level",
+	"storage_lvm_max_overcommit_ratio",
+	"storage_lvm_external_vg_rename",
+	"storage_lvm_image_thinpool",
+	"storage_lvm_mount_propagation",
 }
 
 // APIExtensionsCount returns the number of available API extensions.
@@ -305,6 +305,7 @@ if [ "${1:-"all"}" != "cluster" ]; then
     run_test test_image_import_dir "import image from directory"
     run_test test_image_import_existing_alias "import existing image from alias"
     run_test test_image_refresh "image refresh"
+    run_test test_image_concurrent_unpack "image concurrent unpack"
     run_test test_image_acl "image acl"
     run_test test_cloud_init "cloud-init"
     run_test test_exec "exec"
@@ -182,3 +182,35 @@ test_image_refresh() {
   lxc remote rm l2
   kill_lxd "${LXD2_DIR}"
 }
+
+test_image_concurrent_unpack() {
+  lxd_backend=$(storage_backend "$LXD_DIR")
+  if [ "${lxd_backend}" != "zfs" ] && [ "${lxd_backend}" != "lvm" ] && [ "${lxd_backend}" != "btrfs" ]; then
+    echo "==> SKIP: storage driver ${lxd_backend} doesn't use optimized image volumes"
+    return
+  fi
+
+  ensure_import_testimage
+
+  # Use a new pool so that the image volume doesn't exist yet when the instances are created.
+  pool="lxdtest-$(basename "${LXD_DIR}")-concurrent-unpack"
+  lxc storage create "${pool}" "${lxd_backend}" size=1GiB
+  fp="$(lxc image info testimage | awk '/^Fingerprint/ {print $2}')"
+
+  # Create two instances from the same image at the same time, both should wait for a single unpack.
+  timeout 300 lxc init testimage c1 -s "${pool}" &
+  c1_pid=$!
+  timeout 300 lxc init testimage c2 -s "${pool}" &
+  c2_pid=$!
+
+  wait "${c1_pid}"
+  wait "${c2_pid}"
+
+  [ "$(lxc storage volume list "${pool}" --format csv --columns tn | grep -c "^image,${fp}$")" = "1" ]
+  lxc list -c n --format csv | grep -xF c1
+  lxc list -c n --format csv | grep -xF c2
+
+  lxc delete c1 c2
+  lxc image delete testimage
+  lxc storage delete "${pool}"
+}